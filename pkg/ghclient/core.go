@@ -19,6 +19,7 @@ limitations under the License.
 package ghclient
 
 import (
+	"context"
 	"fmt"
 	"math"
 	"net/http"
@@ -39,6 +40,7 @@ type Client struct {
 	prService    pullRequestService
 	repoService  repositoryService
 	userService  usersService
+	orgService   organizationsService
 
 	retries             int
 	retryInitialBackoff time.Duration
@@ -54,28 +56,44 @@ func NewClient(token string, dryRun bool) *Client {
 
 // NewClientWithEndpoint makes a new Client with the provided endpoint.
 func NewClientWithEndpoint(endpoint string, token string, dryRun bool) *Client {
-	baseURL, err := url.Parse(endpoint)
+	return NewClientWithTransport(endpoint, token, dryRun, http.DefaultTransport)
+}
+
+// NewClientWithTransport makes a new Client with the provided endpoint that sends its requests
+// with base, e.g. to go through a proxy or trust a custom CA.
+func NewClientWithTransport(endpoint string, token string, dryRun bool, base http.RoundTripper) *Client {
+	return NewEnterpriseClient(endpoint, "", token, dryRun, base)
+}
+
+// NewEnterpriseClient makes a new Client for a GitHub Enterprise Server install, with the API
+// endpoint (e.g. https://github.example.com/api/v3/) and the upload endpoint (e.g.
+// https://github.example.com/api/uploads/). An empty upload endpoint keeps the github.com default.
+func NewEnterpriseClient(endpoint, uploadEndpoint string, token string, dryRun bool, base http.RoundTripper) *Client {
+	baseURL, err := parseEndpoint(endpoint)
 	if err != nil {
 		glog.Fatalf("invalid github endpoint %s: %s", endpoint, err)
 	}
-	if !strings.HasSuffix(baseURL.Path, "/") {
-		baseURL.Path += "/"
-	}
 
 	httpClient := &http.Client{
 		Transport: &oauth2.Transport{
-			Base:   http.DefaultTransport,
+			Base:   base,
 			Source: oauth2.ReuseTokenSource(nil, oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})),
 		},
 	}
 	client := github.NewClient(httpClient)
 	client.BaseURL = baseURL
+	if uploadEndpoint != "" {
+		if client.UploadURL, err = parseEndpoint(uploadEndpoint); err != nil {
+			glog.Fatalf("invalid github upload endpoint %s: %s", uploadEndpoint, err)
+		}
+	}
 
 	return &Client{
 		issueService:        client.Issues,
 		prService:           client.PullRequests,
 		repoService:         client.Repositories,
 		userService:         client.Users,
+		orgService:          client.Organizations,
 		retries:             5,
 		retryInitialBackoff: time.Second,
 		tokenReserve:        50,
@@ -83,23 +101,48 @@ func NewClientWithEndpoint(endpoint string, token string, dryRun bool) *Client {
 	}
 }
 
-func (c *Client) sleepForAttempt(retryCount int) {
+// parseEndpoint parses a github URL, adding the trailing slash that go-github requires.
+func parseEndpoint(endpoint string) (*url.URL, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(u.Path, "/") {
+		u.Path += "/"
+	}
+	return u, nil
+}
+
+func (c *Client) sleepForAttempt(ctx context.Context, retryCount int) error {
 	maxDelay := 20 * time.Second
 	delay := c.retryInitialBackoff * time.Duration(math.Exp2(float64(retryCount)))
 	if delay > maxDelay {
 		delay = maxDelay
 	}
-	time.Sleep(delay)
+	return sleep(ctx, delay)
 }
 
-func (c *Client) limitRate(r *github.Rate) {
+func (c *Client) limitRate(ctx context.Context, r *github.Rate) error {
 	if r.Remaining <= c.tokenReserve {
 		sleepDuration := time.Until(r.Reset.Time) + (time.Second * 10)
 		if sleepDuration > 0 {
 			glog.Infof("--Rate Limiting-- Tokens reached minimum reserve %d. Sleeping until reset in %v.\n", c.tokenReserve, sleepDuration)
-			time.Sleep(sleepDuration)
+			return sleep(ctx, sleepDuration)
 		}
 	}
+	return nil
+}
+
+// sleep waits for d, or returns the error of ctx if it is done first.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
 
 type retryAbort struct{ error }
@@ -108,36 +151,56 @@ func (r *retryAbort) Error() string {
 	return fmt.Sprintf("aborting retry loop: %v", r.error)
 }
 
-// retry handles rate limiting and retry logic for a github API call.
-func (c *Client) retry(action string, call func() (*github.Response, error)) (*github.Response, error) {
+// IsNotFound returns true iff err is a github API error for a resource that does not exist.
+func IsNotFound(err error) bool {
+	errResp, ok := err.(*github.ErrorResponse)
+	return ok && errResp.Response != nil && errResp.Response.StatusCode == http.StatusNotFound
+}
+
+// retry handles rate limiting and retry logic for a github API call. It gives up once ctx is done,
+// including while waiting to retry or for the rate limit to reset.
+func (c *Client) retry(ctx context.Context, action string, call func() (*github.Response, error)) (*github.Response, error) {
 	var err error
 	var resp *github.Response
 
 	for retryCount := 0; retryCount <= c.retries; retryCount++ {
 		if resp, err = call(); err == nil {
-			c.limitRate(&resp.Rate)
+			if err := c.limitRate(ctx, &resp.Rate); err != nil {
+				return resp, err
+			}
 			return resp, nil
 		}
+		if ctx.Err() != nil {
+			return resp, err
+		}
 		switch err := err.(type) {
 		case *github.RateLimitError:
-			c.limitRate(&err.Rate)
+			if err := c.limitRate(ctx, &err.Rate); err != nil {
+				return resp, err
+			}
 		case *github.TwoFactorAuthError:
 			return resp, err
 		case *retryAbort:
 			return resp, err
 		}
+		if IsNotFound(err) {
+			// Retrying won't make the resource exist.
+			return resp, err
+		}
 
 		if retryCount == c.retries {
 			return resp, err
 		}
 		glog.Errorf("error %s: %v. Will retry.\n", action, err)
-		c.sleepForAttempt(retryCount)
+		if err := c.sleepForAttempt(ctx, retryCount); err != nil {
+			return resp, err
+		}
 	}
 	return resp, err
 }
 
 // depaginate adds depagination on top of the retry and rate limiting logic provided by retry.
-func (c *Client) depaginate(action string, opts *github.ListOptions, call func() ([]interface{}, *github.Response, error)) ([]interface{}, error) {
+func (c *Client) depaginate(ctx context.Context, action string, opts *github.ListOptions, call func() ([]interface{}, *github.Response, error)) ([]interface{}, error) {
 	var allItems []interface{}
 	wrapper := func() (*github.Response, error) {
 		items, resp, err := call()
@@ -151,7 +214,7 @@ func (c *Client) depaginate(action string, opts *github.ListOptions, call func()
 	opts.PerPage = 100
 	lastPage := 1
 	for ; opts.Page <= lastPage; opts.Page++ {
-		resp, err := c.retry(action, wrapper)
+		resp, err := c.retry(ctx, action, wrapper)
 		if err != nil {
 			return allItems, fmt.Errorf("error while depaginating page %d/%d: %v", opts.Page, lastPage, err)
 		}
@@ -17,7 +17,9 @@ limitations under the License.
 package ghclient
 
 import (
+	"context"
 	"fmt"
+	"net/http"
 	"testing"
 	"time"
 
@@ -81,7 +83,7 @@ func TestRetryAndPagination(t *testing.T) {
 	for _, test := range tests {
 		client := &Client{}
 		setForTest(client)
-		pages, err := client.depaginate("retry test", &test.listOpts, test.call)
+		pages, err := client.depaginate(context.Background(), "retry test", &test.listOpts, test.call)
 		if (err == nil) != test.shouldSucceed {
 			t.Errorf("Retry+Pagination test '%s' failed because the error value was unexpected: %v", test.name, err)
 		}
@@ -93,3 +95,72 @@ func TestRetryAndPagination(t *testing.T) {
 		}
 	}
 }
+
+func TestRetryNotFound(t *testing.T) {
+	client := &Client{}
+	setForTest(client)
+	hits := 0
+	_, err := client.retry(context.Background(), "not found test", func() (*github.Response, error) {
+		hits++
+		return nil, &github.ErrorResponse{Response: &http.Response{StatusCode: http.StatusNotFound}}
+	})
+	if !IsNotFound(err) {
+		t.Errorf("expected a not found error, got %v", err)
+	}
+	if hits != 1 {
+		t.Errorf("expected a not found error not to be retried, got %d hits", hits)
+	}
+	if IsNotFound(fmt.Errorf("some other error")) {
+		t.Errorf("IsNotFound returned true for an unrelated error")
+	}
+}
+
+func TestRetryCancelled(t *testing.T) {
+	client := &Client{}
+	setForTest(client)
+	client.retryInitialBackoff = time.Hour
+	ctx, cancel := context.WithCancel(context.Background())
+	hits := 0
+	called := make(chan bool, 1)
+	done := make(chan error)
+	go func() {
+		_, err := client.retry(ctx, "cancel test", func() (*github.Response, error) {
+			hits++
+			called <- true
+			return nil, fmt.Errorf("server error")
+		})
+		done <- err
+	}()
+	<-called
+	cancel()
+	select {
+	case err := <-done:
+		if err != context.Canceled {
+			t.Errorf("expected the retry to stop with %v, got %v", context.Canceled, err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatalf("the retry did not stop waiting to retry when its context was cancelled")
+	}
+	if hits != 1 {
+		t.Errorf("expected no retries after cancellation, got %d hits", hits)
+	}
+}
+
+func TestParseEndpoint(t *testing.T) {
+	tests := map[string]string{
+		"https://api.github.com":                  "https://api.github.com/",
+		"https://github.example.com/api/v3":       "https://github.example.com/api/v3/",
+		"https://github.example.com/api/uploads/": "https://github.example.com/api/uploads/",
+	}
+	for endpoint, expected := range tests {
+		u, err := parseEndpoint(endpoint)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", endpoint, err)
+		} else if u.String() != expected {
+			t.Errorf("%s: expected %q, got %q", endpoint, expected, u.String())
+		}
+	}
+	if _, err := parseEndpoint("://bad"); err == nil {
+		t.Error("expected an error for an invalid endpoint")
+	}
+}
@@ -32,7 +32,10 @@ import (
 
 type issueService interface {
 	Create(ctx context.Context, owner string, repo string, issue *github.IssueRequest) (*github.Issue, *github.Response, error)
+	Edit(ctx context.Context, owner string, repo string, number int, issue *github.IssueRequest) (*github.Issue, *github.Response, error)
 	ListByRepo(ctx context.Context, org, repo string, opt *github.IssueListByRepoOptions) ([]*github.Issue, *github.Response, error)
+	ListComments(ctx context.Context, owner string, repo string, number int, opt *github.IssueListCommentsOptions) ([]*github.IssueComment, *github.Response, error)
+	CreateComment(ctx context.Context, owner string, repo string, number int, comment *github.IssueComment) (*github.IssueComment, *github.Response, error)
 	ListLabels(ctx context.Context, owner, repo string, opt *github.ListOptions) ([]*github.Label, *github.Response, error)
 }
 
@@ -50,8 +53,13 @@ type usersService interface {
 	Get(ctx context.Context, login string) (*github.User, *github.Response, error)
 }
 
+type organizationsService interface {
+	ListTeams(ctx context.Context, org string, opt *github.ListOptions) ([]*github.Team, *github.Response, error)
+	ListTeamMembers(ctx context.Context, team int64, opt *github.OrganizationListTeamMembersOptions) ([]*github.User, *github.Response, error)
+}
+
 // CreateIssue tries to create and return a new github issue.
-func (c *Client) CreateIssue(org, repo, title, body string, labels, assignees []string) (*github.Issue, error) {
+func (c *Client) CreateIssue(ctx context.Context, org, repo, title, body string, labels, assignees []string) (*github.Issue, error) {
 	glog.Infof("CreateIssue(dry=%t) Title:%q, Labels:%q, Assignees:%q\n", c.dryRun, title, labels, assignees)
 	if c.dryRun {
 		return nil, nil
@@ -69,12 +77,52 @@ func (c *Client) CreateIssue(org, repo, title, body string, labels, assignees []
 	}
 
 	var result *github.Issue
-	_, err := c.retry(
+	_, err := c.retry(ctx,
 		fmt.Sprintf("creating issue '%s'", title),
 		func() (*github.Response, error) {
 			var resp *github.Response
 			var err error
-			result, resp, err = c.issueService.Create(context.Background(), org, repo, issue)
+			result, resp, err = c.issueService.Create(ctx, org, repo, issue)
+			return resp, err
+		},
+	)
+	return result, err
+}
+
+// EditIssue tries to apply the changes in issue to an existing github issue and returns the result.
+func (c *Client) EditIssue(ctx context.Context, org, repo string, number int, issue *github.IssueRequest) (*github.Issue, error) {
+	glog.Infof("EditIssue(dry=%t) Number:%d\n", c.dryRun, number)
+	if c.dryRun {
+		return nil, nil
+	}
+
+	var result *github.Issue
+	_, err := c.retry(ctx,
+		fmt.Sprintf("editing issue #%d", number),
+		func() (*github.Response, error) {
+			var resp *github.Response
+			var err error
+			result, resp, err = c.issueService.Edit(ctx, org, repo, number, issue)
+			return resp, err
+		},
+	)
+	return result, err
+}
+
+// CreateComment tries to add a comment to a github issue or PR and returns the new comment.
+func (c *Client) CreateComment(ctx context.Context, org, repo string, number int, body string) (*github.IssueComment, error) {
+	glog.Infof("CreateComment(dry=%t) Number:%d\n", c.dryRun, number)
+	if c.dryRun {
+		return nil, nil
+	}
+
+	var result *github.IssueComment
+	_, err := c.retry(ctx,
+		fmt.Sprintf("commenting on #%d", number),
+		func() (*github.Response, error) {
+			var resp *github.Response
+			var err error
+			result, resp, err = c.issueService.CreateComment(ctx, org, repo, number, &github.IssueComment{Body: &body})
 			return resp, err
 		},
 	)
@@ -82,17 +130,17 @@ func (c *Client) CreateIssue(org, repo, title, body string, labels, assignees []
 }
 
 // CreateStatus creates or updates a status context on the indicated reference.
-func (c *Client) CreateStatus(owner, repo, ref string, status *github.RepoStatus) (*github.RepoStatus, error) {
+func (c *Client) CreateStatus(ctx context.Context, owner, repo, ref string, status *github.RepoStatus) (*github.RepoStatus, error) {
 	glog.Infof("CreateStatus(dry=%t) ref:%s: %s:%s", c.dryRun, ref, *status.Context, *status.State)
 	if c.dryRun {
 		return nil, nil
 	}
 	var result *github.RepoStatus
 	msg := fmt.Sprintf("creating status for ref '%s'", ref)
-	_, err := c.retry(msg, func() (*github.Response, error) {
+	_, err := c.retry(ctx, msg, func() (*github.Response, error) {
 		var resp *github.Response
 		var err error
-		result, resp, err = c.repoService.CreateStatus(context.Background(), owner, repo, ref, status)
+		result, resp, err = c.repoService.CreateStatus(ctx, owner, repo, ref, status)
 		return resp, err
 	})
 	return result, err
@@ -103,16 +151,16 @@ type PRMungeFunc func(*github.PullRequest) error
 // ForEachPR iterates over all PRs that fit the specified criteria, calling the munge function on every PR.
 // If the munge function returns a non-nil error, ForEachPR will return immediately with a non-nil
 // error unless continueOnError is true in which case an error will be logged and the remaining PRs will be munged.
-func (c *Client) ForEachPR(owner, repo string, opts *github.PullRequestListOptions, continueOnError bool, mungePR PRMungeFunc) error {
+func (c *Client) ForEachPR(ctx context.Context, owner, repo string, opts *github.PullRequestListOptions, continueOnError bool, mungePR PRMungeFunc) error {
 	var lastPage int
 	// Munge each page as we get it (or in other words, wait until we are ready to munge the next
 	// page of issues before getting it). We use depaginate to make the calls, but don't care about
 	// the slice it returns since we consume the pages as we go.
-	_, err := c.depaginate(
+	_, err := c.depaginate(ctx,
 		"processing PRs",
 		&opts.ListOptions,
 		func() ([]interface{}, *github.Response, error) {
-			list, resp, err := c.prService.List(context.Background(), owner, repo, opts)
+			list, resp, err := c.prService.List(ctx, owner, repo, opts)
 			if err == nil {
 				for _, pr := range list {
 					if pr == nil {
@@ -142,13 +190,13 @@ func (c *Client) ForEachPR(owner, repo string, opts *github.PullRequestListOptio
 }
 
 // GetCollaborators returns all github users who are members or outside collaborators of the repo.
-func (c *Client) GetCollaborators(org, repo string) ([]*github.User, error) {
+func (c *Client) GetCollaborators(ctx context.Context, org, repo string) ([]*github.User, error) {
 	opts := &github.ListCollaboratorsOptions{}
-	collaborators, err := c.depaginate(
+	collaborators, err := c.depaginate(ctx,
 		fmt.Sprintf("getting collaborators for '%s/%s'", org, repo),
 		&opts.ListOptions,
 		func() ([]interface{}, *github.Response, error) {
-			page, resp, err := c.repoService.ListCollaborators(context.Background(), org, repo, opts)
+			page, resp, err := c.repoService.ListCollaborators(ctx, org, repo, opts)
 
 			var interfaceList []interface{}
 			if err == nil {
@@ -169,16 +217,16 @@ func (c *Client) GetCollaborators(org, repo string) ([]*github.User, error) {
 }
 
 // GetCombinedStatus retrieves the CombinedStatus for the specified reference.
-func (c *Client) GetCombinedStatus(owner, repo, ref string) (*github.CombinedStatus, error) {
+func (c *Client) GetCombinedStatus(ctx context.Context, owner, repo, ref string) (*github.CombinedStatus, error) {
 	var result *github.CombinedStatus
 	listOpts := &github.ListOptions{}
 
-	statuses, err := c.depaginate(
+	statuses, err := c.depaginate(ctx,
 		fmt.Sprintf("getting combined status for ref '%s'", ref),
 		listOpts,
 		func() ([]interface{}, *github.Response, error) {
 			combined, resp, err := c.repoService.GetCombinedStatus(
-				context.Background(),
+				ctx,
 				owner,
 				repo,
 				ref,
@@ -210,12 +258,12 @@ func (c *Client) GetCombinedStatus(owner, repo, ref string) (*github.CombinedSta
 }
 
 // GetIssues gets all the issues in a repo that meet the list options.
-func (c *Client) GetIssues(org, repo string, opts *github.IssueListByRepoOptions) ([]*github.Issue, error) {
-	issues, err := c.depaginate(
+func (c *Client) GetIssues(ctx context.Context, org, repo string, opts *github.IssueListByRepoOptions) ([]*github.Issue, error) {
+	issues, err := c.depaginate(ctx,
 		fmt.Sprintf("getting issues from '%s/%s'", org, repo),
 		&opts.ListOptions,
 		func() ([]interface{}, *github.Response, error) {
-			page, resp, err := c.issueService.ListByRepo(context.Background(), org, repo, opts)
+			page, resp, err := c.issueService.ListByRepo(ctx, org, repo, opts)
 
 			var interfaceList []interface{}
 			if err == nil {
@@ -235,14 +283,41 @@ func (c *Client) GetIssues(org, repo string, opts *github.IssueListByRepoOptions
 	return result, err
 }
 
+// GetIssueComments gets all the comments on a github issue or PR, oldest first.
+func (c *Client) GetIssueComments(ctx context.Context, org, repo string, number int) ([]*github.IssueComment, error) {
+	opts := &github.IssueListCommentsOptions{}
+	comments, err := c.depaginate(ctx,
+		fmt.Sprintf("getting comments on '%s/%s#%d'", org, repo, number),
+		&opts.ListOptions,
+		func() ([]interface{}, *github.Response, error) {
+			page, resp, err := c.issueService.ListComments(ctx, org, repo, number, opts)
+
+			var interfaceList []interface{}
+			if err == nil {
+				interfaceList = make([]interface{}, 0, len(page))
+				for _, comment := range page {
+					interfaceList = append(interfaceList, comment)
+				}
+			}
+			return interfaceList, resp, err
+		},
+	)
+
+	result := make([]*github.IssueComment, 0, len(comments))
+	for _, comment := range comments {
+		result = append(result, comment.(*github.IssueComment))
+	}
+	return result, err
+}
+
 // GetRepoLabels gets all the labels that valid in the specified repo.
-func (c *Client) GetRepoLabels(org, repo string) ([]*github.Label, error) {
+func (c *Client) GetRepoLabels(ctx context.Context, org, repo string) ([]*github.Label, error) {
 	opts := &github.ListOptions{}
-	labels, err := c.depaginate(
+	labels, err := c.depaginate(ctx,
 		fmt.Sprintf("getting valid labels for '%s/%s'", org, repo),
 		opts,
 		func() ([]interface{}, *github.Response, error) {
-			page, resp, err := c.issueService.ListLabels(context.Background(), org, repo, opts)
+			page, resp, err := c.issueService.ListLabels(ctx, org, repo, opts)
 
 			var interfaceList []interface{}
 			if err == nil {
@@ -262,16 +337,75 @@ func (c *Client) GetRepoLabels(org, repo string) ([]*github.Label, error) {
 	return result, err
 }
 
+// GetTeamMembers returns all members of the org's team with the specified slug (or name).
+func (c *Client) GetTeamMembers(ctx context.Context, org, team string) ([]*github.User, error) {
+	listOpts := &github.ListOptions{}
+	teams, err := c.depaginate(ctx,
+		fmt.Sprintf("listing teams for '%s'", org),
+		listOpts,
+		func() ([]interface{}, *github.Response, error) {
+			page, resp, err := c.orgService.ListTeams(ctx, org, listOpts)
+
+			var interfaceList []interface{}
+			if err == nil {
+				interfaceList = make([]interface{}, 0, len(page))
+				for _, team := range page {
+					interfaceList = append(interfaceList, team)
+				}
+			}
+			return interfaceList, resp, err
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	var teamID *int64
+	for _, t := range teams {
+		t := t.(*github.Team)
+		if (t.Slug != nil && *t.Slug == team) || (t.Name != nil && *t.Name == team) {
+			teamID = t.ID
+			break
+		}
+	}
+	if teamID == nil {
+		return nil, fmt.Errorf("team '%s' not found in org '%s'", team, org)
+	}
+
+	opts := &github.OrganizationListTeamMembersOptions{}
+	members, err := c.depaginate(ctx,
+		fmt.Sprintf("getting members of team '%s/%s'", org, team),
+		&opts.ListOptions,
+		func() ([]interface{}, *github.Response, error) {
+			page, resp, err := c.orgService.ListTeamMembers(ctx, *teamID, opts)
+
+			var interfaceList []interface{}
+			if err == nil {
+				interfaceList = make([]interface{}, 0, len(page))
+				for _, user := range page {
+					interfaceList = append(interfaceList, user)
+				}
+			}
+			return interfaceList, resp, err
+		},
+	)
+
+	result := make([]*github.User, 0, len(members))
+	for _, user := range members {
+		result = append(result, user.(*github.User))
+	}
+	return result, err
+}
+
 // GetUser gets the github user with the specified login or the currently authenticated user.
 // To get the currently authenticated user specify a login of "".
-func (c *Client) GetUser(login string) (*github.User, error) {
+func (c *Client) GetUser(ctx context.Context, login string) (*github.User, error) {
 	var result *github.User
-	_, err := c.retry(
+	_, err := c.retry(ctx,
 		fmt.Sprintf("getting user '%s'", login),
 		func() (*github.Response, error) {
 			var resp *github.Response
 			var err error
-			result, resp, err = c.userService.Get(context.Background(), login)
+			result, resp, err = c.userService.Get(ctx, login)
 			return resp, err
 		},
 	)
@@ -19,6 +19,8 @@ package ghclient
 import (
 	"context"
 	"fmt"
+	"reflect"
+	"sort"
 	"testing"
 	"time"
 
@@ -54,19 +56,19 @@ func TestGetUser(t *testing.T) {
 	client := &Client{userService: newFakeUserService("me", []string{"a", "b", "c"})}
 	setForTest(client)
 	// try getting the currently authenticated user
-	if user, err := client.GetUser(""); err != nil {
+	if user, err := client.GetUser(context.Background(), ""); err != nil {
 		t.Errorf("Unexpected error from GetUser(\"\"): %v.", err)
 	} else if *user.Login != "me" {
 		t.Errorf("GetUser(\"\") returned user %q instead of \"me\".", *user.Login)
 	}
 	// try getting another user
-	if user, err := client.GetUser("b"); err != nil {
+	if user, err := client.GetUser(context.Background(), "b"); err != nil {
 		t.Errorf("Unexpected error from GetUser(\"b\"): %v.", err)
 	} else if *user.Login != "b" {
 		t.Errorf("GetUser(\"b\") returned user %q instead of \"b\".", *user.Login)
 	}
 	// try getting an invalid user
-	if user, err := client.GetUser("d"); err == nil {
+	if user, err := client.GetUser(context.Background(), "d"); err == nil {
 		t.Errorf("Expected error from GetUser(\"d\") (invalid user), but did not get an error.")
 	} else if user != nil {
 		t.Error("Got a user even though GetUser(\"d\") (invalid user) returned a nil user.")
@@ -166,7 +168,7 @@ func TestCreateStatus(t *testing.T) {
 	svc := newFakeRepoService("k8s", "kuber", "ref", 0, nil)
 	client := &Client{repoService: svc}
 	setForTest(client)
-	status, err := client.CreateStatus("k8s", "kuber", "ref", sampleStatus)
+	status, err := client.CreateStatus(context.Background(), "k8s", "kuber", "ref", sampleStatus)
 	if err != nil {
 		t.Fatalf("Unexpected error from CreateStatus with valid args: %v", err)
 	}
@@ -186,7 +188,7 @@ func TestCreateStatus(t *testing.T) {
 		t.Errorf("Expected RepoStatus from CreateStatus to have a target URL of '%s' instead of '%s'", urlStr, *status.TargetURL)
 	}
 
-	_, err = client.CreateStatus("k8s", "kuber", "ref2", sampleStatus)
+	_, err = client.CreateStatus(context.Background(), "k8s", "kuber", "ref2", sampleStatus)
 	if err == nil {
 		t.Error("Expected error from CreateStatus on invalid ref, but didn't get an error.")
 	}
@@ -196,7 +198,7 @@ func TestGetCombinedStatus(t *testing.T) {
 	svc := newFakeRepoService("k8s", "kuber", "ref", 6, nil)
 	client := &Client{repoService: svc}
 	setForTest(client)
-	combStatus, err := client.GetCombinedStatus("k8s", "kuber", "ref")
+	combStatus, err := client.GetCombinedStatus(context.Background(), "k8s", "kuber", "ref")
 	if err != nil {
 		t.Fatalf("Unexpected error from GetCombinedStatus on valid args: %v", err)
 	}
@@ -218,7 +220,7 @@ func TestGetCombinedStatus(t *testing.T) {
 			t.Errorf("Expected status at index %d to have a context of '%s' instead of '%s'.", index, expectedContext, *status.Context)
 		}
 	}
-	if _, err = client.GetCombinedStatus("k8s", "kuber", "ref2"); err == nil {
+	if _, err = client.GetCombinedStatus(context.Background(), "k8s", "kuber", "ref2"); err == nil {
 		t.Error("Expected error getting CombinedStatus for non-existent ref, but got none.")
 	}
 }
@@ -230,7 +232,7 @@ func TestGetCollaborators(t *testing.T) {
 	expected := []string{"a", "b", "c", "d"}
 	client := &Client{repoService: newFakeRepoService("k8s", "kuber", "", 0, expected)}
 	setForTest(client)
-	if users, err = client.GetCollaborators("k8s", "kuber"); err != nil {
+	if users, err = client.GetCollaborators(context.Background(), "k8s", "kuber"); err != nil {
 		t.Errorf("Unexpected error from GetCollaborators on valid org and repo: %v.", err)
 	} else {
 		for _, expect := range expected {
@@ -250,7 +252,7 @@ func TestGetCollaborators(t *testing.T) {
 		}
 	}
 	// test invalid repo
-	if users, err = client.GetCollaborators("not-an-org", "not a repo"); err == nil {
+	if users, err = client.GetCollaborators(context.Background(), "not-an-org", "not a repo"); err == nil {
 		t.Error("Expected error from GetCollaborators, but did not get an error.")
 	}
 	if len(users) > 0 {
@@ -259,7 +261,7 @@ func TestGetCollaborators(t *testing.T) {
 	// test empty list
 	client = &Client{repoService: newFakeRepoService("k8s", "kuber", "", 0, nil)}
 	setForTest(client)
-	if users, err = client.GetCollaborators("k8s", "kuber"); err != nil {
+	if users, err = client.GetCollaborators(context.Background(), "k8s", "kuber"); err != nil {
 		t.Errorf("Unexpected error from GetCollaborators on valid org and repo: %v.", err)
 	}
 	if len(users) > 0 {
@@ -267,10 +269,96 @@ func TestGetCollaborators(t *testing.T) {
 	}
 }
 
+// fakeOrgService serves 1 team and 2 team members per page of results (served in order).
+type fakeOrgService struct {
+	org     string
+	teams   []*github.Team
+	members map[int64][]*github.User
+}
+
+func newFakeOrgService(org string, teams map[string][]string) *fakeOrgService {
+	f := &fakeOrgService{org: org, members: map[int64][]*github.User{}}
+	slugs := make([]string, 0, len(teams))
+	for slug := range teams {
+		slugs = append(slugs, slug)
+	}
+	sort.Strings(slugs)
+	for i, slug := range slugs {
+		id := int64(i + 1)
+		slugCopy := slug
+		f.teams = append(f.teams, &github.Team{ID: &id, Slug: &slugCopy})
+		for _, member := range teams[slug] {
+			memberCopy := member
+			f.members[id] = append(f.members[id], &github.User{Login: &memberCopy})
+		}
+	}
+	return f
+}
+
+func (f *fakeOrgService) ListTeams(ctx context.Context, org string, opt *github.ListOptions) ([]*github.Team, *github.Response, error) {
+	resp := &github.Response{
+		Rate:     github.Rate{Limit: 5000, Remaining: 1000, Reset: github.Timestamp{Time: time.Now()}},
+		LastPage: len(f.teams),
+	}
+	if org != f.org {
+		return nil, resp, fmt.Errorf("org '%s' not recognized, only '%s' is valid", org, f.org)
+	}
+	if len(f.teams) == 0 {
+		return nil, resp, nil
+	}
+	return []*github.Team{f.teams[opt.Page-1]}, resp, nil
+}
+
+func (f *fakeOrgService) ListTeamMembers(ctx context.Context, team int64, opt *github.OrganizationListTeamMembersOptions) ([]*github.User, *github.Response, error) {
+	members := f.members[team]
+	resp := &github.Response{
+		Rate:     github.Rate{Limit: 5000, Remaining: 1000, Reset: github.Timestamp{Time: time.Now()}},
+		LastPage: (len(members) + 1) / 2,
+	}
+	start := (opt.Page - 1) * 2
+	end := start + 2
+	if end > len(members) {
+		end = len(members)
+	}
+	if start >= end {
+		return nil, resp, nil
+	}
+	return members[start:end], resp, nil
+}
+
+func TestGetTeamMembers(t *testing.T) {
+	client := &Client{orgService: newFakeOrgService("k8s", map[string][]string{
+		"empty":     nil,
+		"reviewers": {"a", "b", "c"},
+		"testers":   {"d"},
+	})}
+	setForTest(client)
+
+	users, err := client.GetTeamMembers(context.Background(), "k8s", "reviewers")
+	if err != nil {
+		t.Fatalf("Unexpected error from GetTeamMembers: %v.", err)
+	}
+	var logins []string
+	for _, user := range users {
+		logins = append(logins, *user.Login)
+	}
+	if !reflect.DeepEqual(logins, []string{"a", "b", "c"}) {
+		t.Errorf("Expected team members [a b c], but got %v.", logins)
+	}
+
+	if users, err = client.GetTeamMembers(context.Background(), "k8s", "empty"); err != nil || len(users) > 0 {
+		t.Errorf("Expected no members and no error for an empty team, got %v and %v.", users, err)
+	}
+	if _, err = client.GetTeamMembers(context.Background(), "k8s", "not-a-team"); err == nil {
+		t.Error("Expected error from GetTeamMembers for an unknown team, but did not get an error.")
+	}
+}
+
 type fakeIssueService struct {
 	org, repo  string
 	repoLabels []*github.Label
 	repoIssues map[int]*github.Issue
+	comments   map[int][]*github.IssueComment
 }
 
 func newFakeIssueService(org, repo string, labels []string, issueCount int) *fakeIssueService {
@@ -321,6 +409,28 @@ func (f *fakeIssueService) Create(ctx context.Context, owner string, repo string
 	return result, resp, nil
 }
 
+func (f *fakeIssueService) Edit(ctx context.Context, owner string, repo string, number int, issue *github.IssueRequest) (*github.Issue, *github.Response, error) {
+	resp := &github.Response{Rate: github.Rate{Limit: 5000, Remaining: 1000, Reset: github.Timestamp{Time: time.Now()}}}
+	if owner != f.org || repo != f.repo {
+		return nil, resp, fmt.Errorf("repo '%s/%s' not recognized, only '%s/%s' is valid", owner, repo, f.org, f.repo)
+	}
+	result, ok := f.repoIssues[number]
+	if !ok {
+		return nil, resp, fmt.Errorf("issue #%d does not exist", number)
+	}
+	if issue.Body != nil {
+		result.Body = issue.Body
+	}
+	if issue.Labels != nil {
+		result.Labels = nil
+		for _, label := range *issue.Labels {
+			labelCopy := label
+			result.Labels = append(result.Labels, github.Label{Name: &labelCopy})
+		}
+	}
+	return result, resp, nil
+}
+
 // ListByRepo returns 2 issues per page of results (served in order by number).
 func (f *fakeIssueService) ListByRepo(ctx context.Context, org, repo string, opt *github.IssueListByRepoOptions) ([]*github.Issue, *github.Response, error) {
 	resp := &github.Response{
@@ -339,6 +449,35 @@ func (f *fakeIssueService) ListByRepo(ctx context.Context, org, repo string, opt
 	return []*github.Issue{f.repoIssues[(opt.ListOptions.Page*2)-1], f.repoIssues[opt.ListOptions.Page*2]}, resp, nil
 }
 
+// ListComments returns every comment on an issue in a single page.
+func (f *fakeIssueService) ListComments(ctx context.Context, owner string, repo string, number int, opt *github.IssueListCommentsOptions) ([]*github.IssueComment, *github.Response, error) {
+	resp := &github.Response{Rate: github.Rate{Limit: 5000, Remaining: 1000, Reset: github.Timestamp{Time: time.Now()}}}
+	if owner != f.org || repo != f.repo {
+		return nil, resp, fmt.Errorf("repo '%s/%s' not recognized, only '%s/%s' is valid", owner, repo, f.org, f.repo)
+	}
+	if _, ok := f.repoIssues[number]; !ok {
+		return nil, resp, fmt.Errorf("issue #%d does not exist", number)
+	}
+	return f.comments[number], resp, nil
+}
+
+func (f *fakeIssueService) CreateComment(ctx context.Context, owner string, repo string, number int, comment *github.IssueComment) (*github.IssueComment, *github.Response, error) {
+	resp := &github.Response{Rate: github.Rate{Limit: 5000, Remaining: 1000, Reset: github.Timestamp{Time: time.Now()}}}
+	if owner != f.org || repo != f.repo {
+		return nil, resp, fmt.Errorf("repo '%s/%s' not recognized, only '%s/%s' is valid", owner, repo, f.org, f.repo)
+	}
+	if _, ok := f.repoIssues[number]; !ok {
+		return nil, resp, fmt.Errorf("issue #%d does not exist", number)
+	}
+	if f.comments == nil {
+		f.comments = map[int][]*github.IssueComment{}
+	}
+	id := int64(len(f.comments[number]) + 1)
+	result := &github.IssueComment{ID: &id, Body: comment.Body}
+	f.comments[number] = append(f.comments[number], result)
+	return result, resp, nil
+}
+
 // ListLabels returns 2 labels per page or results (served in order).
 func (f *fakeIssueService) ListLabels(ctx context.Context, owner, repo string, opt *github.ListOptions) ([]*github.Label, *github.Response, error) {
 	resp := &github.Response{
@@ -357,6 +496,63 @@ func (f *fakeIssueService) ListLabels(ctx context.Context, owner, repo string, o
 	return []*github.Label{f.repoLabels[(opt.Page*2)-2], f.repoLabels[(opt.Page*2)-1]}, resp, nil
 }
 
+func TestEditIssue(t *testing.T) {
+	svc := newFakeIssueService("k8s", "kuber", nil, 3)
+	client := &Client{issueService: svc}
+	setForTest(client)
+	body := "New body"
+	labels := []string{"kind/flake", "lifecycle/stale"}
+	issue, err := client.EditIssue(context.Background(), "k8s", "kuber", 2, &github.IssueRequest{Body: &body, Labels: &labels})
+	if err != nil {
+		t.Fatalf("Unexpected error from EditIssue with valid args: %v.", err)
+	}
+	if *issue.Body != body {
+		t.Errorf("Expected the body of the edited issue to be %q instead of %q.", body, *issue.Body)
+	}
+	if len(issue.Labels) != 2 || *issue.Labels[1].Name != "lifecycle/stale" {
+		t.Errorf("Expected the edited issue to have the labels %q, got %v.", labels, issue.Labels)
+	}
+	if *svc.repoIssues[1].Body != "1" {
+		t.Errorf("Expected EditIssue to leave other issues unchanged.")
+	}
+
+	if _, err := client.EditIssue(context.Background(), "k8s", "kuber", 7, &github.IssueRequest{Body: &body}); err == nil {
+		t.Errorf("Expected an error from EditIssue for an issue that does not exist.")
+	}
+}
+
+func TestIssueComments(t *testing.T) {
+	svc := newFakeIssueService("k8s", "kuber", nil, 3)
+	client := &Client{issueService: svc}
+	setForTest(client)
+	for _, body := range []string{"first", "second"} {
+		comment, err := client.CreateComment(context.Background(), "k8s", "kuber", 2, body)
+		if err != nil {
+			t.Fatalf("Unexpected error from CreateComment with valid args: %v.", err)
+		}
+		if *comment.Body != body {
+			t.Errorf("Expected the new comment to have the body %q instead of %q.", body, *comment.Body)
+		}
+	}
+	comments, err := client.GetIssueComments(context.Background(), "k8s", "kuber", 2)
+	if err != nil {
+		t.Fatalf("Unexpected error from GetIssueComments with valid args: %v.", err)
+	}
+	if len(comments) != 2 || *comments[0].Body != "first" || *comments[1].Body != "second" {
+		t.Errorf("Expected the comments 'first' and 'second' in order, got %v.", comments)
+	}
+	if comments, err = client.GetIssueComments(context.Background(), "k8s", "kuber", 1); err != nil || len(comments) != 0 {
+		t.Errorf("Expected no comments on issue #1, got %v and error %v.", comments, err)
+	}
+
+	if _, err := client.CreateComment(context.Background(), "k8s", "kuber", 7, "body"); err == nil {
+		t.Errorf("Expected an error from CreateComment for an issue that does not exist.")
+	}
+	if _, err := client.GetIssueComments(context.Background(), "k8s", "kuber", 7); err == nil {
+		t.Errorf("Expected an error from GetIssueComments for an issue that does not exist.")
+	}
+}
+
 func TestCreateIssue(t *testing.T) {
 	expectedLabels := []string{"label1", "label2"}
 	expectedAssignees := []string{"user1", "user2"}
@@ -364,7 +560,7 @@ func TestCreateIssue(t *testing.T) {
 	svc := newFakeIssueService("k8s", "kuber", nil, 3)
 	client := &Client{issueService: svc}
 	setForTest(client)
-	issue, err := client.CreateIssue("k8s", "kuber", "Title", "Body", expectedLabels, expectedAssignees)
+	issue, err := client.CreateIssue(context.Background(), "k8s", "kuber", "Title", "Body", expectedLabels, expectedAssignees)
 	if err != nil {
 		t.Fatalf("Unexpected error from CreateIssue with valid args: %v.", err)
 	}
@@ -402,7 +598,7 @@ func TestCreateIssue(t *testing.T) {
 		}
 	}
 
-	_, err = client.CreateIssue("k8s", "not-a-repo", "Title", "Body", nil, nil)
+	_, err = client.CreateIssue(context.Background(), "k8s", "not-a-repo", "Title", "Body", nil, nil)
 	if err == nil {
 		t.Error("Expected error from CreateIssue on invalid repo, but didn't get an error.")
 	}
@@ -414,7 +610,7 @@ func TestGetIssues(t *testing.T) {
 	// test normal case
 	client := &Client{issueService: newFakeIssueService("k8s", "kuber", nil, 10)}
 	setForTest(client)
-	if issues, err = client.GetIssues("k8s", "kuber", &github.IssueListByRepoOptions{}); err != nil {
+	if issues, err = client.GetIssues(context.Background(), "k8s", "kuber", &github.IssueListByRepoOptions{}); err != nil {
 		t.Errorf("Unexpected error from GetIssues on valid org and repo: %v.", err)
 	} else {
 		for i := 1; i <= 10; i++ {
@@ -434,7 +630,7 @@ func TestGetIssues(t *testing.T) {
 		}
 	}
 	// test invalid repo
-	if issues, err = client.GetIssues("not-an-org", "not a repo", &github.IssueListByRepoOptions{}); err == nil {
+	if issues, err = client.GetIssues(context.Background(), "not-an-org", "not a repo", &github.IssueListByRepoOptions{}); err == nil {
 		t.Error("Expected error from GetIssues, but did not get an error.")
 	}
 	if len(issues) > 0 {
@@ -443,7 +639,7 @@ func TestGetIssues(t *testing.T) {
 	// test empty list
 	client = &Client{issueService: newFakeIssueService("k8s", "kuber", nil, 0)}
 	setForTest(client)
-	if issues, err = client.GetIssues("k8s", "kuber", &github.IssueListByRepoOptions{}); err != nil {
+	if issues, err = client.GetIssues(context.Background(), "k8s", "kuber", &github.IssueListByRepoOptions{}); err != nil {
 		t.Errorf("Unexpected error from GetIssues on valid org and repo: %v.", err)
 	}
 	if len(issues) > 0 {
@@ -458,7 +654,7 @@ func TestGetRepoLabels(t *testing.T) {
 	expected := []string{"a", "b", "c", "d"}
 	client := &Client{issueService: newFakeIssueService("k8s", "kuber", expected, 0)}
 	setForTest(client)
-	if labels, err = client.GetRepoLabels("k8s", "kuber"); err != nil {
+	if labels, err = client.GetRepoLabels(context.Background(), "k8s", "kuber"); err != nil {
 		t.Errorf("Unexpected error from GetRepoLabels on valid org and repo: %v.", err)
 	} else {
 		for _, expect := range expected {
@@ -478,7 +674,7 @@ func TestGetRepoLabels(t *testing.T) {
 		}
 	}
 	// test invalid repo
-	if labels, err = client.GetRepoLabels("not-an-org", "not a repo"); err == nil {
+	if labels, err = client.GetRepoLabels(context.Background(), "not-an-org", "not a repo"); err == nil {
 		t.Error("Expected error from GetRepoLabels, but did not get an error.")
 	}
 	if len(labels) > 0 {
@@ -487,7 +683,7 @@ func TestGetRepoLabels(t *testing.T) {
 	// test empty list
 	client = &Client{issueService: newFakeIssueService("k8s", "kuber", nil, 0)}
 	setForTest(client)
-	if labels, err = client.GetRepoLabels("k8s", "kuber"); err != nil {
+	if labels, err = client.GetRepoLabels(context.Background(), "k8s", "kuber"); err != nil {
 		t.Errorf("Unexpected error from GetRepoLabels on valid org and repo: %v.", err)
 	}
 	if len(labels) > 0 {
@@ -539,7 +735,7 @@ func TestForEachPR(t *testing.T) {
 		return nil
 	}
 	// Test normal run without errors.
-	err := client.ForEachPR("k8s", "kuber", &github.PullRequestListOptions{}, false, process)
+	err := client.ForEachPR(context.Background(), "k8s", "kuber", &github.PullRequestListOptions{}, false, process)
 	if err != nil {
 		t.Errorf("Unexpected error from ForEachPR: %v.", err)
 	}
@@ -550,7 +746,7 @@ func TestForEachPR(t *testing.T) {
 	// Test break on error.
 	processed = 0
 	svc.prCount = 16
-	err = client.ForEachPR("k8s", "kuber", &github.PullRequestListOptions{}, false, process)
+	err = client.ForEachPR(context.Background(), "k8s", "kuber", &github.PullRequestListOptions{}, false, process)
 	if err == nil {
 		t.Fatal("Expected error from ForEachPR after processing 13th PR, but got none.")
 	}
@@ -560,7 +756,7 @@ func TestForEachPR(t *testing.T) {
 
 	// Test continue on error.
 	processed = 0
-	err = client.ForEachPR("k8s", "kuber", &github.PullRequestListOptions{}, true, process)
+	err = client.ForEachPR(context.Background(), "k8s", "kuber", &github.PullRequestListOptions{}, true, process)
 	if err != nil {
 		t.Fatalf("Unexpected error from ForEachPR with continue-on-error enabled: %v", err)
 	}
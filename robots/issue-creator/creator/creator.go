@@ -18,11 +18,19 @@ package creator
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"os"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/google/go-github/github"
 	"k8s.io/test-infra/pkg/ghclient"
@@ -34,11 +42,15 @@ import (
 // RepoClient is the interface IssueCreator used to interact with github.
 // This interface is necessary for testing the IssueCreator with dependency injection.
 type RepoClient interface {
-	GetUser(login string) (*github.User, error)
-	GetRepoLabels(org, repo string) ([]*github.Label, error)
-	GetIssues(org, repo string, options *github.IssueListByRepoOptions) ([]*github.Issue, error)
-	CreateIssue(org, repo, title, body string, labels, owners []string) (*github.Issue, error)
-	GetCollaborators(org, repo string) ([]*github.User, error)
+	GetUser(ctx context.Context, login string) (*github.User, error)
+	GetRepoLabels(ctx context.Context, org, repo string) ([]*github.Label, error)
+	GetIssues(ctx context.Context, org, repo string, options *github.IssueListByRepoOptions) ([]*github.Issue, error)
+	CreateIssue(ctx context.Context, org, repo, title, body string, labels, owners []string) (*github.Issue, error)
+	EditIssue(ctx context.Context, org, repo string, number int, issue *github.IssueRequest) (*github.Issue, error)
+	CreateComment(ctx context.Context, org, repo string, number int, body string) (*github.IssueComment, error)
+	GetIssueComments(ctx context.Context, org, repo string, number int) ([]*github.IssueComment, error)
+	GetCollaborators(ctx context.Context, org, repo string) ([]*github.User, error)
+	GetTeamMembers(ctx context.Context, org, team string) ([]*github.User, error)
 }
 
 // gihubClient is an wrapper of ghclient.Client that implements the RepoClient interface.
@@ -47,22 +59,42 @@ type githubClient struct {
 	*ghclient.Client
 }
 
-func (c githubClient) GetUser(login string) (*github.User, error) {
-	return c.Client.GetUser(login)
+func (c githubClient) GetUser(ctx context.Context, login string) (*github.User, error) {
+	return c.Client.GetUser(ctx, login)
 }
 
-func (c githubClient) GetRepoLabels(org, repo string) ([]*github.Label, error) {
-	return c.Client.GetRepoLabels(org, repo)
+func (c githubClient) GetRepoLabels(ctx context.Context, org, repo string) ([]*github.Label, error) {
+	return c.Client.GetRepoLabels(ctx, org, repo)
 }
 
-func (c githubClient) GetIssues(org, repo string, options *github.IssueListByRepoOptions) ([]*github.Issue, error) {
-	return c.Client.GetIssues(org, repo, options)
+func (c githubClient) GetIssues(ctx context.Context, org, repo string, options *github.IssueListByRepoOptions) ([]*github.Issue, error) {
+	return c.Client.GetIssues(ctx, org, repo, options)
 }
 
-func (c githubClient) CreateIssue(org, repo, title, body string, labels, owners []string) (*github.Issue, error) {
-	return c.Client.CreateIssue(org, repo, title, body, labels, owners)
+func (c githubClient) CreateIssue(ctx context.Context, org, repo, title, body string, labels, owners []string) (*github.Issue, error) {
+	return c.Client.CreateIssue(ctx, org, repo, title, body, labels, owners)
 }
 
+func (c githubClient) EditIssue(ctx context.Context, org, repo string, number int, issue *github.IssueRequest) (*github.Issue, error) {
+	return c.Client.EditIssue(ctx, org, repo, number, issue)
+}
+
+func (c githubClient) CreateComment(ctx context.Context, org, repo string, number int, body string) (*github.IssueComment, error) {
+	return c.Client.CreateComment(ctx, org, repo, number, body)
+}
+
+func (c githubClient) GetIssueComments(ctx context.Context, org, repo string, number int) ([]*github.IssueComment, error) {
+	return c.Client.GetIssueComments(ctx, org, repo, number)
+}
+
+func (c githubClient) GetTeamMembers(ctx context.Context, org, team string) ([]*github.User, error) {
+	return c.Client.GetTeamMembers(ctx, org, team)
+}
+
+// teamPrefix marks a test owner that is a GitHub team in the repo's org (e.g. "team:sig-node")
+// rather than a user. An assignee is picked from the team's members.
+const teamPrefix = "team:"
+
 // OwnerMapper finds an owner for a given test name.
 type OwnerMapper interface {
 	// TestOwner returns a GitHub username for a test, or "" if none are found.
@@ -72,6 +104,28 @@ type OwnerMapper interface {
 	TestSIG(testName string) string
 }
 
+// BatchOwnerMapper is implemented by OwnerMappers that can look up many tests at once more
+// efficiently than one at a time.
+type BatchOwnerMapper interface {
+	// BatchTestOwners returns the owner of each test, in the same order as testNames.
+	BatchTestOwners(testNames []string) []string
+	// BatchTestSIGs returns the SIG of each test, in the same order as testNames.
+	BatchTestSIGs(testNames []string) []string
+}
+
+// InfoOwnerMapper is implemented by OwnerMappers that know whether a test's owner should be
+// assigned to its issues or only mentioned.
+type InfoOwnerMapper interface {
+	// TestInfo returns the owner, SIG, and whether the owner should be assigned for a test.
+	TestInfo(testName string) testowner.OwnerInfo
+}
+
+// GapReporter is implemented by OwnerMappers that can report which tests lack an owner or SIG.
+type GapReporter interface {
+	// OwnershipGaps returns the tests from testNames without an owner and without a SIG.
+	OwnershipGaps(testNames []string) *testowner.OwnershipGaps
+}
+
 // Issue is an interface implemented by structs that can be synced with github issues via the IssueCreator.
 type Issue interface {
 	// Title yields the initial title text of the github issue.
@@ -97,7 +151,8 @@ type Issue interface {
 
 // IssueSource represents a source of auto-filed issues, such as triage-filer or flakyjob-reporter.
 type IssueSource interface {
-	Issues(*IssueCreator) ([]Issue, error)
+	// Issues returns the issues to sync. Fetches made by the source are cancelled with ctx.
+	Issues(ctx context.Context, c *IssueCreator) ([]Issue, error)
 	RegisterFlags()
 }
 
@@ -119,12 +174,38 @@ type IssueCreator struct {
 
 	// ownerPath is the path the test owners csv file or "" if no assignments or SIG areas should be used.
 	ownerPath string
+	// ownerConflicts is the policy for test owners rows that conflict: first-wins, error, or merge.
+	ownerConflicts string
+	// ownerMatching is how strictly test names must match the test owners data: exact,
+	// whitespace, normalized, punctuation, or fuzzy.
+	ownerMatching string
+	// ownerURL is the base URL of a remote ownership service to use instead of ownerPath.
+	ownerURL string
+	// ownerTimeout is the timeout for requests to the ownership service.
+	ownerTimeout time.Duration
+	// ownerCacheSize and ownerCacheTTL bound the cache of answers from the ownership service.
+	ownerCacheSize int
+	ownerCacheTTL  time.Duration
 	// maxSIGCount is the maximum number of SIG areas to include on a single github issue.
 	MaxSIGCount int
 	// maxAssignees is the maximum number of user to assign to a single github issue.
 	MaxAssignees int
 	// tokenFIle is the file containing the github authentication token to use.
 	tokenFile string
+	// githubEndpoint and githubUploadEndpoint are the API and upload URLs of github, which differ
+	// for GitHub Enterprise Server.
+	githubEndpoint       string
+	githubUploadEndpoint string
+	// proxyURL and caFile configure the transport used for outbound HTTP requests.
+	proxyURL string
+	caFile   string
+	// transport sends all outbound HTTP requests, including those to github.
+	transport http.RoundTripper
+	// fetchQPS and fetchBurst rate limit the HTTP requests that are not sent to github.
+	fetchQPS   float64
+	fetchBurst int
+	// fetchTransport wraps transport with the rate limit for requests not sent to github.
+	fetchTransport http.RoundTripper
 	// dryRun is true iff no modifying or 'write' operations should be made to github.
 	dryRun bool
 	// project is the name of the github repo.
@@ -132,8 +213,47 @@ type IssueCreator struct {
 	// org is the github organization that owns the repo.
 	org string
 
+	// LintOwners is true iff the test owners data should be checked for mistakes instead of syncing
+	// issues.
+	LintOwners bool
+	// RunOnce is true iff the process should exit with an error code when any part of the run
+	// failed, for running from a CronJob or periodic job.
+	RunOnce bool
+	// runErrors are the failures of the current run.
+	runErrors []string
+	// latestBuildTime, if set, is the RFC 3339 time to use as the end of the window instead of the
+	// latest build in the data.
+	latestBuildTime string
+
+	// ReplayDir is a directory of dated triage snapshots to replay instead of syncing issues.
+	ReplayDir string
+
+	// enabledSources are the names of the sources to run, or empty to run every source.
+	enabledSources string
+	// disabledSourcesFile names a file listing sources to skip, one per line. It is re-read before
+	// each source runs, so that a misbehaving source can be turned off during a run or between
+	// runs without a redeploy.
+	disabledSourcesFile string
+	// stallTimeout is how long the IssueCreator may go without progress before /healthz fails.
+	stallTimeout time.Duration
+	// health tracks initialization and progress for the health handlers.
+	health healthState
+	// runTokenFile is the file containing the bearer token for on-demand runs, or empty to
+	// disable them. runToken is its content.
+	runTokenFile string
+	runToken     string
+	// runLock is held for a whole run, so that on-demand runs wait for the current run.
+	runLock sync.Mutex
+	// decisions receives a line for every decision made during an on-demand run, or is nil.
+	decisions io.Writer
+
 	// Owners is an OwnerMapper that maps test names to owners and SIG areas.
 	Owners OwnerMapper
+	// teamMembers caches the lowercase logins of the members of each team named by a test owner.
+	teamMembers map[string][]string
+	// lookupCtx is the context of the current run, for the owner lookups that are made through
+	// methods like TestOwner that sources call without one.
+	lookupCtx context.Context
 }
 
 var sources = map[string]IssueSource{}
@@ -147,7 +267,7 @@ func RegisterSourceOrDie(name string, src IssueSource) {
 	glog.Infof("Registered issue source '%s'.", name)
 }
 
-func (c *IssueCreator) initialize() error {
+func (c *IssueCreator) initialize(ctx context.Context) error {
 	if c.org == "" {
 		return errors.New("'--org' is a required flag")
 	}
@@ -162,61 +282,249 @@ func (c *IssueCreator) initialize() error {
 		return fmt.Errorf("failed to read token file '%s': %v", c.tokenFile, err)
 	}
 	token := strings.TrimSpace(string(b))
+	if c.runTokenFile != "" {
+		b, err := ioutil.ReadFile(c.runTokenFile)
+		if err != nil {
+			return fmt.Errorf("failed to read run token file '%s': %v", c.runTokenFile, err)
+		}
+		if c.runToken = strings.TrimSpace(string(b)); c.runToken == "" {
+			return fmt.Errorf("run token file '%s' is empty", c.runTokenFile)
+		}
+	}
 
-	c.client = RepoClient(githubClient{ghclient.NewClient(token, c.dryRun)})
+	transport, err := newTransport(c.proxyURL, c.caFile)
+	if err != nil {
+		return err
+	}
+	c.transport = transport
+	c.fetchTransport = newRateLimitedTransport(c.fetchQPS, c.fetchBurst, transport)
+	c.client = RepoClient(githubClient{ghclient.NewEnterpriseClient(c.githubEndpoint, c.githubUploadEndpoint, token, c.dryRun, &githubTransport{next: transport})})
+	if err := c.initOwners(ctx); err != nil {
+		return err
+	}
+	return c.loadCache(ctx)
+}
 
-	if c.ownerPath == "" {
+// initOwners sets up the OwnerMapper from the test owners flags.
+func (c *IssueCreator) initOwners(ctx context.Context) error {
+	var err error
+	// Accept file:// URLs for the CSV so that it can be configured like the other data sources.
+	c.ownerPath = strings.TrimPrefix(c.ownerPath, "file://")
+	if c.ownerPath != "" && c.ownerURL != "" {
+		return errors.New("only one of '--test-owners-csv' and '--test-owners-url' may be specified")
+	}
+	if c.ownerURL != "" {
+		service := testowner.NewOwnerService(c.ownerURL, c.ownerTimeout)
+		service.EnableCache(c.ownerCacheSize, c.ownerCacheTTL)
+		service.SetTransport(c.Transport())
+		service.SetContext(ctx)
+		c.Owners = service
+	} else if c.ownerPath == "" {
 		c.Owners = nil
 	} else {
-		var err error
-		if c.Owners, err = testowner.NewReloadingOwnerList(c.ownerPath); err != nil {
+		var opts testowner.Options
+		if opts.Conflicts, err = testowner.ParseConflictPolicy(c.ownerConflicts); err != nil {
+			return err
+		}
+		if opts.Matching, err = testowner.ParseMatchMode(c.ownerMatching); err != nil {
+			return err
+		}
+		if c.Owners, err = testowner.NewReloadingOwnerListWithOptions(c.ownerPath, opts); err != nil {
 			return err
 		}
 	}
-
-	return c.loadCache()
+	return nil
 }
 
 // CreateAndSync is the main workhorse function of IssueCreator. It initializes the IssueCreator,
-// asks each source for its issues to sync, and syncs the issues.
-func (c *IssueCreator) CreateAndSync() {
+// asks each source for its issues to sync, and syncs the issues. It stops early once ctx is done.
+// An error listing the failures of the run is returned if anything failed or the run was stopped.
+func (c *IssueCreator) CreateAndSync(ctx context.Context) error {
 	var err error
-	if err = c.initialize(); err != nil {
+	if err = c.Validate(); err != nil {
+		glog.Fatalf("Invalid configuration, %v", err)
+	}
+	// Initializing replaces the cache of issues, so an on-demand run can't be in progress.
+	c.runLock.Lock()
+	err = c.initialize(ctx)
+	c.runLock.Unlock()
+	if err != nil {
 		glog.Fatalf("Error initializing IssueCreator: %v.", err)
 	}
 	glog.Info("IssueCreator initialization complete.")
+	c.health.markReady()
+	return c.syncSources(ctx)
+}
 
+// syncSources runs every enabled source. runLock is held for the whole pass, so that an on-demand
+// run can't clear the failures recorded so far or change the dry run setting between sources.
+func (c *IssueCreator) syncSources(ctx context.Context) error {
+	c.runLock.Lock()
+	defer c.runLock.Unlock()
 	for srcName, src := range sources {
-		glog.Infof("Generating issues from source: %s.", srcName)
-		var issues []Issue
-		if issues, err = src.Issues(c); err != nil {
-			glog.Errorf("Error generating issues. Source: %s Msg: %v.", srcName, err)
+		if ctx.Err() != nil {
+			glog.Warningf("source=%s msg=Stopping before the source: %v.", srcName, ctx.Err())
+			return ctx.Err()
+		}
+		if !c.sourceEnabled(srcName) {
+			glog.Infof("source=%s msg=Skipping the disabled source.", srcName)
 			continue
 		}
+		c.runSource(ctx, srcName, src)
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return c.runError()
+}
+
+// RecordError records a failure of the current run that a source worked around, e.g. a failed
+// update of an issue, so that the run still ends with an error.
+func (c *IssueCreator) RecordError(err error) {
+	c.runErrors = append(c.runErrors, err.Error())
+}
 
-		// Note: We assume that no issues made by this bot with ID's matching issues generated by
-		// sources will be created while this code is creating issues. If this is a possibility then
-		// this loop should be updated to fetch recently changed issues from github after every issue
-		// sync that results in an issue being created.
-		glog.Infof("Syncing issues from source: %s.", srcName)
-		created := 0
-		for _, issue := range issues {
-			if c.sync(issue) {
-				created++
+// runError returns an error listing the failures recorded since the last call, or nil.
+func (c *IssueCreator) runError() error {
+	errs := c.runErrors
+	c.runErrors = nil
+	if len(errs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%d failures: %s", len(errs), strings.Join(errs, "; "))
+}
+
+// runSource asks a source for its issues and syncs them. It stops early once ctx is done.
+func (c *IssueCreator) runSource(ctx context.Context, srcName string, src IssueSource) {
+	c.lookupCtx = ctx
+	defer func() { c.lookupCtx = nil }()
+	glog.Infof("source=%s msg=Generating issues.", srcName)
+	c.health.markProgress()
+	start := time.Now()
+	issues, err := src.Issues(ctx, c)
+	if err != nil {
+		glog.Errorf("source=%s msg=Error generating issues: %v.", srcName, err)
+		c.report("Error generating issues: %v.", err)
+		c.RecordError(fmt.Errorf("source %s: %v", srcName, err))
+		sourceErrors.WithLabelValues(srcName).Inc()
+		sourceDuration.WithLabelValues(srcName).Observe(time.Since(start).Seconds())
+		return
+	}
+
+	// Note: We assume that no issues made by this bot with ID's matching issues generated by
+	// sources will be created while this code is creating issues. If this is a possibility then
+	// this loop should be updated to fetch recently changed issues from github after every issue
+	// sync that results in an issue being created.
+	glog.Infof("source=%s msg=Syncing %d issues.", srcName, len(issues))
+	created := 0
+	for i, issue := range issues {
+		if ctx.Err() != nil {
+			glog.Warningf("source=%s msg=Stopping the sync with %d issues left unsynced: %v.", srcName, len(issues)-i, ctx.Err())
+			break
+		}
+		synced := c.sync(ctx, srcName, issue)
+		c.health.markProgress()
+		if synced {
+			created++
+			issuesSynced.WithLabelValues(srcName, "created").Inc()
+		} else {
+			issuesSynced.WithLabelValues(srcName, "not_created").Inc()
+		}
+	}
+	sourceDuration.WithLabelValues(srcName).Observe(time.Since(start).Seconds())
+	glog.Infof("source=%s msg=Created issues for %d of the %d issues synced.", srcName, created, len(issues))
+}
+
+// sourceEnabled returns true iff the source is enabled by '--sources' and is not listed in the
+// disabled sources file. If the file can't be read the source is enabled.
+func (c *IssueCreator) sourceEnabled(name string) bool {
+	if c.enabledSources != "" {
+		enabled := false
+		for _, src := range strings.Split(c.enabledSources, ",") {
+			if strings.TrimSpace(src) == name {
+				enabled = true
+				break
 			}
 		}
-		glog.Infof(
-			"Created issues for %d of the %d issues synced from source: %s.",
-			created,
-			len(issues),
-			srcName,
-		)
+		if !enabled {
+			return false
+		}
+	}
+	if c.disabledSourcesFile == "" {
+		return true
+	}
+	b, err := ioutil.ReadFile(c.disabledSourcesFile)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			glog.Errorf("Failed to read the disabled sources file '%s', treating every source as enabled: %v", c.disabledSourcesFile, err)
+		}
+		return true
+	}
+	for _, line := range strings.Split(string(b), "\n") {
+		line = strings.TrimSpace(line)
+		if line == name {
+			return false
+		}
+	}
+	return true
+}
+
+// LintTestOwners initializes the IssueCreator and writes the problems found in the test owners CSV
+// to w. The number of problems is returned.
+func (c *IssueCreator) LintTestOwners(ctx context.Context, w io.Writer) (int, error) {
+	if err := c.initialize(ctx); err != nil {
+		return 0, err
+	}
+	return c.lintTestOwners(ctx, w)
+}
+
+func (c *IssueCreator) lintTestOwners(ctx context.Context, w io.Writer) (int, error) {
+	if c.ownerPath == "" {
+		return 0, errors.New("'--test-owners-csv' is required to lint test owners")
+	}
+	mode, err := testowner.ParseMatchMode(c.ownerMatching)
+	if err != nil {
+		return 0, err
+	}
+	userExists := func(login string) (bool, error) { return c.userExists(ctx, login) }
+	linter := &testowner.Linter{Matching: mode, UserExists: userExists}
+	// Every SIG needs a label, so the repo's sig/ labels are the known SIGs.
+	for _, label := range c.validLabels {
+		if strings.HasPrefix(label, "sig/") {
+			linter.KnownSIGs = append(linter.KnownSIGs, strings.TrimPrefix(label, "sig/"))
+		}
+	}
+
+	file, err := os.Open(c.ownerPath)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	problems, err := linter.Lint(file)
+	if err != nil {
+		return 0, fmt.Errorf("could not parse test owners at %s: %v", c.ownerPath, err)
+	}
+	for _, problem := range problems {
+		fmt.Fprintf(w, "%s: %v\n", c.ownerPath, problem)
+	}
+	return len(problems), nil
+}
+
+// userExists returns true iff the GitHub user exists.
+func (c *IssueCreator) userExists(ctx context.Context, login string) (bool, error) {
+	user, err := c.client.GetUser(ctx, login)
+	if err != nil {
+		if ghclient.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
 	}
+	return user != nil, nil
 }
 
 // loadCache loads the valid labels for the repo, the currently authenticated user, and the issue cache from github.
-func (c *IssueCreator) loadCache() error {
-	user, err := c.client.GetUser("")
+func (c *IssueCreator) loadCache(ctx context.Context) error {
+	user, err := c.client.GetUser(ctx, "")
 	if err != nil {
 		return fmt.Errorf("failed to fetch the User struct for the current authenticated user. errmsg: %v", err)
 	}
@@ -229,7 +537,7 @@ func (c *IssueCreator) loadCache() error {
 	c.authorName = *user.Login
 
 	// Try to get the list of valid labels for the repo.
-	if validLabels, err := c.client.GetRepoLabels(c.org, c.project); err != nil {
+	if validLabels, err := c.client.GetRepoLabels(ctx, c.org, c.project); err != nil {
 		c.validLabels = nil
 		glog.Errorf("Failed to retrieve the list of valid labels for repo '%s/%s'. Allowing all labels. errmsg: %v\n", c.org, c.project, err)
 	} else {
@@ -241,7 +549,7 @@ func (c *IssueCreator) loadCache() error {
 		}
 	}
 	// Try to get the valid collaborators for the repo.
-	if collaborators, err := c.client.GetCollaborators(c.org, c.project); err != nil {
+	if collaborators, err := c.client.GetCollaborators(ctx, c.org, c.project); err != nil {
 		c.Collaborators = nil
 		glog.Errorf("Failed to retrieve the list of valid collaborators for repo '%s/%s'. Allowing all assignees. errmsg: %v\n", c.org, c.project, err)
 	} else {
@@ -255,6 +563,7 @@ func (c *IssueCreator) loadCache() error {
 
 	// Populate the issue cache (allIssues).
 	issues, err := c.client.GetIssues(
+		ctx,
 		c.org,
 		c.project,
 		&github.IssueListByRepoOptions{
@@ -277,13 +586,33 @@ func (c *IssueCreator) loadCache() error {
 
 // RegisterFlags registers options for this munger; returns any that require a restart when changed.
 func (c *IssueCreator) RegisterFlags() {
-	flag.StringVar(&c.ownerPath, "test-owners-csv", "", "file containing a CSV-exported test-owners spreadsheet")
+	flag.StringVar(&c.ownerPath, "test-owners-csv", "", "file (or file:// URL) containing a CSV-exported test-owners spreadsheet")
+	flag.StringVar(&c.ownerConflicts, "test-owners-conflicts", "first-wins", "How to handle test-owners rows for the same test with different owners: first-wins, error, or merge.")
+	flag.StringVar(&c.ownerURL, "test-owners-url", "", "base URL of an ownership service answering /owner?test=<name> and /sig?test=<name> (instead of --test-owners-csv)")
+	flag.DurationVar(&c.ownerTimeout, "test-owners-timeout", 10*time.Second, "Timeout for requests to the ownership service.")
+	flag.IntVar(&c.ownerCacheSize, "test-owners-cache-size", 10000, "Maximum number of ownership service answers to cache (0 disables caching).")
+	flag.DurationVar(&c.ownerCacheTTL, "test-owners-cache-ttl", 10*time.Minute, "How long to cache ownership service answers.")
+	flag.StringVar(&c.ownerMatching, "test-owners-matching", "normalized", "How strictly test names must match test-owners rows: exact, whitespace, normalized, punctuation, or fuzzy.")
 	flag.IntVar(&c.MaxSIGCount, "maxSIGs", 3, "The maximum number of SIG labels to attach to an issue.")
 	flag.IntVar(&c.MaxAssignees, "maxAssignees", 3, "The maximum number of users to assign to an issue.")
 
 	flag.StringVar(&c.tokenFile, "token-file", "", "The file containing the github authentication token to use.")
+	flag.StringVar(&c.githubEndpoint, "github-endpoint", "https://api.github.com", "The github API URL, e.g. https://github.example.com/api/v3 for GitHub Enterprise Server.")
+	flag.StringVar(&c.githubUploadEndpoint, "github-upload-endpoint", "", "The github upload URL, e.g. https://github.example.com/api/uploads for GitHub Enterprise Server (default: the github.com upload URL).")
+	flag.StringVar(&c.proxyURL, "proxy-url", "", "The proxy for outbound HTTP requests (default: HTTP_PROXY, HTTPS_PROXY and NO_PROXY from the environment).")
+	flag.StringVar(&c.caFile, "ca-cert-file", "", "A PEM file of CA certificates to trust for outbound HTTPS requests, in addition to the system roots.")
+	flag.Float64Var(&c.fetchQPS, "fetch-qps", 0, "The maximum average rate of non-github HTTP requests, e.g. for triage data or the ownership service (0 for no limit).")
+	flag.IntVar(&c.fetchBurst, "fetch-burst", 5, "The maximum burst of non-github HTTP requests allowed by --fetch-qps.")
 	flag.StringVar(&c.project, "project", "", "The name of the github repo to create issues in.")
 	flag.StringVar(&c.org, "org", "", "The name of the organization that owns the repo to create issues in.")
+	flag.BoolVar(&c.RunOnce, "run-once", false, "Exit with a non-zero code if any source or github write failed during the run, for running as a CronJob or periodic job. Without it failures are only logged.")
+	flag.BoolVar(&c.LintOwners, "lint-test-owners", false, "Check the test owners CSV for mistakes and exit instead of syncing issues.")
+	flag.StringVar(&c.latestBuildTime, "latest-build-time", "", "An RFC 3339 time, e.g. 2017-06-01T00:00:00Z, to end the failure window at instead of the latest build, for backfills and for reproducing past runs. Later builds are ignored.")
+	flag.StringVar(&c.ReplayDir, "replay-dir", "", "A directory of dated snapshots (e.g. 2017-06-01.json) to replay in name order against an empty repo, reporting what would be filed and updated for each, instead of syncing issues.")
+	flag.StringVar(&c.enabledSources, "sources", "", "Comma separated names of the issue sources to run (default: all of them).")
+	flag.StringVar(&c.disabledSourcesFile, "disabled-sources-file", "", "A file naming issue sources to skip, one per line. It is re-read before each source runs, e.g. from a ConfigMap.")
+	flag.StringVar(&c.runTokenFile, "run-token-file", "", "A file containing the bearer token for POST /triage/run on '--metrics-addr', which runs the triage filer immediately and streams its decisions (dry_run=true to change nothing). The process keeps serving after the first run (default: no on-demand runs).")
+	flag.DurationVar(&c.stallTimeout, "stall-timeout", 30*time.Minute, "How long issue syncing may go without progress before /healthz fails (0 to never fail).")
 	flag.BoolVar(&c.dryRun, "dry-run", true, "True iff only 'read' operations should be made on github.")
 
 	for _, src := range sources {
@@ -311,30 +640,60 @@ func setIntersect(a, b []string) (filtered, removed []string) {
 	return
 }
 
+// reportMarkerPrefix starts the marker that ReportMarker returns.
+const reportMarkerPrefix = "<!-- issue-creator report: "
+
+// ReportMarker returns a marker to put in the body of an issue that summarizes other issues, such
+// as a list of the worst flakes. Such bodies mention the IDs of the issues they summarize, so when
+// looking for existing issues they only match their own ID, in the marker.
+func ReportMarker(id string) string {
+	return reportMarkerPrefix + id + " -->"
+}
+
+// IsReport returns true iff body is the body of a report, marked with ReportMarker.
+func IsReport(body string) bool {
+	return strings.Contains(body, reportMarkerPrefix)
+}
+
+// matchesID returns true iff an issue with body is the github issue for the issue with id.
+func matchesID(body, id string) bool {
+	if IsReport(body) {
+		return strings.Contains(body, ReportMarker(id))
+	}
+	return strings.Contains(body, id)
+}
+
 // sync checks to see if an issue is already on github and tries to create a new issue for it if it is not.
-// True is returned iff a new issue is created.
-func (c *IssueCreator) sync(issue Issue) bool {
+// True is returned iff a new issue is created. source is the name of the IssueSource, for logging.
+func (c *IssueCreator) sync(ctx context.Context, source string, issue Issue) bool {
 	// First look for existing issues with this ID.
 	id := issue.ID()
+	log := c.Log(source, id)
 	var closedIssues []*github.Issue
 	for _, i := range c.allIssues {
-		if strings.Contains(*i.Body, id) {
+		if matchesID(*i.Body, id) {
 			switch *i.State {
 			case "open":
 				//if an open issue is found with the ID then the issue is already synced
+				log.WithNumber(*i.Number).Infof("Already synced to an open issue.")
+				c.report("%s: already tracked by #%d.", id, *i.Number)
 				return false
 			case "closed":
 				closedIssues = append(closedIssues, i)
 			default:
-				glog.Errorf("Unrecognized issue state '%s' for issue #%d. Ignoring this issue.\n", *i.State, *i.Number)
+				log.WithNumber(*i.Number).Errorf("Unrecognized issue state '%s'. Ignoring this issue.", *i.State)
 			}
 		}
 	}
-	// No open issues exist for the ID.
+	// No open issues exist for the ID. allIssues is a map, so the closed issues are put in order.
+	sort.Slice(closedIssues, func(i, j int) bool { return *closedIssues[i].Number < *closedIssues[j].Number })
+	doneRendering := TrackAllocs("render")
 	body := issue.Body(closedIssues)
+	doneRendering()
 	if body == "" {
 		// Issue indicated that it should not be synced.
-		glog.Infof("Issue aborted sync by providing \"\" (empty) body. ID: %s.", id)
+		log.Infof("Issue aborted sync by providing \"\" (empty) body.")
+		c.report("%s: not filed, the source skipped it.", id)
 		return false
 	}
 	if !strings.Contains(body, id) {
@@ -347,7 +706,7 @@ func (c *IssueCreator) sync(issue Issue) bool {
 		var removedOwners []string
 		owners, removedOwners = setIntersect(owners, c.Collaborators)
 		if len(removedOwners) > 0 {
-			glog.Errorf("Filtered the following invalid assignees from issue %q: %q.", title, removedOwners)
+			log.Errorf("Filtered the following invalid assignees from issue %q: %q.", title, removedOwners)
 		}
 	}
 
@@ -359,24 +718,141 @@ func (c *IssueCreator) sync(issue Issue) bool {
 		var removedLabels []string
 		labels, removedLabels = setIntersect(labels, c.validLabels)
 		if len(removedLabels) > 0 {
-			glog.Errorf("Filtered the following invalid labels from issue %q: %q.", title, removedLabels)
+			log.Errorf("Filtered the following invalid labels from issue %q: %q.", title, removedLabels)
 		}
 	}
 
-	glog.Infof("Create Issue: %q Assigned to: %q\n", title, owners)
+	log.Infof("Create Issue: %q Assigned to: %q", title, owners)
 	if c.dryRun {
+		c.report("%s: would file %q labels=%q assignees=%q.", id, title, labels, owners)
 		return true
 	}
 
-	created, err := c.client.CreateIssue(c.org, c.project, title, body, labels, owners)
+	if ctx.Err() != nil {
+		c.report("%s: not filed, the run was stopped: %v", id, ctx.Err())
+		return false
+	}
+	// Once started, filing isn't cancelled with ctx, so that stopping doesn't abandon a request that
+	// github may already have acted on. The caller stops before the next issue instead.
+	created, err := c.client.CreateIssue(context.Background(), c.org, c.project, title, body, labels, owners)
 	if err != nil {
-		glog.Errorf("Failed to create a new github issue for issue ID '%s'.\n", id)
+		log.Errorf("Failed to create a new github issue: %v", err)
+		c.report("%s: failed to file %q: %v", id, title, err)
+		c.RecordError(fmt.Errorf("failed to file %q: %v", title, err))
 		return false
 	}
+	log.WithNumber(*created.Number).Infof("Created issue.")
+	c.report("%s: filed #%d %q labels=%q assignees=%q.", id, *created.Number, title, labels, owners)
 	c.allIssues[*created.Number] = created
 	return true
 }
 
+// LatestBuildTime returns the time that '--latest-build-time' sets as the end of the window. The
+// bool is false if the window should end at the latest build instead.
+func (c *IssueCreator) LatestBuildTime() (time.Time, bool) {
+	if c.latestBuildTime == "" {
+		return time.Time{}, false
+	}
+	// Validate has already reported times that don't parse.
+	t, err := time.Parse(time.RFC3339, c.latestBuildTime)
+	return t, err == nil
+}
+
+// OpenIssues returns the open issues authored by this bot, ordered by number.
+func (c *IssueCreator) OpenIssues() []*github.Issue {
+	var open []*github.Issue
+	for _, issue := range c.allIssues {
+		if issue.State != nil && *issue.State == "open" {
+			open = append(open, issue)
+		}
+	}
+	sort.Slice(open, func(i, j int) bool { return *open[i].Number < *open[j].Number })
+	return open
+}
+
+// IssuesWithID returns the issues authored by this bot, open or closed, that are the github issues
+// for id, ordered by number.
+func (c *IssueCreator) IssuesWithID(id string) []*github.Issue {
+	var issues []*github.Issue
+	for _, issue := range c.allIssues {
+		if issue.Body != nil && matchesID(*issue.Body, id) {
+			issues = append(issues, issue)
+		}
+	}
+	sort.Slice(issues, func(i, j int) bool { return *issues[i].Number < *issues[j].Number })
+	return issues
+}
+
+// OpenIssuesByOthers returns the open issues with the label that were not authored by this bot,
+// e.g. flakes that a human filed by hand.
+func (c *IssueCreator) OpenIssuesByOthers(ctx context.Context, label string) ([]*github.Issue, error) {
+	issues, err := c.client.GetIssues(ctx, c.org, c.project, &github.IssueListByRepoOptions{
+		State:  "open",
+		Labels: []string{label},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the open issues labeled '%s' in repo '%s/%s': %v", label, c.org, c.project, err)
+	}
+	var others []*github.Issue
+	for _, issue := range issues {
+		if issue.User != nil && issue.User.Login != nil && strings.EqualFold(*issue.User.Login, c.authorName) {
+			continue
+		}
+		others = append(others, issue)
+	}
+	return others, nil
+}
+
+// UpdateIssue replaces the body and labels of an issue authored by this bot. Labels that are not
+// valid for the repo are dropped. In dry-run mode the change is only logged.
+func (c *IssueCreator) UpdateIssue(ctx context.Context, number int, body string, labels []string) error {
+	if c.validLabels != nil {
+		var removedLabels []string
+		labels, removedLabels = setIntersect(labels, c.validLabels)
+		if len(removedLabels) > 0 {
+			glog.Errorf("Filtered the following invalid labels from issue #%d: %q.", number, removedLabels)
+		}
+	}
+	glog.Infof("Update Issue: #%d Labels: %q", number, labels)
+	if c.dryRun {
+		c.report("#%d: would update the body and labels=%q.", number, labels)
+		return nil
+	}
+	updated, err := c.client.EditIssue(ctx, c.org, c.project, number, &github.IssueRequest{Body: &body, Labels: &labels})
+	if err != nil {
+		c.report("#%d: failed to update: %v", number, err)
+		return fmt.Errorf("failed to update issue #%d: %v", number, err)
+	}
+	c.report("#%d: updated the body and labels=%q.", number, labels)
+	c.allIssues[number] = updated
+	return nil
+}
+
+// IssueComments returns the comments on an issue in the repo, oldest first.
+func (c *IssueCreator) IssueComments(ctx context.Context, number int) ([]*github.IssueComment, error) {
+	comments, err := c.client.GetIssueComments(ctx, c.org, c.project, number)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the comments on issue #%d: %v", number, err)
+	}
+	return comments, nil
+}
+
+// CommentOnIssue adds a comment to an issue in the repo. In dry-run mode the comment is only
+// logged.
+func (c *IssueCreator) CommentOnIssue(ctx context.Context, number int, body string) error {
+	glog.Infof("Comment on Issue: #%d", number)
+	if c.dryRun {
+		c.report("#%d: would comment.", number)
+		return nil
+	}
+	if _, err := c.client.CreateComment(ctx, c.org, c.project, number, body); err != nil {
+		c.report("#%d: failed to comment: %v", number, err)
+		return fmt.Errorf("failed to comment on issue #%d: %v", number, err)
+	}
+	c.report("#%d: commented.", number)
+	return nil
+}
+
 // TestSIG uses the IssueCreator's OwnerMapper to look up the SIG for a test.
 func (c *IssueCreator) TestSIG(testName string) string {
 	if c.Owners == nil {
@@ -390,13 +866,69 @@ func (c *IssueCreator) TestOwner(testName string) string {
 	if c.Owners == nil {
 		return ""
 	}
-	owner := c.Owners.TestOwner(testName)
+	return c.assignableOwner(c.Owners.TestOwner(testName))
+}
+
+// assignableOwner turns an owner from the OwnerMapper into a user that can be assigned to issues,
+// or "" if there is none.
+func (c *IssueCreator) assignableOwner(owner string) string {
+	if strings.HasPrefix(owner, teamPrefix) {
+		owner = c.teamMember(strings.TrimPrefix(owner, teamPrefix))
+	}
 	if !c.isAssignable(owner) {
 		return ""
 	}
 	return owner
 }
 
+// TestAutoAssigned returns false iff the OwnerMapper says the owner of the test only opted into
+// tracking it and should be mentioned rather than assigned.
+func (c *IssueCreator) TestAutoAssigned(testName string) bool {
+	mapper, ok := c.Owners.(InfoOwnerMapper)
+	if !ok {
+		return true
+	}
+	return !mapper.TestInfo(testName).MentionOnly
+}
+
+// lookupContext returns the context of the current run, or the background context between runs.
+func (c *IssueCreator) lookupContext() context.Context {
+	if c.lookupCtx == nil {
+		return context.Background()
+	}
+	return c.lookupCtx
+}
+
+// teamMember picks a random assignable member of the team, or "" if there is none. Team
+// membership is fetched from github once per team and cached. Failed fetches are not cached, so
+// they are retried at the next lookup rather than leaving the team without owners until restart.
+func (c *IssueCreator) teamMember(team string) string {
+	members, ok := c.teamMembers[team]
+	if !ok {
+		if c.client == nil {
+			return ""
+		}
+		users, err := c.client.GetTeamMembers(c.lookupContext(), c.org, team)
+		if err != nil {
+			glog.Errorf("Failed to get the members of team '%s/%s'. errmsg: %v\n", c.org, team, err)
+			return ""
+		}
+		for _, user := range users {
+			if user.Login != nil && c.isAssignable(*user.Login) {
+				members = append(members, strings.ToLower(*user.Login))
+			}
+		}
+		if c.teamMembers == nil {
+			c.teamMembers = make(map[string][]string)
+		}
+		c.teamMembers[team] = members
+	}
+	if len(members) == 0 {
+		return ""
+	}
+	return members[rand.Intn(len(members))]
+}
+
 // TestsSIGs uses the IssueCreator's OwnerMapper to look up the SIGs for a list of tests.
 // The number of SIGs returned is limited by MaxSIGCount.
 // The return value is a map from sigs to the tests from testNames that each sig owns.
@@ -404,9 +936,10 @@ func (c *IssueCreator) TestsSIGs(testNames []string) map[string][]string {
 	if c.Owners == nil {
 		return nil
 	}
+	testSIGs := c.batchTestSIGs(testNames)
 	sigs := make(map[string][]string)
-	for _, test := range testNames {
-		sig := c.Owners.TestSIG(test)
+	for i, test := range testNames {
+		sig := testSIGs[i]
 		if sig == "" {
 			continue
 		}
@@ -429,9 +962,10 @@ func (c *IssueCreator) TestsOwners(testNames []string) map[string][]string {
 	if c.Owners == nil {
 		return nil
 	}
+	testOwners := c.batchTestOwners(testNames)
 	users := make(map[string][]string)
-	for _, test := range testNames {
-		user := c.TestOwner(test)
+	for i, test := range testNames {
+		user := c.assignableOwner(testOwners[i])
 		if user == "" {
 			continue
 		}
@@ -447,6 +981,52 @@ func (c *IssueCreator) TestsOwners(testNames []string) map[string][]string {
 	return users
 }
 
+// SortedKeys returns the keys of a map from TestsOwners or TestsSIGs in sorted order, so that
+// what is generated from it doesn't change from run to run.
+func SortedKeys(m map[string][]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// batchTestSIGs looks up the SIG of each test, all at once if the OwnerMapper supports it.
+func (c *IssueCreator) batchTestSIGs(testNames []string) []string {
+	if batch, ok := c.Owners.(BatchOwnerMapper); ok {
+		return batch.BatchTestSIGs(testNames)
+	}
+	sigs := make([]string, len(testNames))
+	for i, test := range testNames {
+		sigs[i] = c.Owners.TestSIG(test)
+	}
+	return sigs
+}
+
+// batchTestOwners looks up the owner of each test, all at once if the OwnerMapper supports it.
+// The owners are not yet checked for being assignable.
+func (c *IssueCreator) batchTestOwners(testNames []string) []string {
+	if batch, ok := c.Owners.(BatchOwnerMapper); ok {
+		return batch.BatchTestOwners(testNames)
+	}
+	owners := make([]string, len(testNames))
+	for i, test := range testNames {
+		owners[i] = c.Owners.TestOwner(test)
+	}
+	return owners
+}
+
+// OwnershipGaps uses the IssueCreator's OwnerMapper to find which tests lack an owner or SIG.
+// Nil is returned if there is no OwnerMapper or it is unable to report gaps.
+func (c *IssueCreator) OwnershipGaps(testNames []string) *testowner.OwnershipGaps {
+	reporter, ok := c.Owners.(GapReporter)
+	if !ok {
+		return nil
+	}
+	return reporter.OwnershipGaps(testNames)
+}
+
 // ExplainTestAssignments returns a string explaining how tests caused the individual/sig assignments.
 func (c *IssueCreator) ExplainTestAssignments(testNames []string) string {
 	assignees := c.TestsOwners(testNames)
@@ -455,13 +1035,15 @@ func (c *IssueCreator) ExplainTestAssignments(testNames []string) string {
 	if len(assignees) > 0 || len(sigs) > 0 {
 		fmt.Fprint(&buf, "\n<details><summary>Rationale for assignments:</summary>\n")
 		fmt.Fprint(&buf, "\n| Assignee or SIG area | Owns test(s) |\n| --- | --- |\n")
-		for assignee, tests := range assignees {
+		for _, assignee := range SortedKeys(assignees) {
+			tests := assignees[assignee]
 			if len(tests) > 3 {
 				tests = tests[0:3]
 			}
 			fmt.Fprintf(&buf, "| %s | %s |\n", assignee, strings.Join(tests, "; "))
 		}
-		for sig, tests := range sigs {
+		for _, sig := range SortedKeys(sigs) {
+			tests := sigs[sig]
 			if len(tests) > 3 {
 				tests = tests[0:3]
 			}
@@ -18,8 +18,13 @@ package creator
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
 	"reflect"
 	"sort"
 	"strings"
@@ -38,25 +43,33 @@ type fakeClient struct {
 	issues     []*github.Issue
 	org        string
 	project    string
+	teams      map[string][]string
+	users      []string
+	comments   map[int][]*github.IssueComment
 	t          *testing.T
 }
 
-func (c *fakeClient) GetUser(login string) (*github.User, error) {
+func (c *fakeClient) GetUser(ctx context.Context, login string) (*github.User, error) {
 	if login == "" {
 		return &github.User{Login: &c.userName}, nil
 	}
-	return nil, fmt.Errorf("fake Client is only able to retrieve the current authenticated user in its current state")
+	for _, user := range c.users {
+		if user == login {
+			return &github.User{Login: &login}, nil
+		}
+	}
+	return nil, &github.ErrorResponse{Response: &http.Response{StatusCode: http.StatusNotFound}, Message: "Not Found"}
 }
 
-func (c *fakeClient) GetRepoLabels(org, repo string) ([]*github.Label, error) {
+func (c *fakeClient) GetRepoLabels(ctx context.Context, org, repo string) ([]*github.Label, error) {
 	return makeLabelSlice(c.repoLabels), nil
 }
 
-func (c *fakeClient) GetIssues(org, repo string, options *github.IssueListByRepoOptions) ([]*github.Issue, error) {
+func (c *fakeClient) GetIssues(ctx context.Context, org, repo string, options *github.IssueListByRepoOptions) ([]*github.Issue, error) {
 	return c.issues, nil
 }
 
-func (c *fakeClient) CreateIssue(org, repo string, title, body string, labels, owners []string) (*github.Issue, error) {
+func (c *fakeClient) CreateIssue(ctx context.Context, org, repo string, title, body string, labels, owners []string) (*github.Issue, error) {
 	// Check if labels are valid.
 	for _, label := range labels {
 		found := false
@@ -77,10 +90,47 @@ func (c *fakeClient) CreateIssue(org, repo string, title, body string, labels, o
 	return issue, nil
 }
 
-func (c *fakeClient) GetCollaborators(org, repo string) ([]*github.User, error) {
+func (c *fakeClient) EditIssue(ctx context.Context, org, repo string, number int, issue *github.IssueRequest) (*github.Issue, error) {
+	for _, existing := range c.issues {
+		if *existing.Number != number {
+			continue
+		}
+		if issue.Body != nil {
+			existing.Body = issue.Body
+		}
+		if issue.Labels != nil {
+			existing.Labels = makeLabelSliceNoPtr(*issue.Labels)
+		}
+		return existing, nil
+	}
+	return nil, fmt.Errorf("issue #%d does not exist", number)
+}
+
+func (c *fakeClient) CreateComment(ctx context.Context, org, repo string, number int, body string) (*github.IssueComment, error) {
+	if c.comments == nil {
+		c.comments = map[int][]*github.IssueComment{}
+	}
+	comment := &github.IssueComment{Body: &body, User: &github.User{Login: &c.userName}}
+	c.comments[number] = append(c.comments[number], comment)
+	return comment, nil
+}
+
+func (c *fakeClient) GetIssueComments(ctx context.Context, org, repo string, number int) ([]*github.IssueComment, error) {
+	return c.comments[number], nil
+}
+
+func (c *fakeClient) GetCollaborators(ctx context.Context, org, repo string) ([]*github.User, error) {
 	return nil, errors.New("some error (allow all assignees)")
 }
 
+func (c *fakeClient) GetTeamMembers(ctx context.Context, org, team string) ([]*github.User, error) {
+	members, ok := c.teams[org+"/"+team]
+	if !ok {
+		return nil, fmt.Errorf("team '%s/%s' does not exist", org, team)
+	}
+	return makeUserSlice(members), nil
+}
+
 // Verify checks that exactly 1 issue in c.issues matches the parameters and that no
 // issues in c.issues have an empty body string (since that means they shouldn't have been created).
 func (c *fakeClient) Verify(title, body string, owners, labels []string) bool {
@@ -170,7 +220,7 @@ func TestIssueCreator(t *testing.T) {
 	creator := &IssueCreator{
 		client: c,
 	}
-	if err := creator.loadCache(); err != nil {
+	if err := creator.loadCache(context.Background()); err != nil {
 		t.Fatalf("IssueCreator failed to load data from github while initing: %v", err)
 	}
 
@@ -183,14 +233,14 @@ func TestIssueCreator(t *testing.T) {
 		owners:   []string{"user0"},
 		priority: "",
 	}
-	creator.sync(i0)
+	creator.sync(context.Background(), "test", i0)
 	if !c.Verify(i0.title, i0.body, i0.owners, i0.labels) {
 		t.Errorf("Failed to do a simple sync of i0\n")
 	}
 
 	// Test that issues can't be double synced.
 	origLen := len(c.issues)
-	creator.sync(i1)
+	creator.sync(context.Background(), "test", i1)
 	if len(c.issues) > origLen {
 		t.Errorf("Second sync of i1 created a duplicate issue!\n")
 	}
@@ -208,7 +258,7 @@ func TestIssueCreator(t *testing.T) {
 		priority: "",
 	}
 	origLen = len(c.issues)
-	creator.sync(i2)
+	creator.sync(context.Background(), "test", i2)
 	if len(c.issues) > origLen {
 		t.Errorf("sync of i2 with empty body should not have created issue!\n")
 	}
@@ -222,7 +272,7 @@ func TestIssueCreator(t *testing.T) {
 		owners:   []string{"user3"},
 		priority: "",
 	}
-	creator.sync(i3)
+	creator.sync(context.Background(), "test", i3)
 	if !c.Verify(i3.title, i3.body, i3.owners, []string{"kind/flake"}) {
 		t.Errorf("sync of i3 was invalid. The label 'label/wannabe' should not be added to the new issue.\n")
 	}
@@ -238,7 +288,7 @@ func TestIssueCreator(t *testing.T) {
 		priority: "",
 	}
 	origLen = len(c.issues)
-	creator.sync(i4)
+	creator.sync(context.Background(), "test", i4)
 	if len(c.issues) > origLen {
 		t.Errorf("sync of i4 with DryRun on should not have created issue!\n")
 	}
@@ -254,7 +304,7 @@ func TestIssueCreator(t *testing.T) {
 		owners:   []string{"user5", "user1"}, // Test multiple users and labels here too.
 		priority: "P0",
 	}
-	creator.sync(i5)
+	creator.sync(context.Background(), "test", i5)
 	if !c.Verify(i5.title, i5.body, i5.owners, []string{"kind/flake", "kind/flakeypastry", "priority/P0"}) {
 		t.Errorf("sync of i5 was invalid. The labels in the created issue were incorrect.\n")
 	}
@@ -374,5 +424,350 @@ Upgrade master upgrade should maintain a functioning cluster,xiang90,1,cluster-l
 				t.Errorf("Assignment explanation table is missing row: '%s'\n", row)
 			}
 		}
+		// Rows are sorted so that the table is the same every run.
+		last := -1
+		for _, row := range append(SortedKeys(owners), prefixed("sig/", SortedKeys(sigs))...) {
+			i := strings.Index(table, "| "+row+" |")
+			if i < last {
+				t.Errorf("Expected the row of %s to come later in the table:\n%s", row, table)
+			}
+			last = i
+		}
+	}
+}
+
+func prefixed(prefix string, names []string) []string {
+	result := make([]string, len(names))
+	for i, name := range names {
+		result[i] = prefix + name
+	}
+	return result
+}
+
+func TestSortedKeys(t *testing.T) {
+	keys := SortedKeys(map[string][]string{"spxtr": nil, "cjwagner": {"a"}, "luxas": {"b", "c"}})
+	if expected := []string{"cjwagner", "luxas", "spxtr"}; !reflect.DeepEqual(keys, expected) {
+		t.Errorf("Expected keys %q, got %q", expected, keys)
+	}
+	if keys := SortedKeys(nil); len(keys) != 0 {
+		t.Errorf("Expected no keys of a nil map, got %q", keys)
+	}
+}
+
+func TestTeamOwners(t *testing.T) {
+	ownerlist := testowner.NewOwnerList(map[string]*testowner.OwnerInfo{
+		"node test":    {User: "team:sig-node-reviewers", SIG: "node"},
+		"storage test": {User: "team:storage", SIG: "storage"},
+		"empty test":   {User: "team:nobody-assignable", SIG: "node"},
+		"missing test": {User: "team:missing", SIG: "node"},
+	})
+	client := &fakeClient{
+		t: t,
+		teams: map[string][]string{
+			"k8s/sig-node-reviewers": {"Alice", "bob", "outsider"},
+			"k8s/storage":            {"carol"},
+			"k8s/nobody-assignable":  {"outsider"},
+		},
+	}
+	c := &IssueCreator{
+		client:        client,
+		org:           "k8s",
+		Collaborators: []string{"alice", "bob", "carol"},
+		Owners:        ownerlist,
+		MaxAssignees:  3,
+	}
+
+	seen := map[string]bool{}
+	for i := 0; i < 100; i++ {
+		seen[c.TestOwner("node test")] = true
+	}
+	if !reflect.DeepEqual(seen, map[string]bool{"alice": true, "bob": true}) {
+		t.Errorf("Expected assignable members of the team to be picked, got %v.", seen)
+	}
+	if owner := c.TestOwner("storage test"); owner != "carol" {
+		t.Errorf("Expected carol from the storage team, got %q.", owner)
+	}
+	if owner := c.TestOwner("empty test"); owner != "" {
+		t.Errorf("Expected no owner for a team without assignable members, got %q.", owner)
+	}
+	if owner := c.TestOwner("missing test"); owner != "" {
+		t.Errorf("Expected no owner for a missing team, got %q.", owner)
+	}
+
+	// Failed lookups are retried.
+	client.teams["k8s/missing"] = []string{"bob"}
+	if owner := c.TestOwner("missing test"); owner != "bob" {
+		t.Errorf("Expected the lookup of a team that failed to be retried, got %q.", owner)
+	}
+
+	// Membership is cached.
+	client.teams = nil
+	if owner := c.TestOwner("storage test"); owner != "carol" {
+		t.Errorf("Expected cached team members to be used, got %q.", owner)
+	}
+}
+
+func TestLintTestOwners(t *testing.T) {
+	file, err := ioutil.TempFile("", "owners")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+	fmt.Fprint(file, `name,owner,sig
+storage test,alice,storage
+node test,ghost,node
+network test,bob,network
+`)
+	file.Close()
+
+	c := &IssueCreator{
+		client:        &fakeClient{t: t, users: []string{"alice", "bob"}},
+		ownerPath:     file.Name(),
+		ownerMatching: "normalized",
+		validLabels:   []string{"kind/flake", "sig/storage", "sig/node"},
+	}
+	var out bytes.Buffer
+	problems, err := c.lintTestOwners(context.Background(), &out)
+	if err != nil {
+		t.Fatalf("Unexpected error linting test owners: %v", err)
+	}
+	if problems != 2 {
+		t.Errorf("Expected 2 problems, got %d:\n%s", problems, out.String())
+	}
+	for _, expected := range []string{`"node test": GitHub user "ghost" does not exist`, `"network test": unknown SIG "network"`} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("Expected lint output to contain %q, got:\n%s", expected, out.String())
+		}
+	}
+}
+
+// batchMapper is an OwnerMapper that counts how often it is asked about tests one at a time and
+// in batches.
+type batchMapper struct {
+	owners, sigs    map[string]string
+	single, batches int
+}
+
+func (m *batchMapper) TestOwner(test string) string {
+	m.single++
+	return m.owners[test]
+}
+
+func (m *batchMapper) TestSIG(test string) string {
+	m.single++
+	return m.sigs[test]
+}
+
+func (m *batchMapper) BatchTestOwners(tests []string) []string {
+	m.batches++
+	owners := make([]string, len(tests))
+	for i, test := range tests {
+		owners[i] = m.owners[test]
+	}
+	return owners
+}
+
+func (m *batchMapper) BatchTestSIGs(tests []string) []string {
+	m.batches++
+	sigs := make([]string, len(tests))
+	for i, test := range tests {
+		sigs[i] = m.sigs[test]
+	}
+	return sigs
+}
+
+func TestBatchOwnerMapper(t *testing.T) {
+	mapper := &batchMapper{
+		owners: map[string]string{"a": "alice", "b": "bob", "c": "alice"},
+		sigs:   map[string]string{"a": "node", "b": "storage", "c": "node"},
+	}
+	c := &IssueCreator{Owners: mapper, MaxAssignees: 3, MaxSIGCount: 3}
+
+	owners := c.TestsOwners([]string{"a", "b", "c", "d"})
+	if !reflect.DeepEqual(owners, map[string][]string{"alice": {"a", "c"}, "bob": {"b"}}) {
+		t.Errorf("Unexpected owners %v.", owners)
+	}
+	sigs := c.TestsSIGs([]string{"a", "b", "c", "d"})
+	if !reflect.DeepEqual(sigs, map[string][]string{"node": {"a", "c"}, "storage": {"b"}}) {
+		t.Errorf("Unexpected SIGs %v.", sigs)
+	}
+	if mapper.batches != 2 || mapper.single != 0 {
+		t.Errorf("Expected 2 batch lookups and no single lookups, got %d and %d.", mapper.batches, mapper.single)
+	}
+}
+
+func TestSourceEnabled(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sources")
+	if err != nil {
+		t.Fatalf("failed to create a temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	disabledFile := filepath.Join(dir, "disabled")
+
+	c := &IssueCreator{enabledSources: "triage-filer, flakyjob-reporter", disabledSourcesFile: disabledFile}
+	check := func(name string, expected bool, desc string) {
+		if enabled := c.sourceEnabled(name); enabled != expected {
+			t.Errorf("%s: expected sourceEnabled(%q) to be %t", desc, name, expected)
+		}
+	}
+
+	check("triage-filer", true, "listed source without a disabled file")
+	check("other", false, "unlisted source")
+
+	if err := ioutil.WriteFile(disabledFile, []byte("flakyjob-reporter\n"), 0644); err != nil {
+		t.Fatalf("failed to write %s: %v", disabledFile, err)
+	}
+	check("triage-filer", true, "source not in the disabled file")
+	check("flakyjob-reporter", false, "source in the disabled file")
+
+	// The file is re-read, so re-enabling a source takes effect without a restart.
+	if err := ioutil.WriteFile(disabledFile, []byte(""), 0644); err != nil {
+		t.Fatalf("failed to write %s: %v", disabledFile, err)
+	}
+	check("flakyjob-reporter", true, "source removed from the disabled file")
+
+	c.enabledSources = ""
+	check("other", true, "any source without '--sources'")
+}
+
+func TestUpdateIssue(t *testing.T) {
+	bot, human := "bot", "human"
+	open := makeTestIssue("open", "open body", "open", []string{"kind/flake"}, nil, 2)
+	open.User = &github.User{Login: &bot}
+	closed := makeTestIssue("closed", "closed body", "closed", []string{"kind/flake"}, nil, 1)
+	closed.User = &github.User{Login: &bot}
+	byHuman := makeTestIssue("human", "human body", "open", []string{"kind/flake"}, nil, 3)
+	byHuman.User = &github.User{Login: &human}
+	client := &fakeClient{
+		userName:   bot,
+		repoLabels: []string{"kind/flake", "lifecycle/stale"},
+		issues:     []*github.Issue{closed, open, byHuman},
+		t:          t,
+	}
+	c := &IssueCreator{client: client, org: "org", project: "repo"}
+	if err := c.loadCache(context.Background()); err != nil {
+		t.Fatalf("Unexpected error loading the cache: %v", err)
+	}
+
+	// The fake lists every issue, so the cache also holds the one by a human.
+	delete(c.allIssues, 3)
+	if issues := c.OpenIssues(); len(issues) != 1 || *issues[0].Number != 2 {
+		t.Errorf("Expected only issue #2 to be open, got %v", issues)
+	}
+	if issues := c.IssuesWithID("body"); len(issues) != 2 || *issues[0].Number != 1 || *issues[1].Number != 2 {
+		t.Errorf("Expected issues #1 and #2 to match, open or closed, got %v", issues)
+	}
+	others, err := c.OpenIssuesByOthers(context.Background(), "kind/flake")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	found := false
+	for _, issue := range others {
+		if *issue.User.Login == bot {
+			t.Errorf("Expected issues by the bot to be excluded, got #%d", *issue.Number)
+		}
+		found = found || *issue.Number == 3
+	}
+	if !found {
+		t.Errorf("Expected the issue filed by a human to be listed, got %v", others)
+	}
+
+	if err := c.UpdateIssue(context.Background(), 2, "new body", []string{"kind/flake", "lifecycle/stale", "invalid"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if *open.Body != "new body" {
+		t.Errorf("Expected the body to be updated, got %q", *open.Body)
+	}
+	var labels []string
+	for _, label := range open.Labels {
+		labels = append(labels, *label.Name)
+	}
+	if !reflect.DeepEqual(labels, []string{"kind/flake", "lifecycle/stale"}) {
+		t.Errorf("Expected the valid labels to be set, got %q", labels)
+	}
+
+	c.dryRun = true
+	if err := c.UpdateIssue(context.Background(), 2, "dry run body", nil); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if *open.Body != "new body" {
+		t.Errorf("Expected no change in dry-run mode, got %q", *open.Body)
+	}
+}
+
+func TestCommentOnIssue(t *testing.T) {
+	client := &fakeClient{userName: "bot", t: t}
+	c := &IssueCreator{client: client, org: "org", project: "repo"}
+	if err := c.CommentOnIssue(context.Background(), 2, "a comment"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	comments, err := c.IssueComments(context.Background(), 2)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(comments) != 1 || *comments[0].Body != "a comment" {
+		t.Errorf("Expected the comment to be added to issue #2, got %v", comments)
+	}
+
+	c.dryRun = true
+	if err := c.CommentOnIssue(context.Background(), 2, "dry run comment"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if comments, _ := c.IssueComments(context.Background(), 2); len(comments) != 1 {
+		t.Errorf("Expected no comment in dry-run mode, got %d comments", len(comments))
+	}
+}
+
+func TestReportMarker(t *testing.T) {
+	report := &fakeIssue{
+		title:  "report",
+		body:   ReportMarker("<REPORT>") + "\nThe worst issue is <ID1>.",
+		id:     "<REPORT>",
+		labels: []string{"kind/flake"},
+	}
+	c := &fakeClient{
+		t:          t,
+		userName:   "BOT_USERNAME",
+		repoLabels: []string{"kind/flake"},
+		issues:     []*github.Issue{makeTestIssue(report.title, report.body, "open", report.labels, nil, 0)},
+	}
+	creator := &IssueCreator{client: c}
+	if err := creator.loadCache(context.Background()); err != nil {
+		t.Fatalf("IssueCreator failed to load data from github while initing: %v", err)
+	}
+
+	if creator.sync(context.Background(), "test", report) {
+		t.Errorf("Expected the open report to match its own ID.")
+	}
+	i1 := &fakeIssue{title: "title1", body: "body<ID1>", id: "<ID1>", labels: []string{"kind/flake"}}
+	if !creator.sync(context.Background(), "test", i1) {
+		t.Errorf("Expected an issue mentioned by the report to be created.")
+	}
+}
+
+func TestSyncStopped(t *testing.T) {
+	c := &fakeClient{t: t, userName: "BOT_USERNAME"}
+	creator := &IssueCreator{client: c}
+	if err := creator.loadCache(context.Background()); err != nil {
+		t.Fatalf("IssueCreator failed to load data from github while initing: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if creator.sync(ctx, "test", &fakeIssue{title: "title", body: "body<ID>", id: "<ID>"}) {
+		t.Errorf("Expected no issue to be created once the run was stopped.")
+	}
+	if len(c.issues) != 0 {
+		t.Errorf("Expected no issue to be filed once the run was stopped, got %d.", len(c.issues))
+	}
+}
+
+func TestLatestBuildTime(t *testing.T) {
+	c := &IssueCreator{}
+	if _, ok := c.LatestBuildTime(); ok {
+		t.Error("Expected no latest build time when '--latest-build-time' is not set.")
+	}
+	c.latestBuildTime = "2017-06-01T12:00:00Z"
+	if end, ok := c.LatestBuildTime(); !ok || end.Unix() != 1496318400 {
+		t.Errorf("Expected the latest build time to be 1496318400, got %d (%t)", end.Unix(), ok)
 	}
 }
@@ -0,0 +1,83 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package creator
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// healthState tracks whether the IssueCreator finished initializing and when it last made
+// progress, for the /healthz and /readyz handlers.
+type healthState struct {
+	lock         sync.Mutex
+	ready        bool
+	lastProgress time.Time
+	// now returns the current time and is replaced in tests.
+	now func() time.Time
+}
+
+func (h *healthState) time() time.Time {
+	if h.now == nil {
+		return time.Now()
+	}
+	return h.now()
+}
+
+// markReady records that the configuration, github client and owner data loaded.
+func (h *healthState) markReady() {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.ready = true
+	h.lastProgress = h.time()
+}
+
+// markProgress records that a source started or an issue was synced.
+func (h *healthState) markProgress() {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.lastProgress = h.time()
+}
+
+// HandleHealthz answers liveness probes. It fails once no progress was made for the stall timeout,
+// e.g. because a fetch or github request hangs.
+func (c *IssueCreator) HandleHealthz(w http.ResponseWriter, r *http.Request) {
+	c.health.lock.Lock()
+	last := c.health.lastProgress
+	now := c.health.time()
+	c.health.lock.Unlock()
+	if c.stallTimeout > 0 && !last.IsZero() && now.Sub(last) > c.stallTimeout {
+		http.Error(w, fmt.Sprintf("no progress for %v", now.Sub(last).Round(time.Second)), http.StatusInternalServerError)
+		return
+	}
+	fmt.Fprint(w, "ok")
+}
+
+// HandleReadyz answers readiness probes. It fails until the IssueCreator has loaded its token,
+// github data and test owners.
+func (c *IssueCreator) HandleReadyz(w http.ResponseWriter, r *http.Request) {
+	c.health.lock.Lock()
+	ready := c.health.ready
+	c.health.lock.Unlock()
+	if !ready {
+		http.Error(w, "not initialized", http.StatusServiceUnavailable)
+		return
+	}
+	fmt.Fprint(w, "ok")
+}
@@ -0,0 +1,52 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package creator
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHealthHandlers(t *testing.T) {
+	now := time.Unix(1000, 0)
+	c := &IssueCreator{stallTimeout: time.Minute}
+	c.health.now = func() time.Time { return now }
+
+	check := func(handler http.HandlerFunc, expected int, desc string) {
+		w := httptest.NewRecorder()
+		handler(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		if w.Code != expected {
+			t.Errorf("%s: expected status %d, got %d (%q)", desc, expected, w.Code, w.Body.String())
+		}
+	}
+
+	check(c.HandleReadyz, http.StatusServiceUnavailable, "readyz before initialization")
+	check(c.HandleHealthz, http.StatusOK, "healthz before initialization")
+
+	c.health.markReady()
+	check(c.HandleReadyz, http.StatusOK, "readyz after initialization")
+	check(c.HandleHealthz, http.StatusOK, "healthz after initialization")
+
+	now = now.Add(2 * time.Minute)
+	check(c.HandleHealthz, http.StatusInternalServerError, "healthz after a stall")
+	check(c.HandleReadyz, http.StatusOK, "readyz after a stall")
+
+	c.health.markProgress()
+	check(c.HandleHealthz, http.StatusOK, "healthz after progress")
+}
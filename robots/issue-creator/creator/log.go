@@ -0,0 +1,93 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package creator
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/golang/glog"
+)
+
+// Log writes log records as single line JSON objects, after the glog header, with stable field
+// names for the source, repo, issue ID (e.g. the triage cluster ID), github issue number, job and
+// build. The history of one issue can then be filtered out of a noisy run with a JSON query.
+// Fields that aren't set are left out.
+type Log struct {
+	fields logFields
+}
+
+// logFields are the fields of a log record other than its message.
+type logFields struct {
+	Source string `json:"source,omitempty"`
+	Repo   string `json:"repo,omitempty"`
+	ID     string `json:"id,omitempty"`
+	Number int    `json:"number,omitempty"`
+	Job    string `json:"job,omitempty"`
+	Build  int    `json:"build,omitempty"`
+}
+
+// logRecord is a single log record.
+type logRecord struct {
+	logFields
+	Msg string `json:"msg"`
+}
+
+// Log returns a Log for the issue with the given ID from the named source, in the repo that the
+// IssueCreator files issues in.
+func (c *IssueCreator) Log(source, id string) Log {
+	fields := logFields{Source: source, ID: id}
+	if c != nil && c.org != "" {
+		fields.Repo = c.org + "/" + c.project
+	}
+	return Log{fields: fields}
+}
+
+// WithNumber adds the number of the github issue to the fields.
+func (l Log) WithNumber(number int) Log {
+	l.fields.Number = number
+	return l
+}
+
+// WithJob adds a job, and the number of one of its builds unless it is 0, to the fields.
+func (l Log) WithJob(job string, build int) Log {
+	l.fields.Job = job
+	l.fields.Build = build
+	return l
+}
+
+// record renders the JSON record of a message.
+func (l Log) record(format string, args ...interface{}) string {
+	b, err := json.Marshal(logRecord{logFields: l.fields, Msg: fmt.Sprintf(format, args...)})
+	if err != nil {
+		// The fields are all strings and numbers, so this can't happen.
+		return fmt.Sprintf("%+v %s", l.fields, fmt.Sprintf(format, args...))
+	}
+	return string(b)
+}
+
+func (l Log) Infof(format string, args ...interface{}) {
+	glog.InfoDepth(1, l.record(format, args...))
+}
+
+func (l Log) Warningf(format string, args ...interface{}) {
+	glog.WarningDepth(1, l.record(format, args...))
+}
+
+func (l Log) Errorf(format string, args ...interface{}) {
+	glog.ErrorDepth(1, l.record(format, args...))
+}
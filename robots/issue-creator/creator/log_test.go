@@ -0,0 +1,58 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package creator
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestLogRecord(t *testing.T) {
+	c := &IssueCreator{org: "kubernetes", project: "kubernetes"}
+	tests := []struct {
+		name     string
+		log      Log
+		expected string
+	}{
+		{
+			name:     "issue",
+			log:      c.Log("triage-filer", `cluster "1"`).WithNumber(42),
+			expected: `{"source":"triage-filer","repo":"kubernetes/kubernetes","id":"cluster \"1\"","number":42,"msg":"Created issue \"x\"."}`,
+		},
+		{
+			name:     "build",
+			log:      c.Log("triage-filer", "abc").WithJob("ci-job", 15),
+			expected: `{"source":"triage-filer","repo":"kubernetes/kubernetes","id":"abc","job":"ci-job","build":15,"msg":"Created issue \"x\"."}`,
+		},
+		{
+			name:     "no creator",
+			log:      (*IssueCreator)(nil).Log("flakyjob-reporter", "").WithJob("ci-job", 0),
+			expected: `{"source":"flakyjob-reporter","job":"ci-job","msg":"Created issue \"x\"."}`,
+		},
+	}
+	for _, test := range tests {
+		record := test.log.record("Created issue %q.", "x")
+		if record != test.expected {
+			t.Errorf("%s: expected %s, got %s", test.name, test.expected, record)
+		}
+		var fields map[string]interface{}
+		if err := json.Unmarshal([]byte(record), &fields); err != nil || !reflect.DeepEqual(fields["msg"], `Created issue "x".`) {
+			t.Errorf("%s: expected a JSON record, got %s (%v)", test.name, record, err)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package creator
+
+import (
+	"net/http"
+	"runtime"
+	"strconv"
+
+	"github.com/golang/glog"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// sourceDuration records how long each source takes to generate and sync its issues.
+var sourceDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "issue_creator_source_duration_seconds",
+	Help:    "Time taken by each issue source to generate and sync its issues.",
+	Buckets: prometheus.ExponentialBuckets(1, 2, 12),
+}, []string{"source"})
+
+// sourceErrors counts the runs of each source that failed to generate issues.
+var sourceErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "issue_creator_source_errors_total",
+	Help: "Runs of each issue source that failed to generate issues.",
+}, []string{"source"})
+
+// issuesSynced counts the issues synced from each source by whether a github issue was created
+// ("created") or not ("not_created") because an open one exists, the source skipped it, or the
+// creation failed.
+var issuesSynced = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "issue_creator_issues_synced_total",
+	Help: "Issues synced by source and result (created or not_created).",
+}, []string{"source", "result"})
+
+// githubRequests counts the HTTP requests sent to github by status code, or "error" if no response
+// was received.
+var githubRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "issue_creator_github_requests_total",
+	Help: "HTTP requests sent to github by status code.",
+}, []string{"code"})
+
+// githubRateRemaining is the number of github API requests left in the current rate limit window.
+var githubRateRemaining = prometheus.NewGauge(prometheus.GaugeOpts{
+	Name: "issue_creator_github_rate_limit_remaining",
+	Help: "The github API requests remaining in the current rate limit window.",
+})
+
+// stageAllocBytes counts the bytes allocated during each instrumented stage of a run, like loading
+// the triage data or rendering issue bodies.
+var stageAllocBytes = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "issue_creator_stage_alloc_bytes_total",
+	Help: "Bytes allocated during each instrumented stage of a run.",
+}, []string{"stage"})
+
+// stageAllocs counts the heap objects allocated during each instrumented stage of a run.
+var stageAllocs = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "issue_creator_stage_allocs_total",
+	Help: "Heap objects allocated during each instrumented stage of a run.",
+}, []string{"stage"})
+
+// stageHeapBytes is the size of the live heap when each instrumented stage last finished.
+var stageHeapBytes = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "issue_creator_stage_heap_bytes",
+	Help: "Bytes of allocated heap objects when each instrumented stage last finished.",
+}, []string{"stage"})
+
+func init() {
+	prometheus.MustRegister(sourceDuration)
+	prometheus.MustRegister(sourceErrors)
+	prometheus.MustRegister(issuesSynced)
+	prometheus.MustRegister(githubRequests)
+	prometheus.MustRegister(githubRateRemaining)
+	prometheus.MustRegister(stageAllocBytes)
+	prometheus.MustRegister(stageAllocs)
+	prometheus.MustRegister(stageHeapBytes)
+}
+
+// TrackAllocs starts accounting the allocations of a stage and returns the function that finishes
+// it, e.g. defer TrackAllocs("render")(). The counts are process wide, so they include anything
+// that runs concurrently with the stage.
+func TrackAllocs(stage string) func() {
+	var before runtime.MemStats
+	runtime.ReadMemStats(&before)
+	return func() {
+		var after runtime.MemStats
+		runtime.ReadMemStats(&after)
+		stageAllocBytes.WithLabelValues(stage).Add(float64(after.TotalAlloc - before.TotalAlloc))
+		stageAllocs.WithLabelValues(stage).Add(float64(after.Mallocs - before.Mallocs))
+		stageHeapBytes.WithLabelValues(stage).Set(float64(after.HeapAlloc))
+		glog.V(2).Infof("Stage %s allocated %d bytes in %d objects, leaving %d bytes of heap.",
+			stage, after.TotalAlloc-before.TotalAlloc, after.Mallocs-before.Mallocs, after.HeapAlloc)
+	}
+}
+
+// githubTransport records the github API consumption of the requests sent with next.
+type githubTransport struct {
+	next http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *githubTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		githubRequests.WithLabelValues("error").Inc()
+		return nil, err
+	}
+	githubRequests.WithLabelValues(strconv.Itoa(resp.StatusCode)).Inc()
+	if remaining, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Remaining")); err == nil {
+		githubRateRemaining.Set(float64(remaining))
+	}
+	return resp, nil
+}
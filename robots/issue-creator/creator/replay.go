@@ -0,0 +1,213 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package creator
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/golang/glog"
+	"github.com/google/go-github/github"
+)
+
+// replayAuthor is the login that issues filed during a replay are authored by.
+const replayAuthor = "issue-creator-replay"
+
+// SnapshotSource is implemented by IssueSources that can be replayed over snapshots of their data.
+type SnapshotSource interface {
+	IssueSource
+	// UseSnapshot makes the source read its data from the file at path. The source must not make
+	// changes outside of the IssueCreator afterwards, e.g. exporting data.
+	UseSnapshot(path string)
+}
+
+// Replay replays the snapshots in c.ReplayDir in name order, so dated names replay oldest first.
+// Each SnapshotSource syncs its issues for every snapshot against a simulated repo that starts
+// empty, and what would have been filed and updated is written to w. Github is never contacted.
+func (c *IssueCreator) Replay(ctx context.Context, w io.Writer) error {
+	files, err := ioutil.ReadDir(c.ReplayDir)
+	if err != nil {
+		return fmt.Errorf("failed to list the snapshots in '%s': %v", c.ReplayDir, err)
+	}
+	var snapshots []string
+	for _, file := range files {
+		if !file.IsDir() {
+			snapshots = append(snapshots, file.Name())
+		}
+	}
+	sort.Strings(snapshots)
+
+	var names []string
+	for name, src := range sources {
+		if _, ok := src.(SnapshotSource); !ok {
+			glog.Infof("source=%s msg=Skipping the source, it can't be replayed.", name)
+			continue
+		}
+		if c.sourceEnabled(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	if len(names) == 0 {
+		return fmt.Errorf("none of the enabled sources can be replayed")
+	}
+
+	if c.transport, err = newTransport(c.proxyURL, c.caFile); err != nil {
+		return err
+	}
+	c.fetchTransport = newRateLimitedTransport(c.fetchQPS, c.fetchBurst, c.transport)
+	if err = c.initOwners(ctx); err != nil {
+		return err
+	}
+	client := &replayClient{}
+	c.client = client
+	c.dryRun = false
+	c.authorName = replayAuthor
+	c.validLabels = nil
+	c.Collaborators = nil
+	c.allIssues = map[int]*github.Issue{}
+
+	for _, snapshot := range snapshots {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		for _, name := range names {
+			src := sources[name].(SnapshotSource)
+			src.UseSnapshot(filepath.Join(c.ReplayDir, snapshot))
+			issues, err := src.Issues(ctx, c)
+			if err != nil {
+				client.record("%s: error generating issues: %v", name, err)
+				continue
+			}
+			for _, issue := range issues {
+				c.sync(ctx, name, issue)
+			}
+		}
+		fmt.Fprintf(w, "== %s ==\n", snapshot)
+		if len(client.events) == 0 {
+			fmt.Fprint(w, "No changes.\n")
+		}
+		for _, event := range client.events {
+			fmt.Fprintf(w, "%s\n", event)
+		}
+		fmt.Fprint(w, "\n")
+		client.events = nil
+	}
+	return nil
+}
+
+// replayClient is an in memory RepoClient that records the changes made to it.
+type replayClient struct {
+	issues   []*github.Issue
+	comments map[int][]*github.IssueComment
+	events   []string
+}
+
+func (r *replayClient) record(format string, args ...interface{}) {
+	r.events = append(r.events, fmt.Sprintf(format, args...))
+}
+
+func (r *replayClient) GetUser(ctx context.Context, login string) (*github.User, error) {
+	if login == "" {
+		login = replayAuthor
+	}
+	return &github.User{Login: &login}, nil
+}
+
+func (r *replayClient) GetRepoLabels(ctx context.Context, org, repo string) ([]*github.Label, error) {
+	return nil, nil
+}
+
+func (r *replayClient) GetIssues(ctx context.Context, org, repo string, options *github.IssueListByRepoOptions) ([]*github.Issue, error) {
+	return r.issues, nil
+}
+
+func (r *replayClient) CreateIssue(ctx context.Context, org, repo, title, body string, labels, owners []string) (*github.Issue, error) {
+	number := len(r.issues) + 1
+	state := "open"
+	login := replayAuthor
+	issue := &github.Issue{Number: &number, Title: &title, Body: &body, State: &state, User: &github.User{Login: &login}}
+	for i := range labels {
+		issue.Labels = append(issue.Labels, github.Label{Name: &labels[i]})
+	}
+	r.issues = append(r.issues, issue)
+	r.record("Filed #%d %q labels=%q assignees=%q", number, title, labels, owners)
+	return issue, nil
+}
+
+func (r *replayClient) EditIssue(ctx context.Context, org, repo string, number int, edit *github.IssueRequest) (*github.Issue, error) {
+	if number < 1 || number > len(r.issues) {
+		return nil, fmt.Errorf("issue #%d does not exist", number)
+	}
+	issue := r.issues[number-1]
+	var changes []string
+	if edit.Body != nil && *edit.Body != *issue.Body {
+		issue.Body = edit.Body
+		changes = append(changes, "body")
+	}
+	if edit.Labels != nil {
+		old := map[string]bool{}
+		for _, label := range issue.Labels {
+			old[*label.Name] = true
+		}
+		var labelChanges []string
+		issue.Labels = nil
+		for i, label := range *edit.Labels {
+			issue.Labels = append(issue.Labels, github.Label{Name: &(*edit.Labels)[i]})
+			if !old[label] {
+				labelChanges = append(labelChanges, "+"+label)
+			}
+			delete(old, label)
+		}
+		for label := range old {
+			labelChanges = append(labelChanges, "-"+label)
+		}
+		sort.Strings(labelChanges)
+		changes = append(changes, labelChanges...)
+	}
+	if len(changes) > 0 {
+		r.record("Updated #%d %q: %s", number, *issue.Title, strings.Join(changes, " "))
+	}
+	return issue, nil
+}
+
+func (r *replayClient) CreateComment(ctx context.Context, org, repo string, number int, body string) (*github.IssueComment, error) {
+	if r.comments == nil {
+		r.comments = map[int][]*github.IssueComment{}
+	}
+	comment := &github.IssueComment{Body: &body}
+	r.comments[number] = append(r.comments[number], comment)
+	r.record("Commented on #%d", number)
+	return comment, nil
+}
+
+func (r *replayClient) GetIssueComments(ctx context.Context, org, repo string, number int) ([]*github.IssueComment, error) {
+	return r.comments[number], nil
+}
+
+func (r *replayClient) GetCollaborators(ctx context.Context, org, repo string) ([]*github.User, error) {
+	return nil, nil
+}
+
+func (r *replayClient) GetTeamMembers(ctx context.Context, org, team string) ([]*github.User, error) {
+	return nil, nil
+}
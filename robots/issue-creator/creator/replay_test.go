@@ -0,0 +1,108 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package creator
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// snapshotSource files an issue for every line of its snapshot, and labels the open issues whose
+// ID is no longer in the snapshot as stale.
+type snapshotSource struct {
+	path string
+}
+
+func (s *snapshotSource) RegisterFlags() {}
+
+func (s *snapshotSource) UseSnapshot(path string) {
+	s.path = path
+}
+
+func (s *snapshotSource) Issues(ctx context.Context, c *IssueCreator) ([]Issue, error) {
+	b, err := ioutil.ReadFile(s.path)
+	if err != nil {
+		return nil, err
+	}
+	var issues []Issue
+	for _, id := range strings.Fields(string(b)) {
+		issues = append(issues, &fakeIssue{title: "Flake " + id, body: "Body of " + id, id: id, labels: []string{"kind/flake"}})
+	}
+	for _, open := range c.OpenIssues() {
+		if !strings.Contains(string(b), strings.TrimPrefix(*open.Body, "Body of ")) {
+			if err := c.UpdateIssue(context.Background(), *open.Number, *open.Body, []string{"kind/flake", "lifecycle/stale"}); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return issues, nil
+}
+
+// liveSource can't be replayed.
+type liveSource struct{}
+
+func (s *liveSource) RegisterFlags() {}
+
+func (s *liveSource) Issues(ctx context.Context, c *IssueCreator) ([]Issue, error) {
+	return []Issue{&fakeIssue{title: "Live", body: "live", id: "live"}}, nil
+}
+
+func TestReplay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "replay")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	for name, content := range map[string]string{
+		"2017-06-01.json": "a b",
+		"2017-06-02.json": "a b",
+		"2017-06-03.json": "b c",
+	} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	saved := sources
+	defer func() { sources = saved }()
+	sources = map[string]IssueSource{"snapshots": &snapshotSource{}, "live": &liveSource{}}
+
+	c := &IssueCreator{ReplayDir: dir, dryRun: true}
+	var out bytes.Buffer
+	if err := c.Replay(context.Background(), &out); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := `== 2017-06-01.json ==
+Filed #1 "Flake a" labels=["kind/flake"] assignees=[]
+Filed #2 "Flake b" labels=["kind/flake"] assignees=[]
+
+== 2017-06-02.json ==
+No changes.
+
+== 2017-06-03.json ==
+Updated #1 "Flake a": +lifecycle/stale
+Filed #3 "Flake c" labels=["kind/flake"] assignees=[]
+
+`
+	if out.String() != expected {
+		t.Errorf("Expected replay report:\n%s\ngot:\n%s", expected, out.String())
+	}
+}
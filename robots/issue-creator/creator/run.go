@@ -0,0 +1,124 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package creator
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// RunsOnDemand returns true iff '--run-token-file' enables on-demand runs, so the process should
+// keep serving them after the first run.
+func (c *IssueCreator) RunsOnDemand() bool {
+	return c.runTokenFile != ""
+}
+
+// RunHandler returns a handler for POST requests that run the named source immediately instead of
+// waiting for the next scheduled run, e.g. as soon as the data it reads is refreshed. Requests must
+// carry the token from '--run-token-file' as a bearer token. With dry_run=true nothing is changed on
+// github. Each decision about an issue is streamed to the response as it is made.
+func (c *IssueCreator) RunHandler(source string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "only POST is allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		c.health.lock.Lock()
+		ready := c.health.ready
+		c.health.lock.Unlock()
+		if !ready {
+			http.Error(w, "not initialized", http.StatusServiceUnavailable)
+			return
+		}
+		if c.runToken == "" {
+			http.Error(w, "on-demand runs are disabled", http.StatusNotFound)
+			return
+		}
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(c.runToken)) != 1 {
+			http.Error(w, "missing or invalid bearer token", http.StatusUnauthorized)
+			return
+		}
+		dryRun := false
+		if value := r.URL.Query().Get("dry_run"); value != "" {
+			var err error
+			if dryRun, err = strconv.ParseBool(value); err != nil {
+				http.Error(w, fmt.Sprintf("invalid dry_run '%s'", value), http.StatusBadRequest)
+				return
+			}
+		}
+		src, ok := sources[source]
+		if !ok {
+			http.Error(w, fmt.Sprintf("no source named '%s'", source), http.StatusNotFound)
+			return
+		}
+		if !c.sourceEnabled(source) {
+			http.Error(w, fmt.Sprintf("source '%s' is disabled", source), http.StatusConflict)
+			return
+		}
+
+		// Runs share the cache of issues, so an on-demand run waits for the current one to finish.
+		c.runLock.Lock()
+		defer c.runLock.Unlock()
+		if err := c.loadCache(r.Context()); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		// '--dry-run' can't be overridden by a request, only made stricter.
+		savedDryRun := c.dryRun
+		c.dryRun = c.dryRun || dryRun
+		c.decisions = flushWriter{w}
+		defer func() {
+			c.dryRun = savedDryRun
+			c.decisions = nil
+		}()
+
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		c.report("Running source %s on demand (dry run: %t).", source, c.dryRun)
+		c.runErrors = nil
+		c.runSource(r.Context(), source, src)
+		if err := c.runError(); err != nil {
+			c.report("Done with %v", err)
+			return
+		}
+		c.report("Done.")
+	}
+}
+
+// report writes a line to the response of the on-demand run in progress, if there is one.
+func (c *IssueCreator) report(format string, args ...interface{}) {
+	if c.decisions != nil {
+		fmt.Fprintf(c.decisions, format+"\n", args...)
+	}
+}
+
+// flushWriter flushes every write to an HTTP response, so that it is streamed to the client.
+type flushWriter struct {
+	w http.ResponseWriter
+}
+
+func (f flushWriter) Write(p []byte) (int, error) {
+	n, err := f.w.Write(p)
+	if flusher, ok := f.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	return n, err
+}
@@ -0,0 +1,179 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package creator
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/github"
+)
+
+// fakeSource returns the same issues, or error, every run.
+type fakeSource struct {
+	issues []Issue
+	err    error
+}
+
+func (s *fakeSource) Issues(ctx context.Context, c *IssueCreator) ([]Issue, error) {
+	return s.issues, s.err
+}
+
+func (s *fakeSource) RegisterFlags() {}
+
+func TestRunHandler(t *testing.T) {
+	sources["run-test"] = &fakeSource{issues: []Issue{
+		&fakeIssue{title: "new", body: "body<NEW>", id: "<NEW>", labels: []string{"kind/flake"}},
+		&fakeIssue{title: "open", body: "body<OPEN>", id: "<OPEN>", labels: []string{"kind/flake"}},
+	}}
+	defer delete(sources, "run-test")
+	client := &fakeClient{
+		t:          t,
+		userName:   "bot",
+		repoLabels: []string{"kind/flake"},
+		issues:     []*github.Issue{makeTestIssue("open", "body<OPEN>", "open", []string{"kind/flake"}, nil, 5)},
+	}
+	c := &IssueCreator{client: client, org: "org", project: "repo", runToken: "secret"}
+	handler := c.RunHandler("run-test")
+
+	run := func(method, query, token string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, "/triage/run"+query, nil)
+		if token != "" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		handler(w, r)
+		return w
+	}
+
+	if w := run(http.MethodPost, "", "secret"); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status %d before initialization, got %d", http.StatusServiceUnavailable, w.Code)
+	}
+	c.health.markReady()
+	for _, test := range []struct {
+		desc, method, query, token string
+		expected                   int
+	}{
+		{desc: "GET", method: http.MethodGet, token: "secret", expected: http.StatusMethodNotAllowed},
+		{desc: "no token", method: http.MethodPost, expected: http.StatusUnauthorized},
+		{desc: "wrong token", method: http.MethodPost, token: "guess", expected: http.StatusUnauthorized},
+		{desc: "bad dry_run", method: http.MethodPost, query: "?dry_run=maybe", token: "secret", expected: http.StatusBadRequest},
+	} {
+		if w := run(test.method, test.query, test.token); w.Code != test.expected {
+			t.Errorf("%s: expected status %d, got %d (%q)", test.desc, test.expected, w.Code, w.Body.String())
+		}
+	}
+
+	w := run(http.MethodPost, "?dry_run=true", "secret")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected a dry run to succeed, got %d (%q)", w.Code, w.Body.String())
+	}
+	for _, expected := range []string{
+		"(dry run: true)",
+		"<NEW>: would file \"new\"",
+		"<OPEN>: already tracked by #5.",
+		"Done.",
+	} {
+		if !strings.Contains(w.Body.String(), expected) {
+			t.Errorf("Expected the dry run report to contain %q, got:\n%s", expected, w.Body.String())
+		}
+	}
+	if len(client.issues) != 1 || c.dryRun || c.decisions != nil {
+		t.Errorf("Expected a dry run to change nothing and restore the IssueCreator, got %d issues", len(client.issues))
+	}
+
+	w = run(http.MethodPost, "", "secret")
+	if !strings.Contains(w.Body.String(), "<NEW>: filed #") || len(client.issues) != 2 {
+		t.Errorf("Expected the run to file the new issue, got:\n%s", w.Body.String())
+	}
+
+	c.runToken = ""
+	if w := run(http.MethodPost, "", "secret"); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d without a run token, got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+// failingSource records a failure and then lingers, so that an on-demand run can try to start
+// while the scheduled run is still going.
+type failingSource struct {
+	started chan struct{}
+}
+
+func (s *failingSource) Issues(ctx context.Context, c *IssueCreator) ([]Issue, error) {
+	c.RecordError(errors.New("failed to update issue #3"))
+	close(s.started)
+	time.Sleep(50 * time.Millisecond)
+	return nil, nil
+}
+
+func (s *failingSource) RegisterFlags() {}
+
+func TestRunHandlerDuringRun(t *testing.T) {
+	failing := &failingSource{started: make(chan struct{})}
+	sources["run-failing"] = failing
+	sources["run-demand"] = &fakeSource{}
+	defer delete(sources, "run-failing")
+	defer delete(sources, "run-demand")
+	client := &fakeClient{t: t, userName: "bot"}
+	c := &IssueCreator{client: client, org: "org", project: "repo", runToken: "secret", enabledSources: "run-failing,run-demand"}
+	if err := c.loadCache(context.Background()); err != nil {
+		t.Fatalf("Unexpected error loading the cache: %v", err)
+	}
+	c.health.markReady()
+
+	done := make(chan error)
+	go func() { done <- c.syncSources(context.Background()) }()
+	<-failing.started
+	r := httptest.NewRequest(http.MethodPost, "/triage/run?dry_run=true", nil)
+	r.Header.Set("Authorization", "Bearer secret")
+	w := httptest.NewRecorder()
+	c.RunHandler("run-demand")(w, r)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "Done.") {
+		t.Errorf("Expected the on-demand run to succeed after the scheduled one, got %d (%q)", w.Code, w.Body.String())
+	}
+	if err := <-done; err == nil || !strings.Contains(err.Error(), "issue #3") {
+		t.Errorf("Expected the scheduled run to keep its failure, got %v", err)
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	client := &fakeClient{t: t, userName: "bot"}
+	c := &IssueCreator{client: client, org: "org", project: "repo"}
+	if err := c.loadCache(context.Background()); err != nil {
+		t.Fatalf("Unexpected error loading the cache: %v", err)
+	}
+
+	c.runSource(context.Background(), "ok", &fakeSource{})
+	if err := c.runError(); err != nil {
+		t.Errorf("Expected no error after a successful run, got %v", err)
+	}
+
+	c.runSource(context.Background(), "broken", &fakeSource{err: errors.New("no data")})
+	c.RecordError(errors.New("failed to update issue #3"))
+	err := c.runError()
+	if err == nil || !strings.Contains(err.Error(), "source broken: no data") || !strings.Contains(err.Error(), "issue #3") {
+		t.Errorf("Expected an error listing both failures, got %v", err)
+	}
+	if err := c.runError(); err != nil {
+		t.Errorf("Expected the failures to be cleared after they are returned, got %v", err)
+	}
+}
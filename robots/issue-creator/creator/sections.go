@@ -0,0 +1,78 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package creator
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// sectionRegex matches a section of an issue body wrapped by Section. The name is captured.
+var sectionRegex = regexp.MustCompile(`(?s)<!-- issue-creator section: ([^ ]+) -->\n.*?<!-- /issue-creator section: ([^ ]+) -->\n`)
+
+// Section wraps content in hidden markers so that the section can be regenerated by UpdateSections
+// without touching the rest of the body. Names must not contain spaces.
+func Section(name, content string) string {
+	if !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+	return fmt.Sprintf("<!-- issue-creator section: %s -->\n%s<!-- /issue-creator section: %s -->\n", name, content, name)
+}
+
+// sections maps the names of the sections in body to the sections, markers included.
+func sections(body string) map[string]string {
+	found := map[string]string{}
+	for _, match := range sectionRegex.FindAllStringSubmatch(body, -1) {
+		if match[1] == match[2] {
+			found[match[1]] = match[0]
+		}
+	}
+	return found
+}
+
+// UpdateSections returns current with each of its sections replaced by the section of the same name
+// in generated, so that edits a human made outside of the sections survive. Sections of generated
+// that current lacks are appended. If current has no sections at all, as for issues filed before
+// sections were used, generated is returned. The bool is false iff the body is unchanged.
+func UpdateSections(current, generated string) (string, bool) {
+	if !sectionRegex.MatchString(current) {
+		return generated, generated != current
+	}
+	fresh := sections(generated)
+	seen := map[string]bool{}
+	updated := sectionRegex.ReplaceAllStringFunc(current, func(old string) string {
+		match := sectionRegex.FindStringSubmatch(old)
+		if match[1] != match[2] {
+			return old
+		}
+		seen[match[1]] = true
+		if section, ok := fresh[match[1]]; ok {
+			return section
+		}
+		return old
+	})
+	for _, match := range sectionRegex.FindAllStringSubmatch(generated, -1) {
+		if match[1] == match[2] && !seen[match[1]] {
+			if !strings.HasSuffix(updated, "\n") {
+				updated += "\n"
+			}
+			updated += match[0]
+		}
+	}
+	return updated, updated != current
+}
@@ -0,0 +1,69 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package creator
+
+import "testing"
+
+func TestUpdateSections(t *testing.T) {
+	generated := "### Header\n" + Section("stats", "3 builds failed") + Section("owners", "/assign @a\n")
+	tcs := []struct {
+		name     string
+		current  string
+		expected string
+		changed  bool
+	}{
+		{
+			name:     "legacy body is replaced",
+			current:  "### Header\n2 builds failed\n",
+			expected: generated,
+			changed:  true,
+		},
+		{
+			name:     "unchanged",
+			current:  generated,
+			expected: generated,
+			changed:  false,
+		},
+		{
+			name:     "only sections are replaced",
+			current:  "### Header (edited)\n" + Section("stats", "2 builds failed") + "A human note.\n" + Section("owners", "/assign @a\n"),
+			expected: "### Header (edited)\n" + Section("stats", "3 builds failed") + "A human note.\n" + Section("owners", "/assign @a\n"),
+			changed:  true,
+		},
+		{
+			name:     "missing sections are appended",
+			current:  "### Header\n" + Section("stats", "2 builds failed") + "A human note.",
+			expected: "### Header\n" + Section("stats", "3 builds failed") + "A human note.\n" + Section("owners", "/assign @a\n"),
+			changed:  true,
+		},
+		{
+			name:     "sections no longer generated are kept",
+			current:  Section("old", "stale") + Section("stats", "3 builds failed"),
+			expected: Section("old", "stale") + Section("stats", "3 builds failed") + Section("owners", "/assign @a\n"),
+			changed:  true,
+		},
+	}
+	for _, tc := range tcs {
+		updated, changed := UpdateSections(tc.current, generated)
+		if updated != tc.expected {
+			t.Errorf("%s: expected body:\n%s\ngot:\n%s", tc.name, tc.expected, updated)
+		}
+		if changed != tc.changed {
+			t.Errorf("%s: expected changed to be %t, got %t", tc.name, tc.changed, changed)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package creator
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+
+	"golang.org/x/time/rate"
+)
+
+// newTransport creates the transport for all outbound HTTP requests. If proxyURL is empty the
+// HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables are used. If caFile is set, the PEM
+// certificates in it are trusted in addition to the system roots.
+func newTransport(proxyURL, caFile string) (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if proxyURL != "" {
+		proxy, err := url.Parse(proxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy URL '%s': %v", proxyURL, err)
+		}
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+	if caFile != "" {
+		pem, err := ioutil.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file '%s': %v", caFile, err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no PEM certificates found in CA file '%s'", caFile)
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+	}
+	return transport, nil
+}
+
+// rateLimitedTransport waits for a token from a shared bucket before sending each request.
+type rateLimitedTransport struct {
+	limiter *rate.Limiter
+	next    http.RoundTripper
+}
+
+// newRateLimitedTransport limits the requests sent with next to qps per second on average, with
+// bursts of up to burst requests. A qps of 0 or less means no limit.
+func newRateLimitedTransport(qps float64, burst int, next http.RoundTripper) http.RoundTripper {
+	if qps <= 0 {
+		return next
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	return &rateLimitedTransport{limiter: rate.NewLimiter(rate.Limit(qps), burst), next: next}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *rateLimitedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.limiter.Wait(req.Context()); err != nil {
+		return nil, err
+	}
+	return t.next.RoundTrip(req)
+}
+
+// Transport returns the transport that sources should use for outbound non-GitHub HTTP requests,
+// so that they honor the proxy and CA configuration and share one rate limit.
+func (c *IssueCreator) Transport() http.RoundTripper {
+	if c.fetchTransport == nil {
+		return http.DefaultTransport
+	}
+	return c.fetchTransport
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package creator
+
+import (
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTransportCA(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "ok")
+	}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "transport")
+	if err != nil {
+		t.Fatalf("failed to create a temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	caFile := filepath.Join(dir, "ca.pem")
+	cert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := ioutil.WriteFile(caFile, cert, 0644); err != nil {
+		t.Fatalf("failed to write %s: %v", caFile, err)
+	}
+
+	untrusted, err := newTransport("", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := (&http.Client{Transport: untrusted}).Get(server.URL); err == nil {
+		t.Error("expected the test server's certificate not to be trusted by default")
+	}
+	trusted, err := newTransport("", caFile)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp, err := (&http.Client{Transport: trusted}).Get(server.URL); err != nil {
+		t.Errorf("expected the CA file to be trusted, got %v", err)
+	} else {
+		resp.Body.Close()
+	}
+
+	if _, err := newTransport("", filepath.Join(dir, "missing.pem")); err == nil {
+		t.Error("expected an error for a missing CA file")
+	}
+}
+
+func TestTransportProxy(t *testing.T) {
+	proxied := 0
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied++
+		fmt.Fprint(w, "proxied")
+	}))
+	defer proxy.Close()
+
+	transport, err := newTransport(proxy.URL, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp, err := (&http.Client{Transport: transport}).Get("http://example.invalid/data")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+	if body, _ := ioutil.ReadAll(resp.Body); string(body) != "proxied" || proxied != 1 {
+		t.Errorf("expected the request to go through the proxy, got %q after %d proxied requests", body, proxied)
+	}
+}
+
+func TestRateLimitedTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	if transport := newRateLimitedTransport(0, 5, http.DefaultTransport); transport != http.DefaultTransport {
+		t.Error("expected no rate limit for a qps of 0")
+	}
+
+	// A burst of 2 at 20 qps lets two requests through at once, then spaces the rest 50ms apart.
+	client := &http.Client{Transport: newRateLimitedTransport(20, 2, http.DefaultTransport)}
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		resp.Body.Close()
+	}
+	if elapsed := time.Since(start); elapsed < 80*time.Millisecond {
+		t.Errorf("expected 4 requests to take at least 100ms with a burst of 2 at 20 qps, took %v", elapsed)
+	}
+}
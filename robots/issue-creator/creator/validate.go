@@ -0,0 +1,156 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package creator
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"k8s.io/test-infra/robots/issue-creator/testowner"
+)
+
+// SourceValidator is implemented by IssueSources that can check their flags before any issues are
+// synced, so that a bad configuration fails at startup rather than partway through a run.
+type SourceValidator interface {
+	// Validate returns every problem found with the source's flags.
+	Validate() []error
+}
+
+// Validate checks the flags of the IssueCreator and of every enabled source that implements
+// SourceValidator. It returns a single error listing every problem found, or nil.
+func (c *IssueCreator) Validate() error {
+	var problems []string
+	add := func(errs ...error) {
+		for _, err := range errs {
+			if err != nil {
+				problems = append(problems, err.Error())
+			}
+		}
+	}
+
+	for flagName, value := range map[string]string{"org": c.org, "project": c.project, "token-file": c.tokenFile} {
+		if value == "" {
+			add(fmt.Errorf("'--%s' is a required flag", flagName))
+		}
+	}
+	if c.tokenFile != "" {
+		add(ValidateFile("token-file", c.tokenFile))
+	}
+	add(ValidateURL("github-endpoint", c.githubEndpoint))
+	if c.githubUploadEndpoint != "" {
+		add(ValidateURL("github-upload-endpoint", c.githubUploadEndpoint))
+	}
+	if c.proxyURL != "" {
+		add(ValidateURL("proxy-url", c.proxyURL))
+	}
+	if c.caFile != "" {
+		add(ValidateFile("ca-cert-file", c.caFile))
+	}
+	if c.runTokenFile != "" {
+		add(ValidateFile("run-token-file", c.runTokenFile))
+		if c.RunOnce {
+			add(fmt.Errorf("'--run-once' can't be used with '--run-token-file', which keeps serving after the run"))
+		}
+	}
+	if c.latestBuildTime != "" {
+		if _, err := time.Parse(time.RFC3339, c.latestBuildTime); err != nil {
+			add(fmt.Errorf("'--latest-build-time' must be an RFC 3339 time: %v", err))
+		}
+	}
+	if c.fetchQPS < 0 {
+		add(fmt.Errorf("'--fetch-qps' must not be negative, got %v", c.fetchQPS))
+	}
+	if c.fetchQPS > 0 && c.fetchBurst < 1 {
+		add(fmt.Errorf("'--fetch-burst' must be at least 1 when '--fetch-qps' is set, got %d", c.fetchBurst))
+	}
+
+	ownerPath := strings.TrimPrefix(c.ownerPath, "file://")
+	if ownerPath != "" && c.ownerURL != "" {
+		add(fmt.Errorf("only one of '--test-owners-csv' and '--test-owners-url' may be specified"))
+	}
+	if ownerPath != "" {
+		add(ValidateFile("test-owners-csv", ownerPath))
+		_, err := testowner.ParseConflictPolicy(c.ownerConflicts)
+		add(err)
+		_, err = testowner.ParseMatchMode(c.ownerMatching)
+		add(err)
+	}
+	if c.ownerURL != "" {
+		add(ValidateURL("test-owners-url", c.ownerURL))
+	}
+
+	if c.enabledSources != "" {
+		for _, name := range strings.Split(c.enabledSources, ",") {
+			if name = strings.TrimSpace(name); name != "" && sources[name] == nil {
+				add(fmt.Errorf("'--sources' names unknown source '%s'", name))
+			}
+		}
+	}
+	var names []string
+	for name := range sources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		validator, ok := sources[name].(SourceValidator)
+		if !ok || !c.sourceEnabled(name) {
+			continue
+		}
+		for _, err := range validator.Validate() {
+			add(fmt.Errorf("source %s: %v", name, err))
+		}
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+	sort.Strings(problems)
+	return fmt.Errorf("found %d configuration problems:\n  %s", len(problems), strings.Join(problems, "\n  "))
+}
+
+// ValidateFile returns an error naming flagName if path is not a readable file.
+func ValidateFile(flagName, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("'--%s': cannot read '%s': %v", flagName, path, err)
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("'--%s': cannot read '%s': %v", flagName, path, err)
+	}
+	if info.IsDir() {
+		return fmt.Errorf("'--%s': '%s' is a directory, not a file", flagName, path)
+	}
+	return nil
+}
+
+// ValidateURL returns an error naming flagName if rawURL is not an absolute URL with a host.
+func ValidateURL(flagName, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("'--%s': invalid URL '%s': %v", flagName, rawURL, err)
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("'--%s': '%s' is not an absolute URL", flagName, rawURL)
+	}
+	return nil
+}
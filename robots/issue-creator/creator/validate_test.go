@@ -0,0 +1,145 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package creator
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type validatingSource struct {
+	errs []error
+}
+
+func (v *validatingSource) Issues(ctx context.Context, c *IssueCreator) ([]Issue, error) {
+	return nil, nil
+}
+
+func (v *validatingSource) RegisterFlags() {}
+
+func (v *validatingSource) Validate() []error {
+	return v.errs
+}
+
+func TestValidate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "validate")
+	if err != nil {
+		t.Fatalf("Failed to create a temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	tokenFile := filepath.Join(dir, "token")
+	if err := ioutil.WriteFile(tokenFile, []byte("token"), 0644); err != nil {
+		t.Fatalf("Failed to write the token file: %v", err)
+	}
+	disabledFile := filepath.Join(dir, "disabled")
+	if err := ioutil.WriteFile(disabledFile, []byte("validate-test\n"), 0644); err != nil {
+		t.Fatalf("Failed to write the disabled sources file: %v", err)
+	}
+	src := &validatingSource{}
+	RegisterSourceOrDie("validate-test", src)
+	defer delete(sources, "validate-test")
+
+	valid := func() *IssueCreator {
+		return &IssueCreator{
+			org:            "org",
+			project:        "project",
+			tokenFile:      tokenFile,
+			githubEndpoint: "https://api.github.com",
+		}
+	}
+	tests := []struct {
+		name     string
+		modify   func(c *IssueCreator)
+		problems []string
+	}{
+		{name: "valid", modify: func(c *IssueCreator) {}},
+		{
+			name: "missing required flags and files",
+			modify: func(c *IssueCreator) {
+				c.org = ""
+				c.caFile = filepath.Join(dir, "missing.pem")
+				c.ownerPath = dir
+			},
+			problems: []string{"'--org' is a required flag", "'--ca-cert-file'", "'--test-owners-csv'"},
+		},
+		{
+			name: "bad URLs",
+			modify: func(c *IssueCreator) {
+				c.githubEndpoint = "api.github.com"
+				c.proxyURL = "http://%zz"
+			},
+			problems: []string{"'--github-endpoint'", "'--proxy-url'"},
+		},
+		{
+			name:     "bad latest build time",
+			modify:   func(c *IssueCreator) { c.latestBuildTime = "2017-06-01" },
+			problems: []string{"'--latest-build-time' must be an RFC 3339 time"},
+		},
+		{
+			name: "run once while serving runs",
+			modify: func(c *IssueCreator) {
+				c.RunOnce = true
+				c.runTokenFile = tokenFile
+			},
+			problems: []string{"'--run-once' can't be used with '--run-token-file'"},
+		},
+		{
+			name:     "unknown source",
+			modify:   func(c *IssueCreator) { c.enabledSources = "no-such-source" },
+			problems: []string{"unknown source 'no-such-source'"},
+		},
+		{
+			name:     "source problems",
+			modify:   func(c *IssueCreator) { src.errs = []error{errors.New("bad source flag")} },
+			problems: []string{"source validate-test: bad source flag"},
+		},
+		{
+			name: "disabled source",
+			modify: func(c *IssueCreator) {
+				src.errs = []error{errors.New("bad source flag")}
+				c.enabledSources = "validate-test,"
+				c.disabledSourcesFile = disabledFile
+			},
+		},
+	}
+	for _, test := range tests {
+		c := valid()
+		src.errs = nil
+		test.modify(c)
+		err := c.Validate()
+		if len(test.problems) == 0 {
+			if err != nil {
+				t.Errorf("%s: unexpected error: %v", test.name, err)
+			}
+			continue
+		}
+		if err == nil {
+			t.Errorf("%s: expected an error", test.name)
+			continue
+		}
+		for _, problem := range test.problems {
+			if !strings.Contains(err.Error(), problem) {
+				t.Errorf("%s: expected the error to mention %q, got: %v", test.name, problem, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,327 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// gen-fixture samples a triage failure_data.json down to a few clusters so that it can be used as
+// test data for the TriageFiler. The build rows referenced by the sample are kept and renumbered,
+// and every job keeps the buildnum to row encoding (contiguous or dictionary) it had in the input.
+//
+// Usage: go run ./robots/issue-creator/gen-fixture --input=failure_data.json > fixture.json
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+
+	"github.com/golang/glog"
+)
+
+// options controls how much of the triage data is kept.
+type options struct {
+	clusters int
+	tests    int
+	jobs     int
+	builds   int
+	// maxContig is the most rows kept for a job with a contiguous row mapping. Builds that don't fit
+	// are dropped, oldest first.
+	maxContig int
+	// endTime, if not zero, shifts every start time so that the latest build starts at endTime.
+	endTime int64
+}
+
+// triageData is the part of the triage JSON format that is sampled. Columns are kept raw since only
+// their rows are selected.
+type triageData struct {
+	Builds struct {
+		Cols     map[string][]json.RawMessage `json:"cols"`
+		Jobs     map[string]json.RawMessage   `json:"jobs"`
+		JobPaths map[string]string            `json:"job_paths"`
+	} `json:"builds"`
+	Clustered []*cluster `json:"clustered"`
+}
+
+type cluster struct {
+	ID    string  `json:"id"`
+	Key   string  `json:"key"`
+	Text  string  `json:"text"`
+	Tests []*test `json:"tests"`
+}
+
+type test struct {
+	Name string `json:"name"`
+	Jobs []*job `json:"jobs"`
+}
+
+type job struct {
+	Name   string `json:"name"`
+	Builds []int  `json:"builds"`
+}
+
+// rowMapping is a job's buildnum to row mapping in either encoding.
+type rowMapping struct {
+	contig bool
+	// startBuild, count and startRow describe a contiguous mapping.
+	startBuild, count, startRow int
+	dict                        map[string]int
+}
+
+func parseMapping(raw json.RawMessage) (*rowMapping, error) {
+	var contig []int
+	if err := json.Unmarshal(raw, &contig); err == nil {
+		if len(contig) != 3 {
+			return nil, fmt.Errorf("contiguous row mapping has %d members instead of 3", len(contig))
+		}
+		return &rowMapping{contig: true, startBuild: contig[0], count: contig[1], startRow: contig[2]}, nil
+	}
+	var dict map[string]int
+	if err := json.Unmarshal(raw, &dict); err != nil {
+		return nil, fmt.Errorf("row mapping is neither a 3 member array nor a dictionary: %v", err)
+	}
+	return &rowMapping{dict: dict}, nil
+}
+
+func (m *rowMapping) row(build int) (int, bool) {
+	if m.contig {
+		if build < m.startBuild || build >= m.startBuild+m.count {
+			return 0, false
+		}
+		return build - m.startBuild + m.startRow, true
+	}
+	row, ok := m.dict[strconv.Itoa(build)]
+	return row, ok
+}
+
+// sample reads triage JSON from in and returns a copy limited according to opts.
+func sample(in []byte, opts options) ([]byte, error) {
+	var data triageData
+	if err := json.Unmarshal(in, &data); err != nil {
+		return nil, fmt.Errorf("error parsing triage data: %v", err)
+	}
+	mappings := map[string]*rowMapping{}
+	for name, raw := range data.Builds.Jobs {
+		mapping, err := parseMapping(raw)
+		if err != nil {
+			return nil, fmt.Errorf("job '%s': %v", name, err)
+		}
+		mappings[name] = mapping
+	}
+
+	// Select the clusters, tests, jobs and builds to keep.
+	if len(data.Clustered) > opts.clusters {
+		data.Clustered = data.Clustered[:opts.clusters]
+	}
+	kept := map[string]map[int]bool{}
+	for _, clust := range data.Clustered {
+		if len(clust.Tests) > opts.tests {
+			clust.Tests = clust.Tests[:opts.tests]
+		}
+		for _, t := range clust.Tests {
+			if len(t.Jobs) > opts.jobs {
+				t.Jobs = t.Jobs[:opts.jobs]
+			}
+			for _, j := range t.Jobs {
+				if mappings[j.Name] == nil {
+					return nil, fmt.Errorf("triage data does not contain a row mapping for job '%s'", j.Name)
+				}
+				builds := append([]int(nil), j.Builds...)
+				sort.Sort(sort.Reverse(sort.IntSlice(builds)))
+				if len(builds) > opts.builds {
+					builds = builds[:opts.builds]
+				}
+				if kept[j.Name] == nil {
+					kept[j.Name] = map[int]bool{}
+				}
+				for _, build := range builds {
+					kept[j.Name][build] = true
+				}
+			}
+		}
+	}
+	for name, builds := range kept {
+		if mappings[name].contig {
+			// Rows between the kept builds are needed to keep the mapping contiguous.
+			latest := 0
+			for build := range builds {
+				if build > latest {
+					latest = build
+				}
+			}
+			for build := range builds {
+				if build <= latest-opts.maxContig {
+					delete(builds, build)
+				}
+			}
+		}
+	}
+	for _, clust := range data.Clustered {
+		var tests []*test
+		for _, t := range clust.Tests {
+			var jobs []*job
+			for _, j := range t.Jobs {
+				var builds []int
+				for _, build := range j.Builds {
+					if kept[j.Name][build] {
+						builds = append(builds, build)
+					}
+				}
+				if j.Builds = builds; len(builds) > 0 {
+					jobs = append(jobs, j)
+				}
+			}
+			if t.Jobs = jobs; len(jobs) > 0 {
+				tests = append(tests, t)
+			}
+		}
+		clust.Tests = tests
+	}
+
+	// Renumber the rows of the kept builds, job by job in name order so that output is stable.
+	names := make([]string, 0, len(kept))
+	for name := range kept {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var rows []int
+	jobs := map[string]interface{}{}
+	paths := map[string]string{}
+	contigs := 0
+	for _, name := range names {
+		mapping := mappings[name]
+		builds := make([]int, 0, len(kept[name]))
+		for build := range kept[name] {
+			builds = append(builds, build)
+		}
+		sort.Ints(builds)
+		if mapping.contig {
+			contigs++
+			first, last := builds[0], builds[len(builds)-1]
+			jobs[name] = []int{first, last - first + 1, len(rows)}
+			for build := first; build <= last; build++ {
+				row, ok := mapping.row(build)
+				if !ok {
+					return nil, fmt.Errorf("job '%s' has no row for build %d", name, build)
+				}
+				rows = append(rows, row)
+			}
+		} else {
+			dict := map[string]int{}
+			for _, build := range builds {
+				row, ok := mapping.row(build)
+				if !ok {
+					return nil, fmt.Errorf("job '%s' has no row for build %d", name, build)
+				}
+				dict[strconv.Itoa(build)] = len(rows)
+				rows = append(rows, row)
+			}
+			jobs[name] = dict
+		}
+		paths[name] = data.Builds.JobPaths[name]
+	}
+	if contigs == 0 || contigs == len(names) {
+		glog.Warningf("The sample only uses one of the two buildnum to row encodings.")
+	}
+
+	cols := map[string][]json.RawMessage{}
+	for col, values := range data.Builds.Cols {
+		sampled := make([]json.RawMessage, 0, len(rows))
+		for _, row := range rows {
+			if row >= len(values) {
+				return nil, fmt.Errorf("column '%s' has no row %d", col, row)
+			}
+			sampled = append(sampled, values[row])
+		}
+		cols[col] = sampled
+	}
+	if opts.endTime != 0 {
+		if err := shiftStarted(cols["started"], opts.endTime); err != nil {
+			return nil, err
+		}
+	}
+
+	out := map[string]interface{}{
+		"builds": map[string]interface{}{
+			"cols":      cols,
+			"jobs":      jobs,
+			"job_paths": paths,
+		},
+		"clustered": data.Clustered,
+	}
+	return json.MarshalIndent(out, "", "  ")
+}
+
+// shiftStarted moves the start times in started so that the latest one is endTime.
+func shiftStarted(started []json.RawMessage, endTime int64) error {
+	times := make([]int64, len(started))
+	latest := int64(0)
+	for i, raw := range started {
+		if err := json.Unmarshal(raw, &times[i]); err != nil {
+			return fmt.Errorf("error parsing start time %s: %v", raw, err)
+		}
+		if times[i] > latest {
+			latest = times[i]
+		}
+	}
+	for i := range started {
+		started[i] = json.RawMessage(strconv.FormatInt(times[i]+endTime-latest, 10))
+	}
+	return nil
+}
+
+func main() {
+	var opts options
+	input := flag.String("input", "-", "The triage failure_data.json file to sample, or '-' for stdin.")
+	output := flag.String("output", "-", "The file to write the sample to, or '-' for stdout.")
+	flag.IntVar(&opts.clusters, "clusters", 3, "The number of clusters to keep, largest first.")
+	flag.IntVar(&opts.tests, "tests", 2, "The number of tests to keep per cluster.")
+	flag.IntVar(&opts.jobs, "jobs", 2, "The number of jobs to keep per test.")
+	flag.IntVar(&opts.builds, "builds", 3, "The number of failed builds to keep per job, latest first.")
+	flag.IntVar(&opts.maxContig, "max-contig-rows", 50, "The most rows to keep for a job with a contiguous buildnum to row mapping.")
+	flag.Int64Var(&opts.endTime, "end-time", 0, "If set, shift start times so that the latest build starts at this unix time.")
+	flag.Parse()
+
+	var in []byte
+	var err error
+	if *input == "-" {
+		in, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		in, err = ioutil.ReadFile(*input)
+	}
+	if err != nil {
+		glog.Fatalf("Error reading triage data: %v.", err)
+	}
+	sampled, err := sample(in, opts)
+	if err != nil {
+		glog.Fatalf("Error sampling triage data: %v.", err)
+	}
+
+	var out io.Writer = os.Stdout
+	if *output != "-" {
+		file, err := os.Create(*output)
+		if err != nil {
+			glog.Fatalf("Error creating %s: %v.", *output, err)
+		}
+		defer file.Close()
+		out = file
+	}
+	if _, err := fmt.Fprintf(out, "%s\n", sampled); err != nil {
+		glog.Fatalf("Error writing sample: %v.", err)
+	}
+}
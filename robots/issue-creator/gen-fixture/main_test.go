@@ -0,0 +1,131 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+const triageJSON = `{
+	"builds": {
+		"cols": {
+			"started": [100, 101, 102, 103, 104, 105, 106, 107],
+			"result": ["FAILURE", "SUCCESS", "FAILURE", "FAILURE", "SUCCESS", "FAILURE", "FAILURE", "FAILURE"]
+		},
+		"jobs": {
+			"contig": [10, 5, 0],
+			"dict": {"7": 5, "8": 6, "9": 7}
+		},
+		"job_paths": {"contig": "gs://bucket/contig", "dict": "gs://bucket/dict"}
+	},
+	"clustered": [
+		{"id": "a", "key": "ka", "text": "ta", "tests": [
+			{"name": "t1", "jobs": [{"name": "contig", "builds": [10, 12, 13]}, {"name": "dict", "builds": [7, 9]}]},
+			{"name": "t2", "jobs": [{"name": "dict", "builds": [8]}]}
+		]},
+		{"id": "b", "key": "kb", "text": "tb", "tests": [
+			{"name": "t3", "jobs": [{"name": "dict", "builds": [7]}]}
+		]}
+	]
+}`
+
+func contigMapping(t *testing.T, raw json.RawMessage) []int {
+	var contig []int
+	if err := json.Unmarshal(raw, &contig); err != nil {
+		t.Errorf("Expected a contiguous mapping, got %s", raw)
+	}
+	return contig
+}
+
+func TestSample(t *testing.T) {
+	opts := options{clusters: 1, tests: 1, jobs: 2, builds: 2, maxContig: 50, endTime: 1000}
+	out, err := sample([]byte(triageJSON), opts)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var sampled struct {
+		Builds struct {
+			Cols     map[string][]interface{}   `json:"cols"`
+			Jobs     map[string]json.RawMessage `json:"jobs"`
+			JobPaths map[string]string          `json:"job_paths"`
+		} `json:"builds"`
+		Clustered []*cluster `json:"clustered"`
+	}
+	if err := json.Unmarshal(out, &sampled); err != nil {
+		t.Fatalf("Error parsing sample: %v\n%s", err, out)
+	}
+
+	expectedClusters := []*cluster{{ID: "a", Key: "ka", Text: "ta", Tests: []*test{
+		{Name: "t1", Jobs: []*job{{Name: "contig", Builds: []int{12, 13}}, {Name: "dict", Builds: []int{7, 9}}}},
+	}}}
+	if !reflect.DeepEqual(sampled.Clustered, expectedClusters) {
+		out, _ := json.Marshal(sampled.Clustered)
+		t.Errorf("Expected only the latest builds of the first test of the first cluster, got %s", out)
+	}
+	// Jobs keep their encoding: the contiguous job keeps rows for builds 12 and 13, then the
+	// dictionary job gets rows for builds 7 and 9.
+	if contig := contigMapping(t, sampled.Builds.Jobs["contig"]); !reflect.DeepEqual(contig, []int{12, 2, 0}) {
+		t.Errorf("Expected contiguous mapping [12 2 0], got %v", contig)
+	}
+	var dict map[string]int
+	if err := json.Unmarshal(sampled.Builds.Jobs["dict"], &dict); err != nil || !reflect.DeepEqual(dict, map[string]int{"7": 2, "9": 3}) {
+		t.Errorf("Expected dictionary mapping map[7:2 9:3], got %s", sampled.Builds.Jobs["dict"])
+	}
+	expectedCols := map[string][]interface{}{
+		"started": {float64(995), float64(996), float64(998), float64(1000)},
+		"result":  {"FAILURE", "FAILURE", "FAILURE", "FAILURE"},
+	}
+	if !reflect.DeepEqual(sampled.Builds.Cols, expectedCols) {
+		t.Errorf("Expected columns %v, got %v", expectedCols, sampled.Builds.Cols)
+	}
+	if len(sampled.Builds.JobPaths) != 2 {
+		t.Errorf("Expected paths for the 2 kept jobs, got %v", sampled.Builds.JobPaths)
+	}
+}
+
+func TestSampleMaxContig(t *testing.T) {
+	opts := options{clusters: 1, tests: 2, jobs: 1, builds: 3, maxContig: 2}
+	out, err := sample([]byte(triageJSON), opts)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var sampled triageData
+	if err := json.Unmarshal(out, &sampled); err != nil {
+		t.Fatalf("Error parsing sample: %v", err)
+	}
+	if contig := contigMapping(t, sampled.Builds.Jobs["contig"]); !reflect.DeepEqual(contig, []int{12, 2, 0}) {
+		t.Errorf("Expected build 10 to be dropped to keep 2 contiguous rows, got %v", contig)
+	}
+	if len(sampled.Builds.Cols["started"]) != 3 {
+		t.Errorf("Expected 2 contiguous rows and 1 for test t2, got %d", len(sampled.Builds.Cols["started"]))
+	}
+}
+
+func TestSampleErrors(t *testing.T) {
+	for _, in := range []string{
+		`not json`,
+		`{"builds": {"cols": {}, "jobs": {"j": [1, 2]}}, "clustered": []}`,
+		`{"builds": {"cols": {}, "jobs": {}}, "clustered": [{"id": "a", "tests": [{"name": "t", "jobs": [{"name": "j", "builds": [1]}]}]}]}`,
+		`{"builds": {"cols": {"started": [1]}, "jobs": {"j": {"1": 4}}}, "clustered": [{"id": "a", "tests": [{"name": "t", "jobs": [{"name": "j", "builds": [1]}]}]}]}`,
+	} {
+		if _, err := sample([]byte(in), options{clusters: 1, tests: 1, jobs: 1, builds: 1, maxContig: 1}); err == nil {
+			t.Errorf("Expected an error sampling %s", in)
+		}
+	}
+}
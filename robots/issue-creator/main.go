@@ -17,7 +17,16 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"flag"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/golang/glog"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 
 	"k8s.io/test-infra/robots/issue-creator/creator"
 
@@ -28,9 +37,78 @@ func main() {
 	c := &creator.IssueCreator{}
 
 	c.RegisterFlags()
+	metricsAddr := flag.String("metrics-addr", "", "The address to serve Prometheus metrics on at /metrics and health checks on at /healthz and /readyz, e.g. ':9090' (default: no server).")
+	servePprof := flag.Bool("pprof", false, "Serve net/http/pprof profiles at /debug/pprof/ on '--metrics-addr', e.g. to see why a run uses too much memory.")
 	flag.Parse()
+	if c.RunsOnDemand() && *metricsAddr == "" {
+		glog.Fatal("'--run-token-file' requires '--metrics-addr' to serve on-demand runs.")
+	}
+	if *servePprof && *metricsAddr == "" {
+		glog.Fatal("'--pprof' requires '--metrics-addr' to serve profiles.")
+	}
+
+	if *metricsAddr != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", promhttp.Handler())
+		mux.HandleFunc("/healthz", c.HandleHealthz)
+		mux.HandleFunc("/readyz", c.HandleReadyz)
+		mux.HandleFunc("/triage/run", c.RunHandler("triage-filer"))
+		if *servePprof {
+			mux.HandleFunc("/debug/pprof/", pprof.Index)
+			mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+			mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+			mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+			mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+		}
+		go func() {
+			glog.Errorf("Metrics and health server stopped: %v.", http.ListenAndServe(*metricsAddr, mux))
+		}()
+	}
+
+	if c.LintOwners {
+		problems, err := c.LintTestOwners(context.Background(), os.Stdout)
+		if err != nil {
+			glog.Fatalf("Error linting test owners: %v.", err)
+		}
+		if problems > 0 {
+			os.Exit(1)
+		}
+		return
+	}
+
+	if c.ReplayDir != "" {
+		if err := c.Replay(context.Background(), os.Stdout); err != nil {
+			glog.Fatalf("Error replaying snapshots: %v.", err)
+		}
+		return
+	}
+
+	// On shutdown stop fetching and syncing, but let the issue being created finish so that it is
+	// not left without its labels or assignees. A second signal exits immediately.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sig := make(chan os.Signal, 2)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		s := <-sig
+		glog.Warningf("Received %v, shutting down after the current issue. Signal again to exit immediately.", s)
+		cancel()
+		s = <-sig
+		glog.Fatalf("Received %v again, exiting immediately.", s)
+	}()
 
-	c.CreateAndSync()
+	err := c.CreateAndSync(ctx)
+	if err != nil {
+		glog.Errorf("The run failed: %v.", err)
+	}
+	if c.RunsOnDemand() && ctx.Err() == nil {
+		glog.Info("Serving on-demand runs until shutdown.")
+		<-ctx.Done()
+	}
+	glog.Flush()
+	if err != nil && c.RunOnce {
+		os.Exit(1)
+	}
 	// Loop through issues sources and get Issues
 	// For each source:
 	// sync issues
@@ -0,0 +1,159 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sources
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	bigquery "google.golang.org/api/bigquery/v2"
+)
+
+// maxInsertRows is the number of rows sent in each streaming insert, below BigQuery's
+// recommended limit of 500.
+const maxInsertRows = 500
+
+// bigQueryTableRegex matches tables named like the bq tool does: project:dataset.table.
+var bigQueryTableRegex = regexp.MustCompile(`^([^:.]+):([A-Za-z0-9_]+)\.([A-Za-z0-9_]+)$`)
+
+// ClusterRow is the record exported for each cluster after every run. The fields are named after
+// the columns of the BigQuery table.
+type ClusterRow struct {
+	ClusterID   string
+	WindowStart time.Time
+	WindowEnd   time.Time
+	WindowDays  int
+	Builds      int
+	Jobs        int
+	Tests       int
+	Owners      []string
+	SIGs        []string
+	// Issue is the number of the open issue tracking the cluster when the row was built, or 0.
+	// Issues filed for the cluster in the same run appear in the rows of later runs.
+	Issue int
+}
+
+// RowExporter stores cluster rows for long term analysis.
+type RowExporter interface {
+	Export(ctx context.Context, rows []*ClusterRow) error
+}
+
+// bigQueryExporter streams rows into a BigQuery table with Application Default Credentials.
+type bigQueryExporter struct {
+	project, dataset, table string
+}
+
+// newBigQueryExporter creates an exporter for a table named like project:dataset.table.
+func newBigQueryExporter(table string) (*bigQueryExporter, error) {
+	match := bigQueryTableRegex.FindStringSubmatch(table)
+	if match == nil {
+		return nil, fmt.Errorf("invalid BigQuery table '%s', expected project:dataset.table", table)
+	}
+	return &bigQueryExporter{project: match[1], dataset: match[2], table: match[3]}, nil
+}
+
+// Export implements RowExporter.
+func (e *bigQueryExporter) Export(ctx context.Context, rows []*ClusterRow) error {
+	service, err := bigquery.NewService(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to create a BigQuery client: %v", err)
+	}
+	for start := 0; start < len(rows); start += maxInsertRows {
+		end := start + maxInsertRows
+		if end > len(rows) {
+			end = len(rows)
+		}
+		req := &bigquery.TableDataInsertAllRequest{}
+		for _, row := range rows[start:end] {
+			req.Rows = append(req.Rows, &bigquery.TableDataInsertAllRequestRows{
+				// The insert ID lets BigQuery drop rows that are sent twice by a retry.
+				InsertId: fmt.Sprintf("%s-%d", row.ClusterID, row.WindowEnd.Unix()),
+				Json:     row.bigQueryJSON(),
+			})
+		}
+		resp, err := service.Tabledata.InsertAll(e.project, e.dataset, e.table, req).Context(ctx).Do()
+		if err != nil {
+			return fmt.Errorf("failed to insert rows into %s:%s.%s: %v", e.project, e.dataset, e.table, err)
+		}
+		if len(resp.InsertErrors) > 0 {
+			var msgs []string
+			for _, insertErr := range resp.InsertErrors {
+				for _, e := range insertErr.Errors {
+					msgs = append(msgs, fmt.Sprintf("row %d: %s", int64(start)+insertErr.Index, e.Message))
+				}
+			}
+			return fmt.Errorf("BigQuery rejected %d rows: %s", len(resp.InsertErrors), strings.Join(msgs, "; "))
+		}
+	}
+	return nil
+}
+
+// bigQueryJSON returns the row keyed by column name.
+func (r *ClusterRow) bigQueryJSON() map[string]bigquery.JsonValue {
+	return map[string]bigquery.JsonValue{
+		"cluster_id":   r.ClusterID,
+		"window_start": r.WindowStart.UTC().Format(time.RFC3339),
+		"window_end":   r.WindowEnd.UTC().Format(time.RFC3339),
+		"window_days":  r.WindowDays,
+		"builds":       r.Builds,
+		"jobs":         r.Jobs,
+		"tests":        r.Tests,
+		"owners":       r.Owners,
+		"sigs":         r.SIGs,
+		"issue":        r.Issue,
+	}
+}
+
+// clusterRows builds the exported row of every cluster. issues maps cluster IDs to the open issues
+// that track them.
+func (f *TriageFiler) clusterRows(clusters []*Cluster, issues map[string]int) []*ClusterRow {
+	end := time.Unix(f.latestStart, 0)
+	start := end.AddDate(0, 0, -f.windowDays)
+	rows := make([]*ClusterRow, 0, len(clusters))
+	for _, clust := range clusters {
+		testNames := make([]string, len(clust.Tests))
+		for i, test := range clust.Tests {
+			testNames[i] = test.Name
+		}
+		var owners []string
+		for owner := range f.creator.TestsOwners(testNames) {
+			owners = append(owners, owner)
+		}
+		sort.Strings(owners)
+		issue := issues[clust.Identifier]
+		if clust.trackedBy != 0 {
+			issue = clust.trackedBy
+		}
+		rows = append(rows, &ClusterRow{
+			ClusterID:   clust.Identifier,
+			WindowStart: start,
+			WindowEnd:   end,
+			WindowDays:  f.windowDays,
+			Builds:      clust.totalBuilds,
+			Jobs:        clust.totalJobs,
+			Tests:       clust.totalTests,
+			Owners:      owners,
+			SIGs:        clust.sigs(),
+			Issue:       issue,
+		})
+	}
+	return rows
+}
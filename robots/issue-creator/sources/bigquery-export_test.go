@@ -0,0 +1,101 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sources
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/github"
+	"k8s.io/test-infra/robots/issue-creator/testowner"
+)
+
+type fakeExporter struct {
+	rows []*ClusterRow
+}
+
+func (f *fakeExporter) Export(ctx context.Context, rows []*ClusterRow) error {
+	f.rows = append(f.rows, rows...)
+	return nil
+}
+
+func TestNewBigQueryExporter(t *testing.T) {
+	tests := []struct {
+		table    string
+		expected *bigQueryExporter
+	}{
+		{table: "k8s-gubernator:triage.clusters", expected: &bigQueryExporter{project: "k8s-gubernator", dataset: "triage", table: "clusters"}},
+		{table: "example.com:project:flakes.cluster_stats", expected: nil},
+		{table: "triage.clusters", expected: nil},
+		{table: "project:triage", expected: nil},
+		{table: "project:triage.clusters.extra", expected: nil},
+	}
+	for _, test := range tests {
+		exporter, err := newBigQueryExporter(test.table)
+		if test.expected == nil {
+			if err == nil {
+				t.Errorf("%s: expected an error, got %+v", test.table, exporter)
+			}
+		} else if err != nil || !reflect.DeepEqual(exporter, test.expected) {
+			t.Errorf("%s: expected %+v, got %+v and error %v", test.table, test.expected, exporter, err)
+		}
+	}
+}
+
+func TestTFClusterRows(t *testing.T) {
+	f := NewTestTriageFiler()
+	f.creator.MaxSIGCount = 3
+	f.creator.MaxAssignees = 3
+	f.creator.Collaborators = []string{"cjwagner", "spxtr"}
+	var err error
+	if f.creator.Owners, err = testowner.NewOwnerListFromCsv(bytes.NewReader(sampleOwnerCSV)); err != nil {
+		t.Fatalf("Failed to create a new OwnersList.  errmsg: %v", err)
+	}
+	f.dataURL = "https://example.com/failure_data.json"
+	f.Fetcher = &fakeFetcher{data: json1issue2job2test}
+	exporter := &fakeExporter{}
+	f.Exporter = exporter
+	f.tracker = &fakeTracker{
+		own: []*github.Issue{makeIssue(7, "### Failure cluster [key_hash](url)", "kind/flake")},
+	}
+
+	if _, err := f.Issues(context.Background(), f.creator); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(exporter.rows) != 1 {
+		t.Fatalf("Expected 1 exported row, got %d", len(exporter.rows))
+	}
+	end := time.Unix(f.latestStart, 0)
+	expected := &ClusterRow{
+		ClusterID:   "key_hash",
+		WindowStart: end.AddDate(0, 0, -5),
+		WindowEnd:   end,
+		WindowDays:  5,
+		Builds:      4,
+		Jobs:        2,
+		Tests:       2,
+		Owners:      []string{"cjwagner", "spxtr"},
+		SIGs:        []string{"sigarea"},
+		Issue:       7,
+	}
+	if row := exporter.rows[0]; !reflect.DeepEqual(row, expected) {
+		t.Errorf("Expected row %+v, got %+v", expected, row)
+	}
+}
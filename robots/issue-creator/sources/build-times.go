@@ -0,0 +1,102 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sources
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// minPlausibleStart is 1 Jan 1990, long before any of the jobs ran. Earlier start times, like the
+	// 10000000 that is written for builds whose start is unknown, are sentinels and not real times.
+	minPlausibleStart = 631152000
+	// maxClockSkew is how far past the current time a build may start before its time is implausible.
+	maxClockSkew = time.Hour
+	// maxImplausibleExamples is the number of builds with implausible start times that are logged.
+	maxImplausibleExamples = 10
+)
+
+// implausibleGauge is the number of failed builds with implausible start times in the last triage data.
+var implausibleGauge = prometheus.NewGauge(prometheus.GaugeOpts{
+	Name: "issue_creator_triage_implausible_builds",
+	Help: "Failed builds in the last triage data whose start times are implausible and were left out of the window.",
+})
+
+func init() {
+	prometheus.MustRegister(implausibleGauge)
+}
+
+// latestPlausibleStart returns the latest start time that a build can plausibly have now.
+func latestPlausibleStart() int64 {
+	return time.Now().Add(maxClockSkew).Unix()
+}
+
+// plausibleStart returns true iff start is a real start time, given the latest plausible one.
+func plausibleStart(start, latest int64) bool {
+	return start >= minPlausibleStart && start <= latest
+}
+
+// implausibleBuilds holds the start times of the failed builds whose start times are implausible,
+// by job and build number. They are left out of the window and reported instead.
+type implausibleBuilds map[string]map[int]int64
+
+func (b implausibleBuilds) add(job string, build int, start int64) {
+	if b[job] == nil {
+		b[job] = map[int]int64{}
+	}
+	b[job][build] = start
+}
+
+// count returns the number of builds.
+func (b implausibleBuilds) count() int {
+	n := 0
+	for _, builds := range b {
+		n += len(builds)
+	}
+	return n
+}
+
+// String describes the builds for the log, listing the first few by job and build number.
+func (b implausibleBuilds) String() string {
+	var examples []string
+	jobs := make([]string, 0, len(b))
+	for job := range b {
+		jobs = append(jobs, job)
+	}
+	sort.Strings(jobs)
+	for _, job := range jobs {
+		builds := make([]int, 0, len(b[job]))
+		for build := range b[job] {
+			builds = append(builds, build)
+		}
+		sort.Ints(builds)
+		for _, build := range builds {
+			examples = append(examples, fmt.Sprintf("%s build %d started at %d", job, build, b[job][build]))
+		}
+	}
+	more := ""
+	if len(examples) > maxImplausibleExamples {
+		more = fmt.Sprintf("; and %d more", len(examples)-maxImplausibleExamples)
+		examples = examples[:maxImplausibleExamples]
+	}
+	return fmt.Sprintf("%d failed builds have implausible start times: %s%s", b.count(), strings.Join(examples, "; "), more)
+}
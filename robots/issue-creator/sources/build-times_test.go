@@ -0,0 +1,88 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sources
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTFImplausibleBuilds(t *testing.T) {
+	// Build 45 of jobname1 is in row 4, which has the sentinel start time 10000000.
+	data := bytes.Replace(json1issue2job2test, []byte(`"builds": [42, 43, 52],`), []byte(`"builds": [42, 43, 45, 52],`), 1)
+	if bytes.Equal(data, json1issue2job2test) {
+		t.Fatal("Failed to add build 45 to the test data.")
+	}
+	f := NewTestTriageFiler()
+	clusters, err := f.loadClusters(data)
+	if err != nil {
+		t.Fatalf("Error parsing triage data: %v", err)
+	}
+	if len(clusters) != 1 || clusters[0].totalBuilds != 4 {
+		t.Fatalf("Expected build 45 to be left out of the 4 builds in the window, got %d clusters", len(clusters))
+	}
+	if f.implausible.count() != 1 || f.implausible["jobname1"][45] != 10000000 {
+		t.Errorf("Expected build 45 of jobname1 to be reported, got %v", f.implausible)
+	}
+}
+
+func TestTFWindowIgnoresImplausibleStarts(t *testing.T) {
+	f := NewTestTriageFiler()
+	future := time.Now().AddDate(1, 0, 0).Unix()
+	latest, _, _ := f.window([]int64{10000000, buildTimes[42], future, buildTimes[144], -1}, 5)
+	if latest != buildTimes[144] {
+		t.Errorf("Expected the window to end at the latest plausible build %d, got %d", buildTimes[144], latest)
+	}
+}
+
+func TestPlausibleStart(t *testing.T) {
+	maxStart := latestBuildTime + 60
+	tests := []struct {
+		start     int64
+		plausible bool
+	}{
+		{start: 0},
+		{start: 10000000},
+		{start: minPlausibleStart - 1},
+		{start: minPlausibleStart, plausible: true},
+		{start: latestBuildTime, plausible: true},
+		{start: maxStart, plausible: true},
+		{start: maxStart + 1},
+	}
+	for _, test := range tests {
+		if plausible := plausibleStart(test.start, maxStart); plausible != test.plausible {
+			t.Errorf("Expected plausibleStart(%d) to be %t", test.start, test.plausible)
+		}
+	}
+}
+
+func TestImplausibleBuildsString(t *testing.T) {
+	builds := implausibleBuilds{}
+	for i := 0; i < maxImplausibleExamples+2; i++ {
+		builds.add("job-b", i, 10000000)
+	}
+	builds.add("job-a", 7, 0)
+	msg := builds.String()
+	if !strings.HasPrefix(msg, "13 failed builds have implausible start times: job-a build 7 started at 0; job-b build 0 started at 10000000; ") {
+		t.Errorf("Unexpected description %q", msg)
+	}
+	if !strings.HasSuffix(msg, "; and 3 more") {
+		t.Errorf("Expected only %d builds to be listed, got %q", maxImplausibleExamples, msg)
+	}
+}
@@ -0,0 +1,129 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sources
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	githubapi "github.com/google/go-github/github"
+)
+
+// crossLinkMarker starts the marker of comments that link another bot's issue to a cluster.
+const crossLinkMarker = "<!-- triage-cross-link: "
+
+// Rules for '--triage-dedupe-match': what an issue filed by another bot must mention to cover a
+// cluster.
+const (
+	matchJobAndTest = "job+test"
+	matchTest       = "test"
+	matchJob        = "job"
+)
+
+// otherBots returns the set of the lowercased logins in dedupeAuthors.
+func (f *TriageFiler) otherBots() map[string]bool {
+	authors := map[string]bool{}
+	for _, author := range strings.Split(f.dedupeAuthors, ",") {
+		if author = strings.TrimSpace(author); author != "" {
+			authors[strings.ToLower(author)] = true
+		}
+	}
+	return authors
+}
+
+// dedupeAgainstBots finds the open issues with dedupeLabel filed by the dedupeAuthors bots, such as
+// failing test issues from CI signal tooling, that cover clusters according to dedupeMatch. Those
+// clusters are not filed, and the bot's issue gets a comment linking it to the cluster instead.
+func (f *TriageFiler) dedupeAgainstBots(ctx context.Context, tracker issueTracker, clusters []*Cluster) error {
+	authors := f.otherBots()
+	issues, err := tracker.OpenIssuesByOthers(ctx, f.dedupeLabel)
+	if err != nil {
+		return err
+	}
+	var errs []string
+	for _, issue := range issues {
+		if issue.User == nil || issue.User.Login == nil || !authors[strings.ToLower(*issue.User.Login)] {
+			continue
+		}
+		for _, clust := range clusters {
+			if clust.trackedBy != 0 || !f.botIssueCovers(issue, clust) {
+				continue
+			}
+			clust.trackedBy = *issue.Number
+			f.log(clust.ID()).WithNumber(*issue.Number).Infof("Covered by an issue from %s, linking instead of filing it.", *issue.User.Login)
+			if err := linkCluster(ctx, tracker, *issue.Number, clust); err != nil {
+				errs = append(errs, err.Error())
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to link %d issues: %s", len(errs), strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// botIssueCovers returns true iff the title or body of issue mentions the cluster's jobs and tests
+// as required by dedupeMatch.
+func (f *TriageFiler) botIssueCovers(issue *githubapi.Issue, clust *Cluster) bool {
+	var text string
+	if issue.Title != nil {
+		text = *issue.Title
+	}
+	if issue.Body != nil {
+		text += "\n" + *issue.Body
+	}
+	mentionsJob := false
+	for job := range clust.jobs {
+		if strings.Contains(text, job) {
+			mentionsJob = true
+			break
+		}
+	}
+	mentionsTest := false
+	for _, test := range clust.Tests {
+		if strings.Contains(text, test.Name) {
+			mentionsTest = true
+			break
+		}
+	}
+	switch f.dedupeMatch {
+	case matchJob:
+		return mentionsJob
+	case matchTest:
+		return mentionsTest
+	default:
+		return mentionsJob && mentionsTest
+	}
+}
+
+// linkCluster comments on the issue with a link to the cluster, unless it already has.
+func linkCluster(ctx context.Context, tracker issueTracker, number int, clust *Cluster) error {
+	marker := crossLinkMarker + clust.Identifier + " -->"
+	comments, err := tracker.IssueComments(ctx, number)
+	if err != nil {
+		return err
+	}
+	for _, comment := range comments {
+		if comment.Body != nil && strings.Contains(*comment.Body, marker) {
+			return nil
+		}
+	}
+	return tracker.CommentOnIssue(ctx, number, fmt.Sprintf(
+		"%s\nThis issue covers failure cluster [%s](%s#%s), so no separate flake issue will be filed for it. The cluster has failed %d builds in %d jobs over the last %d days.\n",
+		marker, clust.Identifier, triageURL, clust.Identifier, clust.totalBuilds, clust.totalJobs, clust.filer.windowDays))
+}
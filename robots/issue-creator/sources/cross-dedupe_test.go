@@ -0,0 +1,110 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sources
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/github"
+)
+
+func makeBotIssue(number int, author, title, body string) *github.Issue {
+	issue := makeIssue(number, body, "kind/failing-test")
+	issue.Title = &title
+	issue.User = &github.User{Login: &author}
+	return issue
+}
+
+func TestTFDedupeAgainstBots(t *testing.T) {
+	tcs := []struct {
+		name    string
+		match   string
+		issue   *github.Issue
+		tracked int
+	}{
+		{
+			name:    "job and test",
+			match:   matchJobAndTest,
+			issue:   makeBotIssue(7, "ci-signal-bot", "[Failing Test] jobname1", "testname2 fails every run."),
+			tracked: 7,
+		},
+		{
+			name:  "job only",
+			match: matchJobAndTest,
+			issue: makeBotIssue(7, "ci-signal-bot", "[Failing Test] jobname1", "Some other test fails."),
+		},
+		{
+			name:    "job only is enough",
+			match:   matchJob,
+			issue:   makeBotIssue(7, "CI-Signal-Bot", "[Failing Test] jobname1", "Some other test fails."),
+			tracked: 7,
+		},
+		{
+			name:    "test only is enough",
+			match:   matchTest,
+			issue:   makeBotIssue(7, "ci-signal-bot", "testname1 is failing", ""),
+			tracked: 7,
+		},
+		{
+			name:  "PR jobs don't count",
+			match: matchJob,
+			issue: makeBotIssue(7, "ci-signal-bot", "[Failing Test] pr:jobname3", ""),
+		},
+		{
+			name:  "other authors are ignored",
+			match: matchJobAndTest,
+			issue: makeBotIssue(7, "someone", "[Failing Test] jobname1", "testname2 fails every run."),
+		},
+	}
+	for _, tc := range tcs {
+		f := NewTestTriageFiler()
+		f.dedupeAuthors = "ci-signal-bot, other-bot"
+		f.dedupeMatch = tc.match
+		clusters, err := f.loadClusters(json1issue2job2test)
+		if err != nil {
+			t.Fatalf("Error parsing triage data: %v\n", err)
+		}
+		tracker := &fakeTracker{others: []*github.Issue{tc.issue}}
+		if err := f.dedupeAgainstBots(context.Background(), tracker, clusters); err != nil {
+			t.Fatalf("%s: unexpected error: %v", tc.name, err)
+		}
+		if clusters[0].trackedBy != tc.tracked {
+			t.Errorf("%s: expected the cluster to be tracked by %d, got %d", tc.name, tc.tracked, clusters[0].trackedBy)
+		}
+		comments := tracker.comments[7]
+		if tc.tracked == 0 {
+			if len(comments) != 0 {
+				t.Errorf("%s: expected no link comment, got %d", tc.name, len(comments))
+			}
+			continue
+		}
+		if len(comments) != 1 || !strings.Contains(*comments[0].Body, "failure cluster [key_hash]") {
+			t.Errorf("%s: expected a comment linking the cluster, got %v", tc.name, comments)
+		}
+
+		// The cluster is linked only once.
+		clusters[0].trackedBy = 0
+		if err := f.dedupeAgainstBots(context.Background(), tracker, clusters); err != nil {
+			t.Fatalf("%s: unexpected error: %v", tc.name, err)
+		}
+		if len(tracker.comments[7]) != 1 {
+			t.Errorf("%s: expected the cluster to be linked once, got %d comments", tc.name, len(tracker.comments[7]))
+		}
+	}
+}
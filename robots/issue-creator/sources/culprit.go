@@ -0,0 +1,215 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sources
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	// culpritMarker identifies comments with culprit hints, so that each issue gets only one.
+	culpritMarker = "<!-- triage-culprit-hints -->"
+	// culpritGreenRuns is the number of builds that must pass right before a cluster's first
+	// failure in a job for the cluster to count as starting abruptly.
+	culpritGreenRuns = 3
+	// startedFile is the file in each build's directory that records the revisions it tested.
+	startedFile = "started.json"
+)
+
+// culpritRange is the pair of builds of a job between which a cluster started failing.
+type culpritRange struct {
+	job       string
+	lastGreen int
+	firstRed  int
+	// greenRuns is the number of builds that passed in a row up to lastGreen.
+	greenRuns int
+}
+
+// startedJSON is the part of a build's started.json that records what was tested.
+type startedJSON struct {
+	// Repos maps org/repo to refs like "master:abc123,456:def789".
+	Repos map[string]string `json:"repos"`
+	// RepoVersion is the older version string, like "v1.8.0-alpha.1.123+abc123".
+	RepoVersion string `json:"repo-version"`
+}
+
+// revision returns the tested repo, if known, and the revision of its base ref.
+func (s *startedJSON) revision() (string, string) {
+	if len(s.Repos) == 1 {
+		for repo, refs := range s.Repos {
+			base := strings.SplitN(refs, ",", 2)[0]
+			if i := strings.Index(base, ":"); i >= 0 {
+				return repo, base[i+1:]
+			}
+		}
+	}
+	if i := strings.LastIndex(s.RepoVersion, "+"); i >= 0 {
+		return "", s.RepoVersion[i+1:]
+	}
+	return "", s.RepoVersion
+}
+
+// culpritRange finds where the cluster started failing in the job it failed the most builds of.
+// It returns nil unless the builds of that job right before the cluster's first failure passed.
+func (c *Cluster) culpritRange() *culpritRange {
+	results := c.filer.data.Builds.Cols.Result
+	started := c.filer.data.Builds.Cols.Started
+	if results == nil {
+		return nil
+	}
+	job := ""
+	for name, builds := range c.jobs {
+		if job == "" || len(builds) > len(c.jobs[job]) || (len(builds) == len(c.jobs[job]) && name < job) {
+			job = name
+		}
+	}
+	rowMap, ok := c.filer.data.Builds.Jobs[job]
+	if !ok {
+		return nil
+	}
+	rows := rowMap.buildRows()
+	firstRed := -1
+	for _, build := range c.jobs[job] {
+		if firstRed == -1 || started[rows[build]] < started[rows[firstRed]] {
+			firstRed = build
+		}
+	}
+
+	var before []int
+	for build, row := range rows {
+		if row < len(started) && row < len(results) && started[row] < started[rows[firstRed]] {
+			before = append(before, build)
+		}
+	}
+	// Walk back from the first failure, latest build first.
+	sort.Slice(before, func(i, j int) bool { return started[rows[before[i]]] > started[rows[before[j]]] })
+	greenRuns := 0
+	for _, build := range before {
+		if results[rows[build]] != "SUCCESS" {
+			break
+		}
+		greenRuns++
+	}
+	if greenRuns < culpritGreenRuns {
+		return nil
+	}
+	return &culpritRange{job: job, lastGreen: before[0], firstRed: firstRed, greenRuns: greenRuns}
+}
+
+// buildRevision reads the repo and revision that a build tested from its started.json.
+func (f *TriageFiler) buildRevision(ctx context.Context, job string, build int) (string, string, error) {
+	path, ok := f.data.Builds.JobPaths[job]
+	if !ok {
+		return "", "", fmt.Errorf("no path for job '%s'", job)
+	}
+	url := fmt.Sprintf("%s/%d/%s", strings.TrimSuffix(path, "/"), build, startedFile)
+	r, _, err := f.Fetcher.Open(ctx, url)
+	if err != nil {
+		return "", "", err
+	}
+	defer r.Close()
+	var started startedJSON
+	if err := json.NewDecoder(r).Decode(&started); err != nil {
+		return "", "", fmt.Errorf("failed to decode '%s': %v", url, err)
+	}
+	repo, revision := started.revision()
+	if revision == "" {
+		return "", "", fmt.Errorf("'%s' does not record a revision", url)
+	}
+	return repo, revision, nil
+}
+
+// postCulpritHints comments on the open issue of every cluster that started failing abruptly with
+// the range of revisions that likely contains the cause. Each issue is only commented on once.
+func (f *TriageFiler) postCulpritHints(ctx context.Context, tracker issueTracker, clusters []*Cluster) error {
+	issues := clusterIssues(f.updatableIssues(tracker))
+	var errs []string
+	for _, clust := range clusters {
+		number := issues[clust.Identifier]
+		if number == 0 {
+			continue
+		}
+		culprit := clust.culpritRange()
+		if culprit == nil {
+			continue
+		}
+		comments, err := tracker.IssueComments(ctx, number)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		commented := false
+		for _, comment := range comments {
+			if comment.Body != nil && strings.Contains(*comment.Body, culpritMarker) {
+				commented = true
+				break
+			}
+		}
+		if commented {
+			continue
+		}
+		body, err := f.culpritComment(ctx, clust, culprit)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("cluster %s: %v", clust.Identifier, err))
+			continue
+		}
+		f.log(clust.ID()).WithNumber(number).WithJob(culprit.job, 0).Infof("Started failing abruptly in the job, commenting on the issue.")
+		if err := tracker.CommentOnIssue(ctx, number, body); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to post %d culprit hints: %s", len(errs), strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// culpritComment renders the comment for a cluster that started failing abruptly.
+func (f *TriageFiler) culpritComment(ctx context.Context, clust *Cluster, culprit *culpritRange) (string, error) {
+	greenRepo, green, err := f.buildRevision(ctx, culprit.job, culprit.lastGreen)
+	if err != nil {
+		return "", err
+	}
+	redRepo, red, err := f.buildRevision(ctx, culprit.job, culprit.firstRed)
+	if err != nil {
+		return "", err
+	}
+
+	path := strings.TrimPrefix(f.data.Builds.JobPaths[culprit.job], "gs://")
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%s\n", culpritMarker)
+	fmt.Fprintf(&buf, "Failure cluster [%s](%s#%s) started abruptly in `%s`. ", clust.Identifier, triageURL, clust.Identifier, culprit.job)
+	fmt.Fprintf(&buf, "The %d builds up to [%d](https://prow.k8s.io/view/gcs/%s/%d) passed, and [%d](https://prow.k8s.io/view/gcs/%s/%d) is the first failure in this cluster.\n",
+		culprit.greenRuns, culprit.lastGreen, path, culprit.lastGreen, culprit.firstRed, path, culprit.firstRed)
+	if green == red {
+		fmt.Fprintf(&buf, "\nBoth builds tested revision %s, so the cause is likely outside the code under test.\n", red)
+		return buf.String(), nil
+	}
+	if greenRepo != "" && greenRepo == redRepo {
+		fmt.Fprintf(&buf, "\nThe culprit is likely in [%s...%s](https://github.com/%s/compare/%s...%s).\n", green, red, greenRepo, green, red)
+	} else {
+		fmt.Fprintf(&buf, "\nThe culprit is likely between revisions %s and %s.\n", green, red)
+	}
+	fmt.Fprint(&buf, "To bisect, with a command that reproduces the failure:\n```\n")
+	fmt.Fprintf(&buf, "git bisect start %s %s\ngit bisect run <reproducer>\n```\n", red, green)
+	return buf.String(), nil
+}
@@ -0,0 +1,144 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sources
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/github"
+)
+
+// mapFetcher serves the data for each URL from a map.
+type mapFetcher map[string]string
+
+func (m mapFetcher) Open(ctx context.Context, url string) (io.ReadCloser, int64, error) {
+	data, ok := m[url]
+	if !ok {
+		return nil, 0, &NotFoundError{Err: fmt.Errorf("no data for '%s'", url)}
+	}
+	return ioutil.NopCloser(strings.NewReader(data)), int64(len(data)), nil
+}
+
+// culpritTriageData returns triage data for builds 10 to 15 of ci-job, one hour apart, with the
+// given results. The cluster fails builds 14 and 15.
+func culpritTriageData(results ...string) []byte {
+	return []byte(fmt.Sprintf(`{
+		"builds": {
+			"cols": {
+				"started": [1000000000, 1000003600, 1000007200, 1000010800, 1000014400, 1000018000],
+				"result": ["%s"]
+			},
+			"jobs": {"ci-job": [10, 6, 0]},
+			"job_paths": {"ci-job": "gs://bucket/logs/ci-job"}
+		},
+		"clustered": [{
+			"id": "culprit_hash",
+			"key": "key_text",
+			"text": "error text",
+			"tests": [{"name": "test1", "jobs": [{"name": "ci-job", "builds": [14, 15]}]}]
+		}]
+	}`, strings.Join(results, `", "`)))
+}
+
+func TestTFCulpritRange(t *testing.T) {
+	tests := []struct {
+		name     string
+		results  []string
+		expected *culpritRange
+	}{
+		{
+			name:     "abrupt",
+			results:  []string{"FAILURE", "SUCCESS", "SUCCESS", "SUCCESS", "FAILURE", "FAILURE"},
+			expected: &culpritRange{job: "ci-job", lastGreen: 13, firstRed: 14, greenRuns: 3},
+		},
+		{
+			name:    "too few passing builds",
+			results: []string{"SUCCESS", "FAILURE", "SUCCESS", "SUCCESS", "FAILURE", "FAILURE"},
+		},
+	}
+	for _, test := range tests {
+		f := NewTestTriageFiler()
+		clusters, err := f.loadClusters(culpritTriageData(test.results...))
+		if err != nil {
+			t.Fatalf("%s: error parsing triage data: %v", test.name, err)
+		}
+		if culprit := clusters[0].culpritRange(); !reflect.DeepEqual(culprit, test.expected) {
+			t.Errorf("%s: expected %+v, got %+v", test.name, test.expected, culprit)
+		}
+	}
+}
+
+func TestTFPostCulpritHints(t *testing.T) {
+	f := NewTestTriageFiler()
+	clusters, err := f.loadClusters(culpritTriageData("FAILURE", "SUCCESS", "SUCCESS", "SUCCESS", "FAILURE", "FAILURE"))
+	if err != nil {
+		t.Fatalf("Error parsing triage data: %v", err)
+	}
+	f.Fetcher = mapFetcher{
+		"gs://bucket/logs/ci-job/13/started.json": `{"repos": {"kubernetes/kubernetes": "master:aaa111"}}`,
+		"gs://bucket/logs/ci-job/14/started.json": `{"repos": {"kubernetes/kubernetes": "master:bbb222"}}`,
+	}
+	tracker := &fakeTracker{
+		own: []*github.Issue{makeIssue(5, "### Failure cluster [culprit_hash](url)", "kind/flake")},
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := f.postCulpritHints(context.Background(), tracker, clusters); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	if len(tracker.comments[5]) != 1 {
+		t.Fatalf("Expected a single comment on #5, got %d", len(tracker.comments[5]))
+	}
+	body := *tracker.comments[5][0].Body
+	for _, expected := range []string{
+		culpritMarker,
+		"started abruptly in `ci-job`",
+		"The 3 builds up to [13](https://prow.k8s.io/view/gcs/bucket/logs/ci-job/13) passed",
+		"https://github.com/kubernetes/kubernetes/compare/aaa111...bbb222",
+		"git bisect start bbb222 aaa111",
+	} {
+		if !strings.Contains(body, expected) {
+			t.Errorf("Expected the comment to contain %q, got:\n%s", expected, body)
+		}
+	}
+}
+
+func TestStartedJSONRevision(t *testing.T) {
+	tests := []struct {
+		started  startedJSON
+		repo     string
+		revision string
+	}{
+		{started: startedJSON{Repos: map[string]string{"org/repo": "master:abc123"}}, repo: "org/repo", revision: "abc123"},
+		{started: startedJSON{Repos: map[string]string{"org/repo": "master:abc123,42:def456"}}, repo: "org/repo", revision: "abc123"},
+		{started: startedJSON{RepoVersion: "v1.8.0-alpha.1.123+abc123"}, revision: "abc123"},
+		{started: startedJSON{RepoVersion: "abc123"}, revision: "abc123"},
+		{started: startedJSON{}},
+	}
+	for _, test := range tests {
+		if repo, revision := test.started.revision(); repo != test.repo || revision != test.revision {
+			t.Errorf("Expected %+v to have repo %q and revision %q, got %q and %q", test.started, test.repo, test.revision, repo, revision)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sources
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	githubapi "github.com/google/go-github/github"
+)
+
+const (
+	// helpWantedLabel is added to issues that are escalated.
+	helpWantedLabel = "help wanted"
+	// escalationMarker identifies escalation comments, so that each issue is escalated once.
+	escalationMarker = "<!-- triage-escalation -->"
+)
+
+// priorityLabels are the priorities from lowest to highest. Escalating an issue moves it one step
+// up, and an issue without a priority is treated as priority/backlog.
+var priorityLabels = []string{
+	"priority/awaiting-more-evidence",
+	"priority/backlog",
+	"priority/important-longterm",
+	"priority/important-soon",
+	"priority/critical-urgent",
+}
+
+// escalateIssues finds the open triage issues filed more than escalateDays ago that no human has
+// commented on, mentions the teams of their SIGs, and then adds help wanted and raises their
+// priority. An issue is escalated once, which its escalation comment records. The comment is
+// posted first, so if either step fails the issue is escalated again on the next run, and the
+// labels are only changed if the issue lacks help wanted.
+func (f *TriageFiler) escalateIssues(ctx context.Context, tracker issueTracker, now time.Time) error {
+	cutoff := now.AddDate(0, 0, -f.escalateDays)
+	bots := f.otherBots()
+	var errs []string
+	for _, issue := range f.updatableIssues(tracker) {
+		if issue.Body == nil || !clusterHeaderRegex.MatchString(*issue.Body) {
+			continue
+		}
+		if issue.CreatedAt == nil || issue.CreatedAt.After(cutoff) {
+			continue
+		}
+		labels := issueLabels(issue)
+		comments, err := tracker.IssueComments(ctx, *issue.Number)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		escalated := hasEscalationComment(comments)
+		if (escalated && hasLabel(labels, helpWantedLabel)) || hasHumanComment(issue, comments, bots) {
+			continue
+		}
+
+		log := f.log("").WithNumber(*issue.Number)
+		if !escalated {
+			log.Infof("Escalating the issue, which has had no human response in %d days.", f.escalateDays)
+			if err := tracker.CommentOnIssue(ctx, *issue.Number, f.escalationComment(labels)); err != nil {
+				errs = append(errs, err.Error())
+				continue
+			}
+		}
+		if hasLabel(labels, helpWantedLabel) {
+			continue
+		}
+		log.Infof("Marking the escalated issue %q and raising its priority.", helpWantedLabel)
+		labels = append(raisePriority(labels), helpWantedLabel)
+		if err := tracker.UpdateIssue(ctx, *issue.Number, *issue.Body, labels); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to escalate %d issues: %s", len(errs), strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// hasEscalationComment returns true iff one of the comments is an escalation comment.
+func hasEscalationComment(comments []*githubapi.IssueComment) bool {
+	for _, comment := range comments {
+		if comment.Body != nil && strings.Contains(*comment.Body, escalationMarker) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasHumanComment returns true iff someone other than the author of the issue, which is this bot,
+// and the other bots commented on it. bots holds lowercased logins. Bots that are not listed, e.g.
+// in '--triage-dedupe-authors', count as humans.
+func hasHumanComment(issue *githubapi.Issue, comments []*githubapi.IssueComment, bots map[string]bool) bool {
+	author := ""
+	if issue.User != nil && issue.User.Login != nil {
+		author = *issue.User.Login
+	}
+	for _, comment := range comments {
+		if comment.User == nil || comment.User.Login == nil {
+			continue
+		}
+		if !strings.EqualFold(*comment.User.Login, author) && !bots[strings.ToLower(*comment.User.Login)] {
+			return true
+		}
+	}
+	return false
+}
+
+// raisePriority replaces the priority label with the next higher one.
+func raisePriority(labels []string) []string {
+	current := 1 // priority/backlog
+	for i, priority := range priorityLabels {
+		if hasLabel(labels, priority) {
+			current = i
+		}
+		labels = removeLabel(labels, priority)
+	}
+	if current+1 < len(priorityLabels) {
+		current++
+	}
+	return append(labels, priorityLabels[current])
+}
+
+// escalationComment mentions the team of every SIG in labels.
+func (f *TriageFiler) escalationComment(labels []string) string {
+	var teams []string
+	for _, label := range labels {
+		if strings.HasPrefix(label, "sig/") && f.sigTeamFormat != "" {
+			teams = append(teams, "@"+fmt.Sprintf(f.sigTeamFormat, strings.TrimPrefix(label, "sig/")))
+		}
+	}
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%s\n", escalationMarker)
+	fmt.Fprintf(&buf, "This flake has had no response in the %d days since it was filed, so its priority was raised and it is marked %q.\n", f.escalateDays, helpWantedLabel)
+	if len(teams) > 0 {
+		fmt.Fprintf(&buf, "\n%s: please triage this issue, or comment if it is not yours.\n", strings.Join(teams, " "))
+	}
+	return buf.String()
+}
@@ -0,0 +1,128 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sources
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/github"
+)
+
+func TestTFEscalateIssues(t *testing.T) {
+	now := time.Now()
+	bot, human, other := "bot", "human", "ci-bot"
+	escalation := &github.IssueComment{Body: github.String(escalationMarker + "\nmentioned"), User: &github.User{Login: &bot}}
+	filed := func(number, daysAgo int, labels ...string) *github.Issue {
+		issue := makeIssue(number, "### Failure cluster [hash](url)", labels...)
+		created := now.AddDate(0, 0, -daysAgo)
+		issue.CreatedAt = &created
+		issue.User = &github.User{Login: &bot}
+		return issue
+	}
+	tracker := &fakeTracker{
+		own: []*github.Issue{
+			filed(1, 10, "kind/flake", "sig/node"),
+			filed(2, 10, "kind/flake", "priority/important-soon"),
+			filed(3, 2, "kind/flake"),
+			filed(4, 10, "kind/flake", helpWantedLabel),
+			filed(5, 10, "kind/flake"),
+			filed(6, 10, "kind/flake"),
+			filed(7, 10, "kind/flake", helpWantedLabel),
+			filed(8, 10, "kind/flake"),
+		},
+		updated: map[int]*github.IssueRequest{},
+		comments: map[int][]*github.IssueComment{
+			1: {{Body: github.String("bot comment"), User: &github.User{Login: &bot}}},
+			5: {{Body: github.String("looking into it"), User: &github.User{Login: &human}}},
+			// Escalated before, but the labels were not changed.
+			6: {escalation},
+			7: {escalation},
+			8: {{Body: github.String("also failing"), User: &github.User{Login: &other}}},
+		},
+	}
+	f := &TriageFiler{escalateDays: 7, sigTeamFormat: "kubernetes/sig-%s-bugs", dedupeAuthors: "CI-Bot"}
+	if err := f.escalateIssues(context.Background(), tracker, now); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := map[int][]string{
+		1: {"kind/flake", "sig/node", "priority/important-longterm", helpWantedLabel},
+		2: {"kind/flake", "priority/critical-urgent", helpWantedLabel},
+		6: {"kind/flake", "priority/important-longterm", helpWantedLabel},
+		8: {"kind/flake", "priority/important-longterm", helpWantedLabel},
+	}
+	if len(tracker.updated) != len(expected) {
+		t.Errorf("Expected issues 1, 2, 6 and 8 to be relabeled, got %v", tracker.updated)
+	}
+	for number, labels := range expected {
+		update := tracker.updated[number]
+		if update == nil {
+			t.Errorf("Expected #%d to be escalated", number)
+			continue
+		}
+		if !reflect.DeepEqual(*update.Labels, labels) {
+			t.Errorf("Expected #%d to get the labels %q, got %q", number, labels, *update.Labels)
+		}
+	}
+	comments := tracker.comments[1]
+	if len(comments) != 2 || !strings.Contains(*comments[1].Body, "@kubernetes/sig-node-bugs") {
+		t.Errorf("Expected a comment on #1 mentioning the sig-node team, got %v", comments)
+	}
+	if len(tracker.comments[2]) != 1 || strings.Contains(*tracker.comments[2][0].Body, "@") {
+		t.Errorf("Expected a comment on #2 that mentions no team, got %v", tracker.comments[2])
+	}
+	// Issues marked help wanted by a human still get the comment, and escalated ones get no other.
+	for number, count := range map[int]int{3: 0, 4: 1, 5: 1, 6: 1, 7: 1, 8: 2} {
+		if len(tracker.comments[number]) != count {
+			t.Errorf("Expected %d comments on #%d, got %d", count, number, len(tracker.comments[number]))
+		}
+	}
+
+	// If the comment can't be posted, the labels are left alone so that the next run tries again.
+	tracker = &fakeTracker{
+		own:        []*github.Issue{filed(1, 10, "kind/flake")},
+		updated:    map[int]*github.IssueRequest{},
+		commentErr: errors.New("rate limited"),
+	}
+	if err := f.escalateIssues(context.Background(), tracker, now); err == nil {
+		t.Error("Expected an error when the comment fails.")
+	}
+	if len(tracker.updated) != 0 {
+		t.Errorf("Expected no issue to be relabeled when the comment fails, got %v", tracker.updated)
+	}
+}
+
+func TestRaisePriority(t *testing.T) {
+	tests := []struct {
+		labels   []string
+		expected []string
+	}{
+		{labels: []string{"kind/flake"}, expected: []string{"kind/flake", "priority/important-longterm"}},
+		{labels: []string{"priority/awaiting-more-evidence"}, expected: []string{"priority/backlog"}},
+		{labels: []string{"priority/critical-urgent"}, expected: []string{"priority/critical-urgent"}},
+	}
+	for _, test := range tests {
+		if labels := raisePriority(test.labels); !reflect.DeepEqual(labels, test.expected) {
+			t.Errorf("Expected %q to be raised to %q, got %q", test.labels, test.expected, labels)
+		}
+	}
+}
@@ -0,0 +1,300 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sources
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"google.golang.org/api/option"
+	htransport "google.golang.org/api/transport/http"
+	"k8s.io/test-infra/robots/issue-creator/creator"
+)
+
+const (
+	gcsPrefix  = "gs://"
+	s3Prefix   = "s3://"
+	filePrefix = "file://"
+)
+
+// S3Options configure the S3 or S3-compatible store that s3:// URLs are read from. They use the
+// same JSON format as the S3 credentials of prow's GCS-compatible storage, so one file can serve
+// both.
+type S3Options struct {
+	// Region is the region of the bucket.
+	Region string `json:"region"`
+	// Endpoint overrides the AWS S3 endpoint, e.g. to use a self-hosted Minio.
+	Endpoint string `json:"endpoint"`
+	// Insecure uses HTTP rather than HTTPS to talk to the endpoint.
+	Insecure bool `json:"insecure"`
+	// S3ForcePathStyle puts the bucket name in the path instead of the host name.
+	S3ForcePathStyle bool `json:"s3_force_path_style"`
+	// AccessKey and SecretKey are static credentials. If they are empty the default AWS
+	// credential chain is used.
+	AccessKey string `json:"access_key"`
+	SecretKey string `json:"secret_key"`
+}
+
+// DataFetcher opens data for reading, so that the source of the data can be replaced.
+type DataFetcher interface {
+	// Open returns a reader for the data at url and its size, or -1 if the size is unknown. The
+	// caller must close the reader. If there is no data at url, the error is a *NotFoundError.
+	Open(ctx context.Context, url string) (io.ReadCloser, int64, error)
+}
+
+// NotFoundError is returned by a DataFetcher when there is no data at a URL, as opposed to data
+// that couldn't be read.
+type NotFoundError struct {
+	Err error
+}
+
+func (e *NotFoundError) Error() string {
+	return e.Err.Error()
+}
+
+// IsNotFound returns true iff err is a *NotFoundError.
+func IsNotFound(err error) bool {
+	_, ok := err.(*NotFoundError)
+	return ok
+}
+
+// URLFetcher is a DataFetcher that reads the URLs that OpenData accepts. It keeps one GCS client
+// for all of its fetches, which is created by the first fetch from GCS.
+type URLFetcher struct {
+	Options HTTPOptions
+
+	gcsLock sync.Mutex
+	gcs     *storage.Client
+}
+
+// Open implements DataFetcher.
+func (u *URLFetcher) Open(ctx context.Context, url string) (io.ReadCloser, int64, error) {
+	var path string
+	switch {
+	case strings.HasPrefix(url, gcsPrefix):
+		client, err := u.gcsClient()
+		if err != nil {
+			return nil, 0, err
+		}
+		return openGCS(ctx, client, url, u.Options)
+	case strings.HasPrefix(url, s3Prefix):
+		return openS3(ctx, url, u.Options)
+	case strings.HasPrefix(url, filePrefix):
+		path = strings.TrimPrefix(url, filePrefix)
+	case !strings.Contains(url, "://"):
+		path = url
+	default:
+		return StreamHTTP(ctx, url, u.Options)
+	}
+	start := time.Now()
+	body, size, err := openFile(path)
+	return observeOpen(url, start, body, err), size, err
+}
+
+// gcsClient returns the GCS client of the fetcher, creating it if needed.
+func (u *URLFetcher) gcsClient() (*storage.Client, error) {
+	u.gcsLock.Lock()
+	defer u.gcsLock.Unlock()
+	if u.gcs != nil {
+		return u.gcs, nil
+	}
+	// The client outlives the fetch that creates it, so it isn't bound to the fetch's context.
+	ctx := context.Background()
+	base := u.Options.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	// Application Default Credentials are added on top of the transport, so that requests to GCS go
+	// through the same proxy, CA certificates and rate limit as the rest.
+	transport, err := htransport.NewTransport(ctx, base, option.WithScopes(storage.ScopeReadOnly))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create a GCS client: %v", err)
+	}
+	client, err := storage.NewClient(ctx, option.WithHTTPClient(&http.Client{Transport: transport}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create a GCS client: %v", err)
+	}
+	u.gcs = client
+	return client, nil
+}
+
+// Close releases the GCS client of the fetcher, if it created one.
+func (u *URLFetcher) Close() error {
+	u.gcsLock.Lock()
+	defer u.gcsLock.Unlock()
+	if u.gcs == nil {
+		return nil
+	}
+	err := u.gcs.Close()
+	u.gcs = nil
+	return err
+}
+
+// OpenData opens the data at a URL for reading. gs://bucket/object URLs are read from GCS with
+// Application Default Credentials, s3://bucket/object URLs are read from the store configured by
+// opts.S3, file:// URLs and plain paths are read from the local disk, and any other URL is fetched
+// with StreamHTTP. Requests to GCS, S3 and other URLs are all sent with opts.Transport. The size of
+// the data is returned if it is known, otherwise -1. The caller must close the reader.
+//
+// Fetches from files are recorded in the fetch metrics here. Requests are recorded by an
+// InstrumentTransport in opts.Transport, if any.
+//
+// OpenData creates a URLFetcher for the one fetch. Use a URLFetcher to share its GCS client
+// between fetches.
+func OpenData(ctx context.Context, url string, opts HTTPOptions) (io.ReadCloser, int64, error) {
+	fetcher := &URLFetcher{Options: opts}
+	body, size, err := fetcher.Open(ctx, url)
+	if err != nil {
+		fetcher.Close()
+		return nil, 0, err
+	}
+	return &cancelReader{ReadCloser: body, cancel: func() { fetcher.Close() }}, size, nil
+}
+
+// validateDataURL returns an error naming flagName if url can't be opened by OpenData: bucket URLs
+// must name an object, local files must exist, and other URLs must be absolute.
+func validateDataURL(flagName, url string) error {
+	switch {
+	case strings.HasPrefix(url, gcsPrefix):
+		_, _, err := splitBucketURL(url, gcsPrefix)
+		return err
+	case strings.HasPrefix(url, s3Prefix):
+		_, _, err := splitBucketURL(url, s3Prefix)
+		return err
+	case strings.HasPrefix(url, filePrefix):
+		return creator.ValidateFile(flagName, strings.TrimPrefix(url, filePrefix))
+	case !strings.Contains(url, "://"):
+		return creator.ValidateFile(flagName, url)
+	default:
+		return creator.ValidateURL(flagName, url)
+	}
+}
+
+func openFile(path string) (io.ReadCloser, int64, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, 0, &NotFoundError{Err: err}
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, 0, err
+	}
+	return file, info.Size(), nil
+}
+
+// splitBucketURL splits a URL like gs://bucket/object into the bucket and object names.
+func splitBucketURL(url, prefix string) (string, string, error) {
+	parts := strings.SplitN(strings.TrimPrefix(url, prefix), "/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("invalid URL '%s', expected %sbucket/object", url, prefix)
+	}
+	return parts[0], parts[1], nil
+}
+
+func openGCS(ctx context.Context, client *storage.Client, url string, opts HTTPOptions) (io.ReadCloser, int64, error) {
+	bucket, object, err := splitBucketURL(url, gcsPrefix)
+	if err != nil {
+		return nil, 0, err
+	}
+	// The GCS client retries on its own, so only the timeout applies. It covers the whole download.
+	cancel := func() {}
+	if opts.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+	}
+	reader, err := client.Bucket(bucket).Object(object).NewReader(ctx)
+	if err != nil {
+		cancel()
+		if err == storage.ErrObjectNotExist {
+			return nil, 0, &NotFoundError{Err: fmt.Errorf("failed to read '%s': %v", url, err)}
+		}
+		return nil, 0, fmt.Errorf("failed to read '%s': %v", url, err)
+	}
+	return &cancelReader{ReadCloser: reader, cancel: cancel}, reader.Attrs.Size, nil
+}
+
+func openS3(ctx context.Context, url string, opts HTTPOptions) (io.ReadCloser, int64, error) {
+	bucket, key, err := splitBucketURL(url, s3Prefix)
+	if err != nil {
+		return nil, 0, err
+	}
+	config := &aws.Config{
+		Region:           aws.String(opts.S3.Region),
+		DisableSSL:       aws.Bool(opts.S3.Insecure),
+		S3ForcePathStyle: aws.Bool(opts.S3.S3ForcePathStyle),
+		MaxRetries:       aws.Int(opts.Retries),
+		HTTPClient:       &http.Client{Transport: opts.Transport},
+	}
+	if opts.S3.Endpoint != "" {
+		config.Endpoint = aws.String(opts.S3.Endpoint)
+	}
+	if opts.S3.AccessKey != "" || opts.S3.SecretKey != "" {
+		config.Credentials = credentials.NewStaticCredentials(opts.S3.AccessKey, opts.S3.SecretKey, "")
+	}
+	sess, err := session.NewSession(config)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to create an S3 session: %v", err)
+	}
+	// Like the GCS client, the S3 client retries on its own and the timeout covers the whole download.
+	cancel := func() {}
+	if opts.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+	}
+	out, err := s3.New(sess).GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		cancel()
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == s3.ErrCodeNoSuchKey {
+			return nil, 0, &NotFoundError{Err: fmt.Errorf("failed to read '%s': %v", url, err)}
+		}
+		return nil, 0, fmt.Errorf("failed to read '%s': %v", url, err)
+	}
+	size := int64(-1)
+	if out.ContentLength != nil {
+		size = *out.ContentLength
+	}
+	return &cancelReader{ReadCloser: out.Body, cancel: cancel}, size, nil
+}
+
+// cancelReader cancels the context of a download once its body is closed.
+type cancelReader struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (r *cancelReader) Close() error {
+	err := r.ReadCloser.Close()
+	r.cancel()
+	return err
+}
@@ -0,0 +1,119 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sources
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSplitBucketURL(t *testing.T) {
+	tests := []struct {
+		url    string
+		prefix string
+		bucket string
+		object string
+		err    bool
+	}{
+		{url: "gs://k8s-gubernator/triage/failure_data.json", prefix: gcsPrefix, bucket: "k8s-gubernator", object: "triage/failure_data.json"},
+		{url: "gs://bucket/object", prefix: gcsPrefix, bucket: "bucket", object: "object"},
+		{url: "s3://bucket/triage/failure_data.json", prefix: s3Prefix, bucket: "bucket", object: "triage/failure_data.json"},
+		{url: "gs://bucket", prefix: gcsPrefix, err: true},
+		{url: "gs://bucket/", prefix: gcsPrefix, err: true},
+		{url: "s3:///object", prefix: s3Prefix, err: true},
+	}
+	for _, test := range tests {
+		bucket, object, err := splitBucketURL(test.url, test.prefix)
+		if test.err {
+			if err == nil {
+				t.Errorf("%s: expected an error, got %q and %q", test.url, bucket, object)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.url, err)
+		} else if bucket != test.bucket || object != test.object {
+			t.Errorf("%s: expected %q and %q, got %q and %q", test.url, test.bucket, test.object, bucket, object)
+		}
+	}
+}
+
+func TestOpenDataHTTP(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/missing":
+			http.NotFound(w, r)
+		case "/broken":
+			http.Error(w, "broken", http.StatusForbidden)
+		default:
+			fmt.Fprint(w, "data")
+		}
+	}))
+	defer server.Close()
+
+	if _, _, err := OpenData(context.Background(), server.URL+"/missing", HTTPOptions{}); !IsNotFound(err) {
+		t.Errorf("expected a *NotFoundError for a missing URL, got %v", err)
+	}
+	if _, _, err := OpenData(context.Background(), server.URL+"/broken", HTTPOptions{}); err == nil || IsNotFound(err) {
+		t.Errorf("expected an error other than a *NotFoundError for a forbidden URL, got %v", err)
+	}
+
+	body, _, err := OpenData(context.Background(), server.URL, HTTPOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer body.Close()
+	if data, err := ioutil.ReadAll(body); err != nil || string(data) != "data" {
+		t.Errorf("expected %q, got %q and error %v", "data", data, err)
+	}
+}
+
+func TestOpenDataFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fetch")
+	if err != nil {
+		t.Fatalf("failed to create a temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "failure_data.json")
+	if err := ioutil.WriteFile(path, []byte("data"), 0644); err != nil {
+		t.Fatalf("failed to write %s: %v", path, err)
+	}
+
+	for _, url := range []string{path, "file://" + path} {
+		body, size, err := OpenData(context.Background(), url, HTTPOptions{})
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", url, err)
+			continue
+		}
+		if size != 4 {
+			t.Errorf("%s: expected a size of 4, got %d", url, size)
+		}
+		if data, err := ioutil.ReadAll(body); err != nil || string(data) != "data" {
+			t.Errorf("%s: expected %q, got %q and error %v", url, "data", data, err)
+		}
+		body.Close()
+	}
+	if _, _, err := OpenData(context.Background(), filepath.Join(dir, "missing.json"), HTTPOptions{}); !IsNotFound(err) {
+		t.Errorf("expected a *NotFoundError for a missing file, got %v", err)
+	}
+}
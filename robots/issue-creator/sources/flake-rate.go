@@ -0,0 +1,109 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sources
+
+import (
+	"strings"
+)
+
+// TestFlakeRate is how often a test failed over the trailing flake rate window.
+type TestFlakeRate struct {
+	// Failures is the number of builds in which the test failed.
+	Failures int
+	// Runs is the number of builds of the jobs that the test failed in. The triage data only
+	// records runs per job, so this counts builds whether or not they ran the test.
+	Runs int
+}
+
+// Rate returns the fraction of runs that failed, or 0 if there were no runs.
+func (r TestFlakeRate) Rate() float64 {
+	if r.Runs == 0 {
+		return 0
+	}
+	return float64(r.Failures) / float64(r.Runs)
+}
+
+// FlakeRate returns the failure rate of a test over the trailing '--triage-flake-rate-weeks' as of
+// the last run, and false if the test did not fail in that time or rates are disabled.
+func (f *TriageFiler) FlakeRate(testName string) (TestFlakeRate, bool) {
+	rate, ok := f.flakeRates[testName]
+	return rate, ok
+}
+
+// computeFlakeRates counts the failures of every test in the clusters and the runs of the jobs
+// they failed in, for builds started after cutoff and by the end of the window. It must run before
+// the clusters are filtered to the sliding window, which is usually shorter. Builds that can't be
+// found are skipped here and reported by filterAndValidate.
+func (f *TriageFiler) computeFlakeRates(cutoff int64) map[string]TestFlakeRate {
+	started := f.data.Builds.Cols.Started
+	jobRuns := map[string]int{}
+	runsSince := func(job string) int {
+		if runs, ok := jobRuns[job]; ok {
+			return runs
+		}
+		runs := 0
+		if rowMap, ok := f.data.Builds.Jobs[job]; ok {
+			for _, row := range rowMap.buildRows() {
+				if row >= 0 && row < len(started) && started[row] > cutoff && started[row] <= f.latestStart {
+					runs++
+				}
+			}
+		}
+		jobRuns[job] = runs
+		return runs
+	}
+
+	// A test can be in several clusters, so a failed build is only counted once per test.
+	failed := map[string]map[string]map[int]bool{}
+	for _, clust := range f.data.Clustered {
+		for _, test := range clust.Tests {
+			for _, job := range test.Jobs {
+				if strings.HasPrefix(job.Name, "pr:") {
+					continue
+				}
+				rowMap, ok := f.data.Builds.Jobs[job.Name]
+				if !ok {
+					continue
+				}
+				for _, buildnum := range job.Builds {
+					row, err := rowMap.rowForBuild(buildnum)
+					if err != nil || row < 0 || row >= len(started) || started[row] <= cutoff || started[row] > f.latestStart {
+						continue
+					}
+					if failed[test.Name] == nil {
+						failed[test.Name] = map[string]map[int]bool{}
+					}
+					if failed[test.Name][job.Name] == nil {
+						failed[test.Name][job.Name] = map[int]bool{}
+					}
+					failed[test.Name][job.Name][buildnum] = true
+				}
+			}
+		}
+	}
+
+	rates := make(map[string]TestFlakeRate, len(failed))
+	for test, jobs := range failed {
+		var rate TestFlakeRate
+		for job, builds := range jobs {
+			rate.Failures += len(builds)
+			rate.Runs += runsSince(job)
+		}
+		rates[test] = rate
+	}
+	return rates
+}
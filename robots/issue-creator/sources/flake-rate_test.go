@@ -0,0 +1,87 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sources
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTFFlakeRates(t *testing.T) {
+	tests := []struct {
+		weeks    int
+		expected map[string]TestFlakeRate
+	}{
+		{weeks: 0},
+		{
+			// Build 41 is 10 days old, so it only counts in the 2 week window. jobname1 has 4
+			// builds in 2 weeks and 3 in 1 week, and jobname2 has 2 builds in both.
+			weeks: 1,
+			expected: map[string]TestFlakeRate{
+				"testname1": {Failures: 4, Runs: 5},
+				"testname2": {Failures: 2, Runs: 3},
+			},
+		},
+		{
+			weeks: 2,
+			expected: map[string]TestFlakeRate{
+				"testname1": {Failures: 4, Runs: 6},
+				"testname2": {Failures: 3, Runs: 4},
+			},
+		},
+	}
+	for _, test := range tests {
+		f := NewTestTriageFiler()
+		f.flakeRateWeeks = test.weeks
+		clusters, err := f.loadClusters(json1issue2job2test)
+		if err != nil {
+			t.Fatalf("Error parsing triage data: %v\n", err)
+		}
+		for name, expected := range test.expected {
+			if rate, ok := f.FlakeRate(name); !ok || rate != expected {
+				t.Errorf("%d weeks: expected %s to have rate %+v, got %+v", test.weeks, name, expected, rate)
+			}
+		}
+		if _, ok := f.FlakeRate("testname3"); ok {
+			t.Errorf("%d weeks: expected no rate for a test that didn't fail", test.weeks)
+		}
+		body := clusters[0].Body(nil)
+		if test.weeks == 0 {
+			if strings.Contains(body, "Failure Rate") {
+				t.Errorf("Expected no failure rates in the body when they are disabled:\n%s", body)
+			}
+		} else if test.weeks == 2 && !strings.Contains(body, "| testname1 | 2 | 66.7% (4/6 runs) |") {
+			t.Errorf("Expected the body to contain the failure rate of testname1:\n%s", body)
+		}
+		if test.weeks == 1 {
+			// A test whose failures are all older than the rate window has no rate.
+			delete(f.flakeRates, "testname2")
+			if body := clusters[0].Body(nil); !strings.Contains(body, "| testname2 | 1 | n/a |") {
+				t.Errorf("Expected no failure rate for a test without runs in the window:\n%s", body)
+			}
+		}
+	}
+}
+
+func TestFlakeRateRate(t *testing.T) {
+	if rate := (TestFlakeRate{}).Rate(); rate != 0 {
+		t.Errorf("Expected a rate of 0 without runs, got %v", rate)
+	}
+	if rate := (TestFlakeRate{Failures: 1, Runs: 4}).Rate(); rate != 0.25 {
+		t.Errorf("Expected a rate of 0.25, got %v", rate)
+	}
+}
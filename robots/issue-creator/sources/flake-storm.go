@@ -0,0 +1,161 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sources
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	githubapi "github.com/google/go-github/github"
+	"k8s.io/test-infra/robots/issue-creator/creator"
+)
+
+const (
+	// flakeStormID identifies the umbrella issue filed instead of the issues of clusters during a
+	// flake storm. DO NOT CHANGE or duplicate issues may be created on github.
+	flakeStormID = "Flake storm"
+	// stormMinBuilds is the fewest builds a cluster must fail in the storm period to be spiking.
+	stormMinBuilds = 2
+	// maxStormClusters is the maximum number of clusters listed in the umbrella issue.
+	maxStormClusters = 20
+)
+
+// flakeStormIssue is the umbrella issue for many clusters that started failing at once, which
+// usually means an infra outage rather than many separate flakes.
+type flakeStormIssue struct {
+	filer *TriageFiler
+	// since is the start of the storm period.
+	since int64
+	// clusters are the spiking clusters, worst first.
+	clusters []*Cluster
+	// jobBuilds and jobClusters are the builds of each job that the spiking clusters failed in the
+	// storm period, and how many of the clusters failed them.
+	jobBuilds, jobClusters map[string]int
+}
+
+// flakeStorm finds the clusters that spiked in the last stormHours: they failed at least
+// stormMinBuilds builds in that period, and more than in the rest of the window.
+func (f *TriageFiler) flakeStorm(clusters []*Cluster) *flakeStormIssue {
+	storm := &flakeStormIssue{
+		filer:       f,
+		since:       f.latestStart - int64(f.stormHours)*60*60,
+		jobBuilds:   map[string]int{},
+		jobClusters: map[string]int{},
+	}
+	for _, clust := range clusters {
+		before, after := clust.buildsAround(storm.since)
+		if after < stormMinBuilds || after <= before {
+			continue
+		}
+		storm.clusters = append(storm.clusters, clust)
+		for job, builds := range clust.jobs {
+			rowMap := f.data.Builds.Jobs[job]
+			recent := 0
+			for _, build := range builds {
+				row, _ := rowMap.rowForBuild(build) // Already validated start time lookup for all builds.
+				if f.data.Builds.Cols.Started[row] >= storm.since {
+					recent++
+				}
+			}
+			if recent > 0 {
+				storm.jobBuilds[job] += recent
+				storm.jobClusters[job]++
+			}
+		}
+	}
+	sort.SliceStable(storm.clusters, func(i, j int) bool {
+		return storm.clusters[i].totalBuilds > storm.clusters[j].totalBuilds
+	})
+	return storm
+}
+
+// active returns true iff enough clusters spiked at once to count as a storm.
+func (s *flakeStormIssue) active() bool {
+	return s.filer.stormClusters > 0 && len(s.clusters) >= s.filer.stormClusters
+}
+
+// Title is the string to use as the github issue title.
+func (s *flakeStormIssue) Title() string {
+	return fmt.Sprintf("%s: %d failure clusters spiked in %d jobs in the last %d hours", flakeStormID, len(s.clusters), len(s.jobBuilds), s.filer.stormHours)
+}
+
+// Body returns the body text of the github issue. A new storm is filed even if the issue of the
+// last one was recently closed. Once the storm subsides the open issue says so.
+func (s *flakeStormIssue) Body(closedIssues []*githubapi.Issue) string {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%s\n### %s\n", creator.ReportMarker(s.ID()), flakeStormID)
+	var report bytes.Buffer
+	if !s.active() {
+		fmt.Fprintf(&report, "The storm subsided by '%s': fewer than %d clusters spiked in the %d hours before it. Issues are filed for individual clusters again.\n",
+			s.filer.windowTime(s.filer.latestStart).Format(timeFormat), s.filer.stormClusters, s.filer.stormHours)
+		buf.WriteString(creator.Section("report", report.String()))
+		return buf.String()
+	}
+	fmt.Fprintf(&report, "%d failure clusters started failing at once between '%s' and '%s', which usually means an infra outage rather than separate flakes. "+
+		"Issues for individual clusters are not filed until the storm subsides. This issue is updated with every triage run.\n",
+		len(s.clusters),
+		s.filer.windowTime(s.since).Format(timeFormat),
+		s.filer.windowTime(s.filer.latestStart).Format(timeFormat))
+
+	jobs := make([]string, 0, len(s.jobBuilds))
+	for job := range s.jobBuilds {
+		jobs = append(jobs, job)
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		if s.jobBuilds[jobs[i]] != s.jobBuilds[jobs[j]] {
+			return s.jobBuilds[jobs[i]] > s.jobBuilds[jobs[j]]
+		}
+		return jobs[i] < jobs[j]
+	})
+	fmt.Fprint(&report, "\n##### Affected jobs:\n")
+	fmt.Fprint(&report, "\n| Job Name | Builds Failed | Clusters |\n| --- | --- | --- |\n")
+	for _, job := range jobs {
+		fmt.Fprintf(&report, "| %s | %d | %d |\n", s.filer.jobLabel(job), s.jobBuilds[job], s.jobClusters[job])
+	}
+
+	fmt.Fprint(&report, "\n##### Spiking failure clusters:\n")
+	for i, clust := range s.clusters {
+		if i == maxStormClusters {
+			fmt.Fprintf(&report, "\n...and %d more clusters.\n", len(s.clusters)-maxStormClusters)
+			break
+		}
+		fmt.Fprintf(&report, "- [%s](%s#%s): %d builds failed\n", clust.Identifier, triageURL, clust.Identifier, clust.totalBuilds)
+	}
+	buf.WriteString(creator.Section("report", report.String()))
+	return buf.String()
+}
+
+// ID yields the string identifier that uniquely identifies this issue.
+func (s *flakeStormIssue) ID() string {
+	return flakeStormID
+}
+
+// Labels returns the labels to apply to the issue on github.
+func (s *flakeStormIssue) Labels() []string {
+	return []string{"kind/flake", infraFlakeLabel}
+}
+
+// Owners returns the list of usernames to assign to this issue on github.
+func (s *flakeStormIssue) Owners() []string {
+	return nil
+}
+
+// Priority calculates and returns the priority of this issue.
+func (s *flakeStormIssue) Priority() (string, bool) {
+	return "", false
+}
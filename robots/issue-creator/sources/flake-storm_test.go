@@ -0,0 +1,91 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sources
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/github"
+	"k8s.io/test-infra/robots/issue-creator/creator"
+)
+
+// stormTriageData has builds 1 to 6 of ci-a and ci-b, one hour apart. storm_a and storm_b failed
+// only recent builds, while steady failed mostly older ones.
+var stormTriageData = []byte(`{
+	"builds": {
+		"cols": {
+			"started": [
+				1000000000, 1000003600, 1000007200, 1000010800, 1000014400, 1000018000,
+				1000000000, 1000003600, 1000007200, 1000010800, 1000014400, 1000018000
+			]
+		},
+		"jobs": {"ci-a": [1, 6, 0], "ci-b": [1, 6, 6]},
+		"job_paths": {"ci-a": "gs://bucket/logs/ci-a", "ci-b": "gs://bucket/logs/ci-b"}
+	},
+	"clustered": [
+		{"id": "storm_a", "key": "a", "text": "error a", "tests": [{"name": "test1", "jobs": [{"name": "ci-a", "builds": [5, 6]}]}]},
+		{"id": "storm_b", "key": "b", "text": "error b", "tests": [{"name": "test2", "jobs": [{"name": "ci-a", "builds": [6]}, {"name": "ci-b", "builds": [4, 5, 6]}]}]},
+		{"id": "steady", "key": "c", "text": "error c", "tests": [{"name": "test3", "jobs": [{"name": "ci-a", "builds": [1, 2, 6]}]}]}
+	]
+}`)
+
+func TestTFFlakeStorm(t *testing.T) {
+	f := NewTestTriageFiler()
+	f.dataURL = "https://example.com/failure_data.json"
+	f.Fetcher = &fakeFetcher{data: stormTriageData}
+	f.stormClusters = 2
+	f.stormHours = 3
+	tracker := &fakeTracker{updated: map[int]*github.IssueRequest{}}
+	f.tracker = tracker
+
+	issues, err := f.Issues(context.Background(), f.creator)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(issues) != 1 || issues[0].ID() != flakeStormID {
+		t.Fatalf("Expected only the umbrella issue to be filed during a storm, got %d issues", len(issues))
+	}
+	body := issues[0].Body(nil)
+	for _, expected := range []string{
+		creator.ReportMarker(flakeStormID),
+		"2 failure clusters started failing at once",
+		"| ci-a | 3 | 2 |\n| ci-b | 3 | 1 |\n",
+		"- [storm_b](" + triageURL + "#storm_b): 4 builds failed\n- [storm_a](",
+	} {
+		if !strings.Contains(body, expected) {
+			t.Errorf("Expected the umbrella issue to contain %q, got:\n%s", expected, body)
+		}
+	}
+	if strings.Contains(body, "steady") {
+		t.Errorf("Expected clusters that didn't spike to be left out, got:\n%s", body)
+	}
+
+	// Once the storm subsides the open umbrella issue says so and clusters are filed again.
+	tracker.own = []*github.Issue{makeIssue(9, body, "kind/flake")}
+	f.stormClusters = 3
+	if issues, err = f.Issues(context.Background(), f.creator); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(issues) != 3 {
+		t.Errorf("Expected the issues of the 3 clusters after the storm, got %d", len(issues))
+	}
+	if update := tracker.updated[9]; update == nil || !strings.Contains(*update.Body, "The storm subsided") {
+		t.Errorf("Expected #9 to be updated to say the storm subsided, got %v", update)
+	}
+}
@@ -18,16 +18,13 @@ package sources
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"flag"
 	"fmt"
-	"io/ioutil"
-	"net/http"
 	"sort"
 	"time"
 
-	"github.com/golang/glog"
-
 	githubapi "github.com/google/go-github/github"
 	"k8s.io/test-infra/robots/issue-creator/creator"
 )
@@ -61,8 +58,11 @@ type FlakyJobReporter struct {
 	creator *creator.IssueCreator
 }
 
+// flakyJobReporterName is the name that the FlakyJobReporter is registered and logs under.
+const flakyJobReporterName = "flakyjob-reporter"
+
 func init() {
-	creator.RegisterSourceOrDie("flakyjob-reporter", &FlakyJobReporter{})
+	creator.RegisterSourceOrDie(flakyJobReporterName, &FlakyJobReporter{})
 }
 
 // RegisterFlags registers options for this munger; returns any that require a restart when changed.
@@ -71,11 +71,25 @@ func (fjr *FlakyJobReporter) RegisterFlags() {
 	flag.IntVar(&fjr.syncCount, "flakyjob-count", 3, "The number of flaky jobs to try to sync to github.")
 }
 
+// Validate implements creator.SourceValidator.
+func (fjr *FlakyJobReporter) Validate() []error {
+	var errs []error
+	if fjr.syncCount < 1 {
+		errs = append(errs, fmt.Errorf("'--flakyjob-count' must be at least 1, got %d", fjr.syncCount))
+	}
+	if err := creator.ValidateURL("flakyjob-url", fjr.flakyJobDataURL); err != nil {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
 // Issues is the main work method of FlakyJobReporter. It fetches and parses flaky job data,
 // then syncs the top issues to github with the IssueCreator.
-func (fjr *FlakyJobReporter) Issues(c *creator.IssueCreator) ([]creator.Issue, error) {
+func (fjr *FlakyJobReporter) Issues(ctx context.Context, c *creator.IssueCreator) ([]creator.Issue, error) {
 	fjr.creator = c
-	json, err := ReadHTTP(fjr.flakyJobDataURL)
+	opts := DefaultHTTPOptions
+	opts.Transport = InstrumentTransport(c.Transport())
+	json, err := ReadHTTPWithOptions(ctx, fjr.flakyJobDataURL, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -108,24 +122,25 @@ func (fjr *FlakyJobReporter) parseFlakyJobs(jsonIn []byte) ([]*FlakyJob, error)
 	flakyJobs := make([]*FlakyJob, 0, len(flakeMap))
 
 	for job, fj := range flakeMap {
+		log := fjr.creator.Log(flakyJobReporterName, "").WithJob(job, 0)
 		if job == "" {
-			glog.Errorf("Flaky jobs json contained a job with an empty jobname.\n")
+			log.Errorf("Flaky jobs json contained a job with an empty jobname.")
 			continue
 		}
 		if fj == nil {
-			glog.Errorf("Flaky jobs json has invalid data for job '%s'.\n", job)
+			log.Errorf("Flaky jobs json has invalid data for the job.")
 			continue
 		}
 		if fj.Consistency == nil {
-			glog.Errorf("Flaky jobs json has no 'consistency' field for job '%s'.\n", job)
+			log.Errorf("Flaky jobs json has no 'consistency' field for the job.")
 			continue
 		}
 		if fj.FlakeCount == nil {
-			glog.Errorf("Flaky jobs json has no 'flakes' field for job '%s'.\n", job)
+			log.Errorf("Flaky jobs json has no 'flakes' field for the job.")
 			continue
 		}
 		if fj.FlakyTests == nil {
-			glog.Errorf("Flaky jobs json has no 'flakiest' field for job '%s'.\n", job)
+			log.Errorf("Flaky jobs json has no 'flakiest' field for the job.")
 			continue
 		}
 		fj.Name = job
@@ -135,6 +150,9 @@ func (fjr *FlakyJobReporter) parseFlakyJobs(jsonIn []byte) ([]*FlakyJob, error)
 
 	sort.SliceStable(flakyJobs, func(i, j int) bool {
 		if *flakyJobs[i].FlakeCount == *flakyJobs[j].FlakeCount {
+			if *flakyJobs[i].Consistency == *flakyJobs[j].Consistency {
+				return flakyJobs[i].Name < flakyJobs[j].Name
+			}
 			return *flakyJobs[i].Consistency < *flakyJobs[j].Consistency
 		}
 		return *flakyJobs[i].FlakeCount > *flakyJobs[j].FlakeCount
@@ -144,7 +162,7 @@ func (fjr *FlakyJobReporter) parseFlakyJobs(jsonIn []byte) ([]*FlakyJob, error)
 }
 
 // TestsSorted returns a slice of the testnames from a FlakyJob's FlakyTests map. The slice is
-// sorted by descending number of failures for the tests.
+// sorted by descending number of failures for the tests, and then by name.
 func (fj *FlakyJob) TestsSorted() []string {
 	if fj.testsSorted != nil {
 		return fj.testsSorted
@@ -155,8 +173,11 @@ func (fj *FlakyJob) TestsSorted() []string {
 		fj.testsSorted[i] = test
 		i++
 	}
-	sort.SliceStable(fj.testsSorted, func(i, j int) bool {
-		return fj.FlakyTests[fj.testsSorted[i]] > fj.FlakyTests[fj.testsSorted[j]]
+	sort.Slice(fj.testsSorted, func(i, j int) bool {
+		if fj.FlakyTests[fj.testsSorted[i]] != fj.FlakyTests[fj.testsSorted[j]] {
+			return fj.FlakyTests[fj.testsSorted[i]] > fj.FlakyTests[fj.testsSorted[j]]
+		}
+		return fj.testsSorted[i] < fj.testsSorted[j]
 	})
 	return fj.testsSorted
 }
@@ -211,7 +232,7 @@ func (fj *FlakyJob) Body(closedIssues []*githubapi.Issue) string {
 	ownersMap := fj.reporter.creator.TestsOwners(testsSorted)
 	if len(ownersMap) > 0 {
 		fmt.Fprint(&buf, "\n/assign")
-		for user := range ownersMap {
+		for _, user := range creator.SortedKeys(ownersMap) {
 			fmt.Fprintf(&buf, " @%s", user)
 		}
 		fmt.Fprint(&buf, "\n")
@@ -231,7 +252,7 @@ func (fj *FlakyJob) Body(closedIssues []*githubapi.Issue) string {
 func (fj *FlakyJob) Labels() []string {
 	labels := []string{"kind/flake"}
 	// get sig labels
-	for sig := range fj.reporter.creator.TestsSIGs(fj.TestsSorted()) {
+	for _, sig := range creator.SortedKeys(fj.reporter.creator.TestsSIGs(fj.TestsSorted())) {
 		labels = append(labels, "sig/"+sig)
 	}
 	return labels
@@ -251,32 +272,3 @@ func (fj *FlakyJob) Priority() (string, bool) {
 	// TODO: implement priority calculations later
 	return "", false
 }
-
-// ReadHTTP fetches file contents from a URL with retries.
-func ReadHTTP(url string) ([]byte, error) {
-	var err error
-	retryDelay := time.Duration(2) * time.Second
-	for retryCount := 0; retryCount < 5; retryCount++ {
-		if retryCount > 0 {
-			time.Sleep(retryDelay)
-			retryDelay *= time.Duration(2)
-		}
-
-		resp, err := http.Get(url)
-		if resp != nil && resp.StatusCode >= 500 {
-			// Retry on this type of error.
-			continue
-		}
-		if err != nil {
-			return nil, err
-		}
-		defer resp.Body.Close()
-
-		body, err := ioutil.ReadAll(resp.Body)
-		if err != nil {
-			continue
-		}
-		return body, nil
-	}
-	return nil, fmt.Errorf("ran out of retries reading from '%s'. Last error was %v", url, err)
-}
@@ -17,6 +17,7 @@ limitations under the License.
 package sources
 
 import (
+	"reflect"
 	"testing"
 	"time"
 
@@ -142,3 +143,11 @@ func checkFlakyJobsSorted(jobs []*FlakyJob) bool {
 	}
 	return true
 }
+
+func TestFJTestsSorted(t *testing.T) {
+	fj := &FlakyJob{FlakyTests: map[string]int{"b": 3, "d": 5, "a": 3, "c": 1}}
+	expected := []string{"d", "a", "b", "c"}
+	if tests := fj.TestsSorted(); !reflect.DeepEqual(tests, expected) {
+		t.Errorf("Expected tests sorted by flakes and then by name %q, got %q", expected, tests)
+	}
+}
@@ -0,0 +1,233 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sources
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+	"golang.org/x/oauth2"
+)
+
+// HTTPOptions control how ReadHTTPWithOptions fetches a URL.
+type HTTPOptions struct {
+	// Timeout limits each attempt, including reading the body. Zero means no timeout.
+	Timeout time.Duration
+	// Retries is the number of times a failed attempt is retried.
+	Retries int
+	// Backoff is the delay before the first retry. It doubles after every retry.
+	Backoff time.Duration
+	// RetryStatuses lists the HTTP status codes that are retried. If empty, every 5xx status is
+	// retried.
+	RetryStatuses StatusCodes
+	// Auth adds credentials to each request. If nil, requests are anonymous.
+	Auth func(*http.Request) error
+	// S3 configures the store that OpenData reads s3:// URLs from.
+	S3 S3Options
+	// Transport sends the requests, e.g. a DiskCache. If nil, http.DefaultTransport is used.
+	Transport http.RoundTripper
+}
+
+// BearerAuth authenticates requests with a static bearer token.
+func BearerAuth(token string) func(*http.Request) error {
+	return func(req *http.Request) error {
+		req.Header.Set("Authorization", "Bearer "+token)
+		return nil
+	}
+}
+
+// BasicAuth authenticates requests with a username and password.
+func BasicAuth(user, password string) func(*http.Request) error {
+	return func(req *http.Request) error {
+		req.SetBasicAuth(user, password)
+		return nil
+	}
+}
+
+// TokenSourceAuth authenticates requests with a token from src, which is asked for a token before
+// each request so that expired tokens are refreshed.
+func TokenSourceAuth(src oauth2.TokenSource) func(*http.Request) error {
+	return func(req *http.Request) error {
+		token, err := src.Token()
+		if err != nil {
+			return fmt.Errorf("failed to get a token: %v", err)
+		}
+		token.SetAuthHeader(req)
+		return nil
+	}
+}
+
+// DefaultHTTPOptions are the options used by ReadHTTP.
+var DefaultHTTPOptions = HTTPOptions{
+	Timeout: 5 * time.Minute,
+	Retries: 4,
+	Backoff: 2 * time.Second,
+}
+
+// ReadHTTP fetches file contents from a URL with retries.
+func ReadHTTP(url string) ([]byte, error) {
+	return ReadHTTPContext(context.Background(), url)
+}
+
+// ReadHTTPContext fetches file contents from a URL with retries. The fetch is abandoned once ctx
+// is done.
+func ReadHTTPContext(ctx context.Context, url string) ([]byte, error) {
+	return ReadHTTPWithOptions(ctx, url, DefaultHTTPOptions)
+}
+
+// ReadHTTPWithOptions fetches file contents from a URL, retrying connection errors and retryable
+// status codes as configured by opts. The fetch is abandoned once ctx is done.
+func ReadHTTPWithOptions(ctx context.Context, url string, opts HTTPOptions) ([]byte, error) {
+	var body []byte
+	err := withRetries(ctx, url, opts, func(client *http.Client) (bool, error) {
+		resp, retry, err := openOnce(ctx, client, url, opts)
+		if err != nil {
+			return retry, err
+		}
+		defer resp.Body.Close()
+		body, err = ioutil.ReadAll(resp.Body)
+		return true, err
+	})
+	return body, err
+}
+
+// StreamHTTP opens a URL for reading, retrying connection errors and retryable status codes as
+// configured by opts, so that large files can be decoded as they arrive instead of being held in
+// memory. The size of the content is returned if the server reports it, otherwise -1. The caller
+// must close the returned reader. opts.Timeout limits the whole download, not just opening it.
+func StreamHTTP(ctx context.Context, url string, opts HTTPOptions) (io.ReadCloser, int64, error) {
+	var resp *http.Response
+	err := withRetries(ctx, url, opts, func(client *http.Client) (bool, error) {
+		var retry bool
+		var err error
+		resp, retry, err = openOnce(ctx, client, url, opts)
+		return retry, err
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return resp.Body, resp.ContentLength, nil
+}
+
+// withRetries calls attempt until it succeeds, it returns a failure that should not be retried,
+// the retries in opts run out, or ctx is done.
+func withRetries(ctx context.Context, url string, opts HTTPOptions, attempt func(*http.Client) (bool, error)) error {
+	client := &http.Client{Timeout: opts.Timeout, Transport: opts.Transport}
+	var err error
+	retryDelay := opts.Backoff
+	for i := 0; i <= opts.Retries; i++ {
+		if i > 0 {
+			glog.Warningf("Failed to read from '%s', retrying in %v: %v", url, retryDelay, err)
+			select {
+			case <-ctx.Done():
+				return fmt.Errorf("gave up reading from '%s': %v", url, ctx.Err())
+			case <-time.After(retryDelay):
+			}
+			retryDelay *= 2
+		}
+
+		var retry bool
+		retry, err = attempt(client)
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return fmt.Errorf("gave up reading from '%s': %v", url, ctx.Err())
+		}
+		if !retry {
+			return err
+		}
+	}
+	return fmt.Errorf("ran out of retries reading from '%s'. Last error was %v", url, err)
+}
+
+// openOnce makes a single request for the URL and returns the successful response. On failure it
+// returns whether the failure is worth retrying.
+func openOnce(ctx context.Context, client *http.Client, url string, opts HTTPOptions) (*http.Response, bool, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	if opts.Auth != nil {
+		if err := opts.Auth(req); err != nil {
+			return nil, true, err
+		}
+	}
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, true, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		resp.Body.Close()
+		err := fmt.Errorf("status %s", resp.Status)
+		if resp.StatusCode == http.StatusNotFound {
+			err = &NotFoundError{Err: err}
+		}
+		return nil, opts.RetryStatuses.retryable(resp.StatusCode), err
+	}
+	return resp, false, nil
+}
+
+// StatusCodes is a list of HTTP status codes that can be set from a comma separated flag value.
+type StatusCodes []int
+
+// String implements flag.Value.
+func (s *StatusCodes) String() string {
+	codes := make([]string, 0, len(*s))
+	for _, code := range *s {
+		codes = append(codes, strconv.Itoa(code))
+	}
+	return strings.Join(codes, ",")
+}
+
+// Set implements flag.Value.
+func (s *StatusCodes) Set(value string) error {
+	var codes StatusCodes
+	for _, field := range strings.Split(value, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		code, err := strconv.Atoi(field)
+		if err != nil || code < 100 || code > 599 {
+			return fmt.Errorf("invalid HTTP status code %q", field)
+		}
+		codes = append(codes, code)
+	}
+	*s = codes
+	return nil
+}
+
+// retryable returns true iff the status code is in the list, or is a 5xx code if the list is empty.
+func (s StatusCodes) retryable(code int) bool {
+	if len(s) == 0 {
+		return code >= 500
+	}
+	for _, c := range s {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,180 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sources
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+func TestReadHTTPWithOptions(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []int
+		retries  int
+		codes    StatusCodes
+		hits     int
+		err      bool
+	}{
+		{name: "success", statuses: []int{200}, retries: 3, hits: 1},
+		{name: "retry 5xx", statuses: []int{500, 503, 200}, retries: 3, hits: 3},
+		{name: "out of retries", statuses: []int{500, 500, 500}, retries: 2, hits: 3, err: true},
+		{name: "no retry 404", statuses: []int{404, 200}, retries: 3, hits: 1, err: true},
+		{name: "retry listed code", statuses: []int{429, 200}, retries: 3, codes: StatusCodes{429}, hits: 2},
+		{name: "unlisted 5xx", statuses: []int{500, 200}, retries: 3, codes: StatusCodes{429}, hits: 1, err: true},
+	}
+	for _, test := range tests {
+		hits := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			status := test.statuses[hits]
+			hits++
+			w.WriteHeader(status)
+			fmt.Fprint(w, "data")
+		}))
+		opts := HTTPOptions{Timeout: time.Second, Retries: test.retries, Backoff: time.Millisecond, RetryStatuses: test.codes}
+		body, err := ReadHTTPWithOptions(context.Background(), server.URL, opts)
+		server.Close()
+
+		if test.err {
+			if err == nil {
+				t.Errorf("%s: expected an error, got body %q", test.name, body)
+			}
+		} else if err != nil || string(body) != "data" {
+			t.Errorf("%s: expected body %q, got %q and error %v", test.name, "data", body, err)
+		}
+		if hits != test.hits {
+			t.Errorf("%s: expected %d requests, got %d", test.name, test.hits, hits)
+		}
+	}
+}
+
+func TestReadHTTPTimeout(t *testing.T) {
+	done := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-done
+	}))
+	defer server.Close()
+	defer close(done)
+
+	if _, err := ReadHTTPWithOptions(context.Background(), server.URL, HTTPOptions{Timeout: 10 * time.Millisecond}); err == nil {
+		t.Error("expected a timeout error")
+	}
+}
+
+func TestReadHTTPCancel(t *testing.T) {
+	hits := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := ReadHTTPWithOptions(ctx, server.URL, HTTPOptions{Retries: 5, Backoff: time.Hour})
+	if err == nil {
+		t.Fatal("expected an error once the context is done")
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("expected the fetch to stop when the context is done, took %v", elapsed)
+	}
+	if hits != 1 {
+		t.Errorf("expected 1 request before the context was done, got %d", hits)
+	}
+}
+
+func TestReadHTTPAuth(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, r.Header.Get("Authorization"))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		auth     func(*http.Request) error
+		expected string
+	}{
+		{name: "anonymous", expected: ""},
+		{name: "bearer", auth: BearerAuth("secret"), expected: "Bearer secret"},
+		{name: "basic", auth: BasicAuth("user", "pass"), expected: "Basic dXNlcjpwYXNz"},
+		{name: "token source", auth: TokenSourceAuth(oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "tok"})), expected: "Bearer tok"},
+	}
+	for _, test := range tests {
+		body, err := ReadHTTPWithOptions(context.Background(), server.URL, HTTPOptions{Auth: test.auth})
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+		} else if string(body) != test.expected {
+			t.Errorf("%s: expected Authorization %q, got %q", test.name, test.expected, body)
+		}
+	}
+}
+
+func TestStatusCodesFlag(t *testing.T) {
+	var codes StatusCodes
+	if err := codes.Set("429, 502,503"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(codes, StatusCodes{429, 502, 503}) {
+		t.Errorf("expected [429 502 503], got %v", codes)
+	}
+	if s := codes.String(); s != "429,502,503" {
+		t.Errorf("expected %q, got %q", "429,502,503", s)
+	}
+	for _, bad := range []string{"abc", "42", "600"} {
+		if err := codes.Set(bad); err == nil {
+			t.Errorf("expected an error for %q", bad)
+		}
+	}
+}
+
+func TestStreamHTTP(t *testing.T) {
+	hits := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		if hits == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Header().Set("Content-Length", "4")
+		fmt.Fprint(w, "data")
+	}))
+	defer server.Close()
+
+	body, size, err := StreamHTTP(context.Background(), server.URL, HTTPOptions{Retries: 1, Backoff: time.Millisecond})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer body.Close()
+	if size != 4 {
+		t.Errorf("expected a size of 4, got %d", size)
+	}
+	if data, err := ioutil.ReadAll(body); err != nil || string(data) != "data" {
+		t.Errorf("expected %q, got %q and error %v", "data", data, err)
+	}
+	if hits != 2 {
+		t.Errorf("expected 2 requests, got %d", hits)
+	}
+}
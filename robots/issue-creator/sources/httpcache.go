@@ -0,0 +1,278 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sources
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+)
+
+const (
+	cacheBodySuffix = ".body"
+	cacheMetaSuffix = ".meta"
+)
+
+// DiskCache is an http.RoundTripper that keeps the bodies of successful GET responses on disk and
+// revalidates them with If-None-Match and If-Modified-Since, so that an unchanged file costs a 304
+// instead of a full download. Only responses with an ETag or Last-Modified header are cached. The
+// least recently used entries are removed once the cache grows past its size limit.
+type DiskCache struct {
+	dir      string
+	maxBytes int64
+	next     http.RoundTripper
+
+	// lock serializes changes to the cache directory.
+	lock sync.Mutex
+}
+
+// cacheMeta is stored next to each cached body.
+type cacheMeta struct {
+	URL          string `json:"url"`
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+	ContentType  string `json:"content_type,omitempty"`
+}
+
+// NewDiskCache creates a DiskCache that stores up to maxBytes of response bodies in dir, and sends
+// requests with next, or http.DefaultTransport if next is nil. A maxBytes of 0 or less means no limit.
+func NewDiskCache(dir string, maxBytes int64, next http.RoundTripper) (*DiskCache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory '%s': %v", dir, err)
+	}
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &DiskCache{dir: dir, maxBytes: maxBytes, next: next}, nil
+}
+
+// RoundTrip implements http.RoundTripper.
+func (c *DiskCache) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet || req.Header.Get("Range") != "" {
+		return c.next.RoundTrip(req)
+	}
+	key := c.key(req.URL.String())
+	meta, cached := c.loadMeta(key)
+	if cached {
+		// Copy the request rather than changing the caller's headers.
+		req = req.Clone(req.Context())
+		if meta.ETag != "" {
+			req.Header.Set("If-None-Match", meta.ETag)
+		}
+		if meta.LastModified != "" {
+			req.Header.Set("If-Modified-Since", meta.LastModified)
+		}
+	}
+
+	resp, err := c.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	if cached && resp.StatusCode == http.StatusNotModified {
+		if hit, err := c.cachedResponse(req, key, meta); err == nil {
+			resp.Body.Close()
+			glog.V(2).Infof("Serving %s from the cache after revalidation.", meta.URL)
+			return hit, nil
+		}
+		// The body disappeared since the metadata was read, so the 304 can't be used.
+		glog.Warningf("Cached body of %s is missing, returning the 304 as is.", meta.URL)
+		return resp, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return resp, nil
+	}
+	meta = cacheMeta{
+		URL:          req.URL.String(),
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		ContentType:  resp.Header.Get("Content-Type"),
+	}
+	if meta.ETag == "" && meta.LastModified == "" {
+		return resp, nil
+	}
+	tmp, err := ioutil.TempFile(c.dir, "download-")
+	if err != nil {
+		glog.Warningf("Not caching %s: %v", meta.URL, err)
+		return resp, nil
+	}
+	resp.Body = &cachingBody{body: resp.Body, tmp: tmp, cache: c, key: key, meta: meta}
+	return resp, nil
+}
+
+// cachedResponse builds a 200 response for req from the cached body.
+func (c *DiskCache) cachedResponse(req *http.Request, key string, meta cacheMeta) (*http.Response, error) {
+	path := filepath.Join(c.dir, key+cacheBodySuffix)
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	// The modification time tracks the last use, so that eviction removes the least recently used.
+	now := time.Now()
+	os.Chtimes(path, now, now)
+
+	header := http.Header{}
+	header.Set(fromCacheHeader, "1")
+	header.Set("Content-Length", strconv.FormatInt(info.Size(), 10))
+	if meta.ETag != "" {
+		header.Set("ETag", meta.ETag)
+	}
+	if meta.LastModified != "" {
+		header.Set("Last-Modified", meta.LastModified)
+	}
+	if meta.ContentType != "" {
+		header.Set("Content-Type", meta.ContentType)
+	}
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          file,
+		ContentLength: info.Size(),
+		Request:       req,
+	}, nil
+}
+
+// key returns the file name prefix that the entry for a URL is stored under.
+func (c *DiskCache) key(url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return hex.EncodeToString(sum[:])
+}
+
+// loadMeta returns the metadata of the cached entry for key, if there is one.
+func (c *DiskCache) loadMeta(key string) (cacheMeta, bool) {
+	var meta cacheMeta
+	b, err := ioutil.ReadFile(filepath.Join(c.dir, key+cacheMetaSuffix))
+	if err != nil {
+		return meta, false
+	}
+	if err := json.Unmarshal(b, &meta); err != nil {
+		glog.Warningf("Ignoring corrupt cache entry %s: %v", key, err)
+		return meta, false
+	}
+	return meta, true
+}
+
+// store moves a completely downloaded body into the cache and evicts old entries if needed.
+func (c *DiskCache) store(tmpPath, key string, meta cacheMeta) error {
+	b, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if err := os.Rename(tmpPath, filepath.Join(c.dir, key+cacheBodySuffix)); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(filepath.Join(c.dir, key+cacheMetaSuffix), b, 0644); err != nil {
+		return err
+	}
+	return c.evict(key)
+}
+
+// evict removes the least recently used entries other than keep until the bodies fit in maxBytes.
+// c.lock must be held.
+func (c *DiskCache) evict(keep string) error {
+	if c.maxBytes <= 0 {
+		return nil
+	}
+	infos, err := ioutil.ReadDir(c.dir)
+	if err != nil {
+		return err
+	}
+	var bodies []os.FileInfo
+	var total int64
+	for _, info := range infos {
+		if !strings.HasSuffix(info.Name(), cacheBodySuffix) {
+			continue
+		}
+		total += info.Size()
+		if info.Name() != keep+cacheBodySuffix {
+			bodies = append(bodies, info)
+		}
+	}
+	sort.Slice(bodies, func(i, j int) bool { return bodies[i].ModTime().Before(bodies[j].ModTime()) })
+	for _, info := range bodies {
+		if total <= c.maxBytes {
+			break
+		}
+		key := strings.TrimSuffix(info.Name(), cacheBodySuffix)
+		os.Remove(filepath.Join(c.dir, key+cacheMetaSuffix))
+		if err := os.Remove(filepath.Join(c.dir, info.Name())); err != nil {
+			return err
+		}
+		total -= info.Size()
+	}
+	return nil
+}
+
+// cachingBody copies a response body to a temporary file as it is read, and adds the file to the
+// cache once the whole body has been read. Bodies that are closed early are not cached.
+type cachingBody struct {
+	body  io.ReadCloser
+	tmp   *os.File
+	cache *DiskCache
+	key   string
+	meta  cacheMeta
+	err   error
+	done  bool
+}
+
+func (b *cachingBody) Read(p []byte) (int, error) {
+	n, err := b.body.Read(p)
+	if n > 0 && b.err == nil {
+		_, b.err = b.tmp.Write(p[:n])
+	}
+	if err == io.EOF {
+		b.done = true
+	}
+	return n, err
+}
+
+func (b *cachingBody) Close() error {
+	err := b.body.Close()
+	b.tmp.Close()
+	if b.done && b.err == nil {
+		if storeErr := b.cache.store(b.tmp.Name(), b.key, b.meta); storeErr != nil {
+			glog.Warningf("Failed to cache %s: %v", b.meta.URL, storeErr)
+		} else {
+			return err
+		}
+	}
+	os.Remove(b.tmp.Name())
+	return err
+}
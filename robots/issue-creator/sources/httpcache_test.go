@@ -0,0 +1,138 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sources
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDiskCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "httpcache")
+	if err != nil {
+		t.Fatalf("failed to create a temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	body := "version 1"
+	etag := `"v1"`
+	full, notModified := 0, 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == etag {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		full++
+		w.Header().Set("ETag", etag)
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	cache, err := NewDiskCache(dir, 0, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	opts := HTTPOptions{Transport: cache}
+	fetch := func(expected string) {
+		data, err := ReadHTTPWithOptions(context.Background(), server.URL, opts)
+		if err != nil || string(data) != expected {
+			t.Errorf("expected %q, got %q and error %v", expected, data, err)
+		}
+	}
+
+	fetch("version 1")
+	fetch("version 1")
+	if full != 1 || notModified != 1 {
+		t.Errorf("expected 1 full download and 1 revalidation, got %d and %d", full, notModified)
+	}
+
+	body, etag = "version 2", `"v2"`
+	fetch("version 2")
+	fetch("version 2")
+	if full != 2 || notModified != 2 {
+		t.Errorf("expected 2 full downloads and 2 revalidations, got %d and %d", full, notModified)
+	}
+}
+
+func TestDiskCachePartialRead(t *testing.T) {
+	dir, err := ioutil.TempDir("", "httpcache")
+	if err != nil {
+		t.Fatalf("failed to create a temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Last-Modified", "Mon, 02 Jan 2006 15:04:05 GMT")
+		w.Write([]byte(strings.Repeat("x", 1024)))
+	}))
+	defer server.Close()
+
+	cache, err := NewDiskCache(dir, 0, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp, err := (&http.Client{Transport: cache}).Get(server.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp.Body.Read(make([]byte, 10))
+	resp.Body.Close()
+	if entries, _ := ioutil.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("expected a partially read body not to be cached, found %d files", len(entries))
+	}
+}
+
+func TestDiskCacheEviction(t *testing.T) {
+	dir, err := ioutil.TempDir("", "httpcache")
+	if err != nil {
+		t.Fatalf("failed to create a temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"`+r.URL.Path+`"`)
+		w.Write([]byte(strings.Repeat("x", 100)))
+	}))
+	defer server.Close()
+
+	cache, err := NewDiskCache(dir, 250, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, path := range []string{"/a", "/b", "/c"} {
+		if _, err := ReadHTTPWithOptions(context.Background(), server.URL+path, HTTPOptions{Transport: cache}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	bodies, err := filepath.Glob(filepath.Join(dir, "*"+cacheBodySuffix))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(bodies) != 2 {
+		t.Errorf("expected 2 cached bodies within the size limit, got %d", len(bodies))
+	}
+	if _, cached := cache.loadMeta(cache.key(server.URL + "/c")); !cached {
+		t.Error("expected the most recent download to stay cached")
+	}
+}
@@ -0,0 +1,92 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sources
+
+import (
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strings"
+)
+
+// infraFlakeLabel marks clusters whose failures come from the test infrastructure rather than the
+// product.
+const infraFlakeLabel = "kind/infra-flake"
+
+// defaultInfraPatterns match the failure text of common infra flakes: clusters or VMs that are not
+// provisioned in time, image pulls that fail, and exhausted quota.
+var defaultInfraPatterns = []string{
+	`(?i)timed? ?out waiting for .*(cluster|nodes?|instances?|machines?) to`,
+	`(?i)failed to (create|provision|bring up|acquire) .*(cluster|instance|project|boskos)`,
+	`(?i)(error pulling image|ErrImagePull|ImagePullBackOff|docker pull .* failed|toomanyrequests)`,
+	`(?i)(quota .*exceeded|exceeded .*quota|QUOTA_EXCEEDED)`,
+	`(?i)no space left on device`,
+}
+
+// loadInfraPatterns compiles the infra flake regexes in the file at path, one per line. Blank lines
+// and lines starting with '#' are skipped. If path is empty the default patterns are used.
+func loadInfraPatterns(path string) ([]*regexp.Regexp, error) {
+	patterns := defaultInfraPatterns
+	if path != "" {
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the infra flake patterns: %v", err)
+		}
+		patterns = nil
+		for _, line := range strings.Split(string(b), "\n") {
+			if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+				patterns = append(patterns, line)
+			}
+		}
+	}
+	regexps := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid infra flake pattern '%s': %v", pattern, err)
+		}
+		regexps = append(regexps, re)
+	}
+	return regexps, nil
+}
+
+// infraMatch returns the infra flake pattern that the cluster's failure text matches, or "" if
+// the cluster is a product flake or classification is off.
+func (c *Cluster) infraMatch() string {
+	for _, re := range c.filer.infraPatterns {
+		if re.MatchString(c.Text) {
+			return re.String()
+		}
+	}
+	return ""
+}
+
+// infraOncall splits '--triage-infra-oncall' into the users to assign and the teams to mention.
+func (f *TriageFiler) infraOncall() (assign, mention []string) {
+	for _, name := range strings.Split(f.infraOncallNames, ",") {
+		if name = strings.TrimPrefix(strings.TrimSpace(name), "@"); name == "" {
+			continue
+		}
+		// Teams can't be assigned, only mentioned.
+		if strings.Contains(name, "/") {
+			mention = append(mention, name)
+		} else {
+			assign = append(assign, name)
+		}
+	}
+	return assign, mention
+}
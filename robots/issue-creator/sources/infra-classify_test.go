@@ -0,0 +1,120 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sources
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"k8s.io/test-infra/robots/issue-creator/testowner"
+)
+
+func TestDefaultInfraPatterns(t *testing.T) {
+	patterns, err := loadInfraPatterns("")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	f := &TriageFiler{infraPatterns: patterns}
+	for text, infra := range map[string]bool{
+		"Timed out waiting for cluster to come up":                        true,
+		"failed to acquire a project from boskos":                         true,
+		"Failed to pull image \"gcr.io/foo\": rpc error: ErrImagePull":    true,
+		"Quota 'CPUS' exceeded. Limit: 500.0 in region us-central1.":      true,
+		"write /var/log/foo: no space left on device":                     true,
+		"Expected <int>: 1 to equal <int>: 2":                             false,
+		"timed out waiting for the condition on pods/nginx to be running": false,
+	} {
+		clust := &Cluster{filer: f, Text: text}
+		if matched := clust.infraMatch() != ""; matched != infra {
+			t.Errorf("Expected %q to be classified as an infra flake: %t, got %t", text, infra, matched)
+		}
+	}
+}
+
+func TestLoadInfraPatterns(t *testing.T) {
+	dir, err := ioutil.TempDir("", "infra")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "patterns")
+	if err := ioutil.WriteFile(path, []byte("# Infra flakes\n\nnetwork is unreachable\n"), 0644); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	patterns, err := loadInfraPatterns(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(patterns) != 1 || patterns[0].String() != "network is unreachable" {
+		t.Errorf("Expected only the pattern in the file, got %v", patterns)
+	}
+
+	if err := ioutil.WriteFile(path, []byte("(unclosed\n"), 0644); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := loadInfraPatterns(path); err == nil {
+		t.Error("Expected an error for an invalid pattern.")
+	}
+	if _, err := loadInfraPatterns(filepath.Join(dir, "missing")); err == nil {
+		t.Error("Expected an error for a missing file.")
+	}
+}
+
+func TestTFInfraFlakes(t *testing.T) {
+	f := NewTestTriageFiler()
+	f.infraOncallNames = "oncall-user, @kubernetes/test-infra-oncall"
+	owners, err := testowner.NewOwnerListFromCsv(bytes.NewReader(sampleOwnerCSV))
+	if err != nil {
+		t.Fatalf("Failed to create a new OwnerList.  errmsg: %v\n", err)
+	}
+	f.creator.Owners = owners
+	f.creator.MaxSIGCount = 3
+	f.creator.MaxAssignees = 3
+	clusters, err := f.loadClusters(json1issue2job2test)
+	if err != nil {
+		t.Fatalf("Error parsing triage data: %v\n", err)
+	}
+	clust := clusters[0]
+
+	// Product flakes go to the test owners.
+	if body := clust.Body(nil); !strings.Contains(body, "/assign @cjwagner @spxtr") || strings.Contains(body, "infra flake") {
+		t.Errorf("Expected a product flake to be assigned to the test owners, got:\n%s", body)
+	}
+
+	f.infraPatterns, _ = loadInfraPatterns("")
+	clust.Text = "Timed out waiting for nodes to be ready"
+	body := clust.Body(nil)
+	for _, expected := range []string{"This looks like an infra flake", "/assign @oncall-user\n", "cc @kubernetes/test-infra-oncall\n"} {
+		if !strings.Contains(body, expected) {
+			t.Errorf("Expected the body of an infra flake to contain %q, got:\n%s", expected, body)
+		}
+	}
+	if strings.Contains(body, "@cjwagner") {
+		t.Errorf("Expected the test owners not to be assigned an infra flake, got:\n%s", body)
+	}
+	if labels := clust.Labels(); !reflect.DeepEqual(labels, []string{"kind/flake", infraFlakeLabel}) {
+		t.Errorf("Expected only the flake labels on an infra flake, got %v", labels)
+	}
+	if sigs := clust.sigs(); len(sigs) != 0 {
+		t.Errorf("Expected an infra flake to belong to no SIG, got %v", sigs)
+	}
+}
@@ -0,0 +1,67 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sources
+
+import (
+	"context"
+	"fmt"
+)
+
+// jobNameConfig maps job names to the names to show people. It also reads the dashboards of a
+// testgrid config, where each job gets the name of the first tab that shows it. Names listed
+// explicitly win over those from tabs.
+type jobNameConfig struct {
+	Names map[string]string `json:"names"`
+	testgridDashboards
+}
+
+// loadJobNames reads the display name of each job from the YAML at url.
+func (f *TriageFiler) loadJobNames(ctx context.Context, url string) (map[string]string, error) {
+	var config jobNameConfig
+	if err := f.readYAML(ctx, url, "job names", &config); err != nil {
+		return nil, err
+	}
+	names := map[string]string{}
+	for _, dashboard := range config.Dashboards {
+		for _, tab := range dashboard.Tabs {
+			if _, ok := names[tab.TestGroupName]; !ok && tab.Name != "" {
+				names[tab.TestGroupName] = tab.Name
+			}
+		}
+	}
+	for job, name := range config.Names {
+		names[job] = name
+	}
+	return names, nil
+}
+
+// jobDisplayName returns the name to show people for a job, which is the job name if it has none.
+func (f *TriageFiler) jobDisplayName(job string) string {
+	if name := f.jobNames[job]; name != "" {
+		return name
+	}
+	return job
+}
+
+// jobLabel returns the display name of a job for tables, followed by the job name if they differ,
+// so that the job can still be searched for.
+func (f *TriageFiler) jobLabel(job string) string {
+	if name := f.jobDisplayName(job); name != job {
+		return fmt.Sprintf("%s (`%s`)", name, job)
+	}
+	return job
+}
@@ -0,0 +1,69 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sources
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadJobNames(t *testing.T) {
+	f := NewTestTriageFiler()
+	f.Fetcher = mapFetcher{
+		"names.yaml": `{
+			"names": {"ci-b": "b override"},
+			"dashboards": [
+				{"name": "sig-release-master-blocking", "dashboard_tab": [{"name": "gce-serial", "test_group_name": "ci-a"}, {"name": "b", "test_group_name": "ci-b"}]},
+				{"name": "sig-node", "dashboard_tab": [{"name": "other name", "test_group_name": "ci-a"}]}
+			]
+		}`,
+	}
+	names, err := f.loadJobNames(context.Background(), "names.yaml")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := map[string]string{"ci-a": "gce-serial", "ci-b": "b override"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("Expected names %v, got %v", expected, names)
+	}
+	if _, err := f.loadJobNames(context.Background(), "missing.yaml"); err == nil {
+		t.Error("Expected an error when the job names can't be read.")
+	}
+}
+
+func TestTFJobNames(t *testing.T) {
+	f := NewTestTriageFiler()
+	clusters, err := f.loadClusters(json1issue2job2test)
+	if err != nil {
+		t.Fatalf("Error parsing triage data: %v", err)
+	}
+	clust := clusters[0]
+	if title := clust.Title(); strings.Contains(title, " in ") {
+		t.Errorf("Expected no job in the title without display names, got %q", title)
+	}
+
+	f.jobNames = map[string]string{"jobname1": "friendly one"}
+	if title := clust.Title(); !strings.HasPrefix(title, "Failure cluster [key_ha...] in friendly one failed 4 builds") {
+		t.Errorf("Expected the title to name the top job, got %q", title)
+	}
+	body := clust.Body(nil)
+	if !strings.Contains(body, "| friendly one (`jobname1`) | 3 |") || !strings.Contains(body, "| jobname2 | 1 |") {
+		t.Errorf("Expected the job table to show display names, got:\n%s", body)
+	}
+}
@@ -0,0 +1,155 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sources
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// Job tiers, from the most to the least important.
+const (
+	tierReleaseBlocking = "release-blocking"
+	tierMergeBlocking   = "merge-blocking"
+	tierInforming       = "informing"
+)
+
+// tierRank orders the tiers, lower is more important.
+var tierRank = map[string]int{tierReleaseBlocking: 1, tierMergeBlocking: 2, tierInforming: 3}
+
+// tierPriority is the priority of clusters that fail a job of each tier.
+var tierPriority = map[string]string{tierReleaseBlocking: "critical-urgent", tierMergeBlocking: "important-soon"}
+
+// testgridDashboards is the part of a testgrid config that lists the jobs on each dashboard.
+type testgridDashboards struct {
+	Dashboards []struct {
+		Name string `json:"name"`
+		Tabs []struct {
+			// Name is the display name of the tab, and TestGroupName is the job shown on it.
+			Name          string `json:"name"`
+			TestGroupName string `json:"test_group_name"`
+		} `json:"dashboard_tab"`
+	} `json:"dashboards"`
+}
+
+// jobTierConfig lists the jobs of each tier. It also reads the dashboards of a testgrid config,
+// where the jobs on dashboards named like sig-release-master-blocking are release-blocking, those
+// on presubmits-kubernetes-blocking are merge-blocking, and those on *-informing are informing.
+type jobTierConfig struct {
+	ReleaseBlocking []string `json:"release-blocking"`
+	MergeBlocking   []string `json:"merge-blocking"`
+	Informing       []string `json:"informing"`
+	testgridDashboards
+}
+
+// dashboardTier returns the tier of the jobs on a testgrid dashboard, or "".
+func dashboardTier(name string) string {
+	switch {
+	case strings.HasSuffix(name, "-blocking") && strings.HasPrefix(name, "presubmits-"):
+		return tierMergeBlocking
+	case strings.HasSuffix(name, "-blocking"):
+		return tierReleaseBlocking
+	case strings.HasSuffix(name, "-informing"):
+		return tierInforming
+	default:
+		return ""
+	}
+}
+
+// readYAML decodes the YAML at url into out. what names the data in errors.
+func (f *TriageFiler) readYAML(ctx context.Context, url, what string, out interface{}) error {
+	data, _, err := f.Fetcher.Open(ctx, url)
+	if err != nil {
+		return fmt.Errorf("failed to open the %s: %v", what, err)
+	}
+	defer data.Close()
+	b, err := ioutil.ReadAll(data)
+	if err != nil {
+		return fmt.Errorf("failed to read the %s: %v", what, err)
+	}
+	if err := yaml.Unmarshal(b, out); err != nil {
+		return fmt.Errorf("failed to parse the %s: %v", what, err)
+	}
+	return nil
+}
+
+// loadJobTiers reads the tier of each job from the YAML at url. A job in several tiers gets the
+// most important one.
+func (f *TriageFiler) loadJobTiers(ctx context.Context, url string) (map[string]string, error) {
+	var config jobTierConfig
+	if err := f.readYAML(ctx, url, "job tiers", &config); err != nil {
+		return nil, err
+	}
+
+	tiers := map[string]string{}
+	add := func(job, tier string) {
+		if current, ok := tiers[job]; !ok || tierRank[tier] < tierRank[current] {
+			tiers[job] = tier
+		}
+	}
+	for tier, jobs := range map[string][]string{
+		tierReleaseBlocking: config.ReleaseBlocking,
+		tierMergeBlocking:   config.MergeBlocking,
+		tierInforming:       config.Informing,
+	} {
+		for _, job := range jobs {
+			add(job, tier)
+		}
+	}
+	for _, dashboard := range config.Dashboards {
+		if tier := dashboardTier(dashboard.Name); tier != "" {
+			for _, tab := range dashboard.Tabs {
+				add(tab.TestGroupName, tier)
+			}
+		}
+	}
+	return tiers, nil
+}
+
+// tier returns the most important tier of the jobs the cluster failed, or "".
+func (c *Cluster) tier() string {
+	best := ""
+	for job := range c.jobs {
+		if tier := c.filer.jobTiers[job]; tier != "" && (best == "" || tierRank[tier] < tierRank[best]) {
+			best = tier
+		}
+	}
+	return best
+}
+
+// blockingJobs returns the release and merge blocking jobs that the cluster failed, most important
+// tier first and then by name.
+func (c *Cluster) blockingJobs() []string {
+	var jobs []string
+	for job := range c.jobs {
+		if tier := c.filer.jobTiers[job]; tier == tierReleaseBlocking || tier == tierMergeBlocking {
+			jobs = append(jobs, job)
+		}
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		if ri, rj := tierRank[c.filer.jobTiers[jobs[i]]], tierRank[c.filer.jobTiers[jobs[j]]]; ri != rj {
+			return ri < rj
+		}
+		return jobs[i] < jobs[j]
+	})
+	return jobs
+}
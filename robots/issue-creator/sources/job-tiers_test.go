@@ -0,0 +1,82 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sources
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadJobTiers(t *testing.T) {
+	f := NewTestTriageFiler()
+	f.Fetcher = mapFetcher{
+		"tiers.yaml": `{"release-blocking": ["ci-a"], "informing": ["ci-a", "ci-b"]}`,
+		"testgrid.yaml": `{"dashboards": [
+			{"name": "sig-release-master-blocking", "dashboard_tab": [{"test_group_name": "ci-a"}]},
+			{"name": "presubmits-kubernetes-blocking", "dashboard_tab": [{"test_group_name": "pull-a"}]},
+			{"name": "sig-release-master-informing", "dashboard_tab": [{"test_group_name": "ci-b"}, {"test_group_name": "ci-a"}]},
+			{"name": "sig-node-kubelet", "dashboard_tab": [{"test_group_name": "ci-c"}]}
+		]}`,
+		"bad.yaml": `[`,
+	}
+	expected := map[string]string{"ci-a": tierReleaseBlocking, "ci-b": tierInforming}
+	if tiers, err := f.loadJobTiers(context.Background(), "tiers.yaml"); err != nil || !reflect.DeepEqual(tiers, expected) {
+		t.Errorf("Expected tiers %v, got %v (%v)", expected, tiers, err)
+	}
+	expected["pull-a"] = tierMergeBlocking
+	if tiers, err := f.loadJobTiers(context.Background(), "testgrid.yaml"); err != nil || !reflect.DeepEqual(tiers, expected) {
+		t.Errorf("Expected tiers %v from the testgrid dashboards, got %v (%v)", expected, tiers, err)
+	}
+	if _, err := f.loadJobTiers(context.Background(), "bad.yaml"); err == nil {
+		t.Error("Expected an error for invalid YAML.")
+	}
+}
+
+func TestTFJobTiers(t *testing.T) {
+	f := NewTestTriageFiler()
+	f.dataURL = "https://example.com/failure_data.json"
+	f.jobTiersURL = "https://example.com/tiers.yaml"
+	f.Fetcher = mapFetcher{
+		f.dataURL:     string(json1issue2job2test),
+		f.jobTiersURL: `{"merge-blocking": ["jobname2"], "informing": ["jobname1"]}`,
+	}
+	f.tracker = &fakeTracker{}
+
+	issues, err := f.Issues(context.Background(), f.creator)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	clust := issues[0].(*Cluster)
+	if labels := clust.Labels(); !reflect.DeepEqual(labels, []string{"kind/flake", "tier/" + tierMergeBlocking}) {
+		t.Errorf("Expected the cluster to be labeled with the tier of its most important job, got %v", labels)
+	}
+	if prio, ok := clust.Priority(); !ok || prio != "important-soon" {
+		t.Errorf("Expected a merge-blocking cluster to be important-soon, got %q (%t)", prio, ok)
+	}
+	body := clust.Body(nil)
+	if !strings.Contains(body, "##### Blocking jobs affected:\n- jobname2 ("+tierMergeBlocking+"): ") || strings.Contains(body, "- jobname1 (") {
+		t.Errorf("Expected the body to list only the blocking jobs, got:\n%s", body)
+	}
+
+	// Informing jobs alone don't raise the priority.
+	f.jobTiers = map[string]string{"jobname1": tierInforming}
+	if prio, ok := clust.Priority(); ok {
+		t.Errorf("Expected no priority for an informing cluster, got %q", prio)
+	}
+}
@@ -0,0 +1,152 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sources
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	// maxJUnitFiles is the most junit_NN.xml files read from a build's artifacts. Parallel e2e
+	// runs write one file per node, numbered from junit_01.xml.
+	maxJUnitFiles = 30
+	// maxExcerptLines is the most lines of a failure message shown in an issue.
+	maxExcerptLines = 20
+)
+
+// junitExcerpt is the failure message of one of a cluster's tests in the latest failed build of a job.
+type junitExcerpt struct {
+	job   string
+	build int
+	test  string
+	text  string
+}
+
+// junitTestCase is a testcase element of a junit XML file.
+type junitTestCase struct {
+	Name    string `xml:"name,attr"`
+	Failure *struct {
+		Message string `xml:"message,attr"`
+		Text    string `xml:",chardata"`
+	} `xml:"failure"`
+}
+
+// junitFailures returns the failure text of each failed test case in a junit XML file. It reads
+// both <testsuites> and bare <testsuite> documents.
+func junitFailures(r io.Reader) (map[string]string, error) {
+	failures := map[string]string{}
+	decoder := xml.NewDecoder(r)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return failures, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != "testcase" {
+			continue
+		}
+		var testCase junitTestCase
+		if err := decoder.DecodeElement(&testCase, &start); err != nil {
+			return nil, err
+		}
+		if testCase.Failure == nil {
+			continue
+		}
+		text := strings.TrimSpace(testCase.Failure.Text)
+		if text == "" {
+			text = strings.TrimSpace(testCase.Failure.Message)
+		}
+		failures[testCase.Name] = text
+	}
+}
+
+// buildFailures reads the failures from the junit files of a build's artifacts. Files are read in
+// order until one is missing. The first file must exist, and failing to read any file is an error.
+func (f *TriageFiler) buildFailures(ctx context.Context, job string, build int) (map[string]string, error) {
+	path, ok := f.data.Builds.JobPaths[job]
+	if !ok {
+		return nil, fmt.Errorf("no path for job '%s'", job)
+	}
+	failures := map[string]string{}
+	for i := 1; i <= maxJUnitFiles; i++ {
+		url := fmt.Sprintf("%s/%d/artifacts/junit_%02d.xml", strings.TrimSuffix(path, "/"), build, i)
+		r, _, err := f.Fetcher.Open(ctx, url)
+		if err != nil {
+			if i > 1 && IsNotFound(err) {
+				break
+			}
+			return nil, err
+		}
+		fileFailures, err := junitFailures(r)
+		r.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode '%s': %v", url, err)
+		}
+		for test, text := range fileFailures {
+			failures[test] = text
+		}
+	}
+	return failures, nil
+}
+
+// loadJUnitExcerpts finds the failure message of each cluster's tests in the latest failed build
+// of each of its top jobs. Builds whose artifacts can't be read are skipped.
+func (f *TriageFiler) loadJUnitExcerpts(ctx context.Context, clusters []*Cluster) {
+	for _, clust := range clusters {
+		clust.excerpts = nil
+		for _, job := range clust.topJobsFailed(topJobsCount) {
+			build, _ := clust.latestBuild(job)
+			failures, err := f.buildFailures(ctx, job.Name, build)
+			if err != nil {
+				f.log(clust.ID()).WithJob(job.Name, build).Warningf("Failed to read the junit artifacts: %v", err)
+				continue
+			}
+			for _, test := range clust.topTestsFailed(len(clust.Tests)) {
+				if text, ok := failures[test.Name]; ok {
+					clust.excerpts = append(clust.excerpts, &junitExcerpt{job: job.Name, build: build, test: test.Name, text: text})
+					break
+				}
+			}
+		}
+	}
+}
+
+// excerptsMarkdown renders the cluster's junit excerpts as collapsible blocks, or returns "".
+func (c *Cluster) excerptsMarkdown() string {
+	if len(c.excerpts) == 0 {
+		return ""
+	}
+	var buf bytes.Buffer
+	fmt.Fprint(&buf, "\n##### Failures in the latest failed builds:\n")
+	for _, excerpt := range c.excerpts {
+		lines := strings.Split(excerpt.text, "\n")
+		if len(lines) > maxExcerptLines {
+			lines = append(lines[:maxExcerptLines], "...")
+		}
+		fmt.Fprintf(&buf, "<details><summary>%s in %s build %d</summary>\n\n```\n%s\n```\n</details>\n",
+			excerpt.test, excerpt.job, excerpt.build, strings.Join(lines, "\n"))
+	}
+	return buf.String()
+}
@@ -0,0 +1,136 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sources
+
+import (
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestJUnitFailures(t *testing.T) {
+	tests := []struct {
+		name     string
+		xml      string
+		expected map[string]string
+	}{
+		{
+			name: "testsuites",
+			xml: `<testsuites><testsuite name="e2e">
+				<testcase name="passes"></testcase>
+				<testcase name="fails"><failure message="short">  stack trace  </failure></testcase>
+			</testsuite></testsuites>`,
+			expected: map[string]string{"fails": "stack trace"},
+		},
+		{
+			name:     "bare testsuite with only a message",
+			xml:      `<testsuite><testcase name="fails"><failure message="expected 1 to equal 2"></failure></testcase></testsuite>`,
+			expected: map[string]string{"fails": "expected 1 to equal 2"},
+		},
+	}
+	for _, test := range tests {
+		failures, err := junitFailures(strings.NewReader(test.xml))
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", test.name, err)
+		}
+		if !reflect.DeepEqual(failures, test.expected) {
+			t.Errorf("%s: expected %v, got %v", test.name, test.expected, failures)
+		}
+	}
+	if _, err := junitFailures(strings.NewReader("<testsuite><testcase")); err == nil {
+		t.Error("Expected an error for truncated XML.")
+	}
+}
+
+func TestTFJUnitExcerpts(t *testing.T) {
+	f := NewTestTriageFiler()
+	clusters, err := f.loadClusters(culpritTriageData("FAILURE", "SUCCESS", "SUCCESS", "SUCCESS", "FAILURE", "FAILURE"))
+	if err != nil {
+		t.Fatalf("Error parsing triage data: %v", err)
+	}
+	f.Fetcher = mapFetcher{
+		"gs://bucket/logs/ci-job/15/artifacts/junit_01.xml": `<testsuite><testcase name="other"><failure>unrelated</failure></testcase></testsuite>`,
+		"gs://bucket/logs/ci-job/15/artifacts/junit_02.xml": `<testsuite><testcase name="test1"><failure>line 1` + strings.Repeat("\nline", maxExcerptLines) + `</failure></testcase></testsuite>`,
+	}
+	clust := clusters[0]
+	f.loadJUnitExcerpts(context.Background(), clusters)
+	if len(clust.excerpts) != 1 {
+		t.Fatalf("Expected an excerpt for the latest failed build, got %d", len(clust.excerpts))
+	}
+	body := clust.Body(nil)
+	for _, expected := range []string{
+		"<details><summary>test1 in ci-job build 15</summary>\n\n```\nline 1\n",
+		"\nline\n...\n```\n</details>\n",
+	} {
+		if !strings.Contains(body, expected) {
+			t.Errorf("Expected the body to contain %q, got:\n%s", expected, body)
+		}
+	}
+	if strings.Contains(body, "unrelated") {
+		t.Errorf("Expected only failures of the cluster's tests in the body, got:\n%s", body)
+	}
+
+	// Builds without junit artifacts are skipped.
+	f.Fetcher = mapFetcher{}
+	f.loadJUnitExcerpts(context.Background(), clusters)
+	if len(clust.excerpts) != 0 {
+		t.Errorf("Expected no excerpts without artifacts, got %d", len(clust.excerpts))
+	}
+}
+
+// failingFetcher is a mapFetcher that fails to read one URL.
+type failingFetcher struct {
+	mapFetcher
+	fail string
+}
+
+func (f failingFetcher) Open(ctx context.Context, url string) (io.ReadCloser, int64, error) {
+	if url == f.fail {
+		return nil, 0, errors.New("connection reset")
+	}
+	return f.mapFetcher.Open(ctx, url)
+}
+
+func TestTFBuildFailures(t *testing.T) {
+	f := NewTestTriageFiler()
+	if _, err := f.loadClusters(culpritTriageData("FAILURE", "SUCCESS", "SUCCESS", "SUCCESS", "FAILURE", "FAILURE")); err != nil {
+		t.Fatalf("Error parsing triage data: %v", err)
+	}
+	files := mapFetcher{
+		"gs://bucket/logs/ci-job/15/artifacts/junit_01.xml": `<testsuite><testcase name="test1"><failure>first</failure></testcase></testsuite>`,
+		"gs://bucket/logs/ci-job/15/artifacts/junit_02.xml": `<testsuite><testcase name="test2"><failure>second</failure></testcase></testsuite>`,
+	}
+
+	// Files are read until one is missing.
+	f.Fetcher = files
+	failures, err := f.buildFailures(context.Background(), "ci-job", 15)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if expected := map[string]string{"test1": "first", "test2": "second"}; !reflect.DeepEqual(failures, expected) {
+		t.Errorf("Expected failures %v, got %v", expected, failures)
+	}
+
+	// A file that can't be read isn't mistaken for the end of the files.
+	f.Fetcher = failingFetcher{mapFetcher: files, fail: "gs://bucket/logs/ci-job/15/artifacts/junit_02.xml"}
+	if _, err := f.buildFailures(context.Background(), "ci-job", 15); err == nil || !strings.Contains(err.Error(), "connection reset") {
+		t.Errorf("Expected the error reading junit_02.xml, got %v", err)
+	}
+}
@@ -0,0 +1,121 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sources
+
+import (
+	"bytes"
+	"fmt"
+
+	githubapi "github.com/google/go-github/github"
+	"k8s.io/test-infra/robots/issue-creator/testowner"
+)
+
+const (
+	// ownershipGapsID identifies the ownership gaps issue.
+	// DO NOT CHANGE or duplicate issues may be created on github.
+	ownershipGapsID = "Triage ownership gaps report"
+	// maxGapTests is the maximum number of tests listed in each section of the issue body.
+	maxGapTests = 50
+)
+
+// ownershipGapsIssue reports the failing tests in the triage data that have no owner or SIG.
+type ownershipGapsIssue struct {
+	filer *TriageFiler
+	gaps  *testowner.OwnershipGaps
+}
+
+// ownershipGapsIssue collects the tests from every cluster and returns an issue describing those
+// without an owner or SIG, or nil if there are no gaps.
+func (f *TriageFiler) ownershipGapsIssue(clusters []*Cluster) *ownershipGapsIssue {
+	var testNames []string
+	for _, clust := range clusters {
+		for _, test := range clust.Tests {
+			testNames = append(testNames, test.Name)
+		}
+	}
+	gaps := f.creator.OwnershipGaps(testNames)
+	if gaps == nil || gaps.Empty() {
+		return nil
+	}
+	return &ownershipGapsIssue{filer: f, gaps: gaps}
+}
+
+// Title is the string to use as the github issue title.
+func (g *ownershipGapsIssue) Title() string {
+	return fmt.Sprintf("Ownership gaps: %d failing tests have no owner, %d have no SIG, and %d need a new owner over %d days",
+		len(g.gaps.NoOwner),
+		len(g.gaps.NoSIG),
+		len(g.gaps.Expired),
+		g.filer.windowDays,
+	)
+}
+
+// Body returns the body text of the github issue. No issue is created if one was closed within
+// the current window.
+func (g *ownershipGapsIssue) Body(closedIssues []*githubapi.Issue) string {
+	cutoffTime := g.filer.windowTime(g.filer.latestStart).AddDate(0, 0, -g.filer.windowDays)
+	for _, closed := range closedIssues {
+		if closed.ClosedAt.After(cutoffTime) {
+			return ""
+		}
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "### %s\n", ownershipGapsID)
+	fmt.Fprintf(&buf, "The following tests failed between '%s' and '%s' but could not be routed using the test owners data.\n",
+		cutoffTime.Format(timeFormat),
+		g.filer.windowTime(g.filer.latestStart).Format(timeFormat))
+	writeGapSection(&buf, "Tests without an owner", g.gaps.NoOwner)
+	writeGapSection(&buf, "Tests without a SIG", g.gaps.NoSIG)
+	writeGapSection(&buf, "Tests whose owner assignment has expired", g.gaps.Expired)
+	return buf.String()
+}
+
+// writeGapSection renders one list of tests, truncated to maxGapTests entries.
+func writeGapSection(buf *bytes.Buffer, heading string, tests []string) {
+	if len(tests) == 0 {
+		return
+	}
+	fmt.Fprintf(buf, "\n##### %s (%d):\n", heading, len(tests))
+	for i, test := range tests {
+		if i == maxGapTests {
+			fmt.Fprintf(buf, "- ...and %d more\n", len(tests)-maxGapTests)
+			break
+		}
+		fmt.Fprintf(buf, "- %s\n", test)
+	}
+}
+
+// ID yields the string identifier that uniquely identifies this issue.
+func (g *ownershipGapsIssue) ID() string {
+	return ownershipGapsID
+}
+
+// Labels returns the labels to apply to the issue on github.
+func (g *ownershipGapsIssue) Labels() []string {
+	return []string{"kind/cleanup"}
+}
+
+// Owners returns the list of usernames to assign to this issue on github.
+func (g *ownershipGapsIssue) Owners() []string {
+	return nil
+}
+
+// Priority calculates and returns the priority of this issue.
+func (g *ownershipGapsIssue) Priority() (string, bool) {
+	return "", false
+}
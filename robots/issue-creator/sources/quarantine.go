@@ -0,0 +1,65 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sources
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"strings"
+)
+
+const (
+	// flakyTag marks tests that are already known to flake. They are quarantined wherever they run.
+	flakyTag = "[Flaky]"
+	// quarantinedPriority is the priority of clusters whose tests are all quarantined, when
+	// demoting them is enabled.
+	quarantinedPriority = "backlog"
+)
+
+// loadQuarantine reads the list of quarantined tests at url, one test name per line. Blank lines
+// and lines starting with '#' are skipped.
+func (f *TriageFiler) loadQuarantine(ctx context.Context, url string) (map[string]bool, error) {
+	data, _, err := f.Fetcher.Open(ctx, url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open the quarantine list: %v", err)
+	}
+	defer data.Close()
+	quarantined := map[string]bool{}
+	scanner := bufio.NewScanner(data)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" && !strings.HasPrefix(line, "#") {
+			quarantined[line] = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read the quarantine list: %v", err)
+	}
+	return quarantined, nil
+}
+
+// quarantinedTests returns the names of the cluster's tests that are on the quarantine list or
+// marked [Flaky], in the order of topTestsFailed.
+func (c *Cluster) quarantinedTests() []string {
+	var names []string
+	for _, test := range c.topTestsFailed(len(c.Tests)) {
+		if c.filer.quarantine[test.Name] || strings.Contains(test.Name, flakyTag) {
+			names = append(names, test.Name)
+		}
+	}
+	return names
+}
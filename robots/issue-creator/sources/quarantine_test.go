@@ -0,0 +1,61 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sources
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestTFQuarantine(t *testing.T) {
+	f := NewTestTriageFiler()
+	f.dataURL = "https://example.com/failure_data.json"
+	f.quarantineURL = "https://example.com/quarantine.txt"
+	f.Fetcher = mapFetcher{
+		f.dataURL:       string(json1issue2job2test),
+		f.quarantineURL: "# Skipped while #123 is fixed.\n\ntestname2\n",
+	}
+	f.tracker = &fakeTracker{}
+
+	issues, err := f.Issues(context.Background(), f.creator)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	clust := issues[0].(*Cluster)
+	if quarantined := clust.quarantinedTests(); len(quarantined) != 1 || quarantined[0] != "testname2" {
+		t.Errorf("Expected only testname2 to be quarantined, got %v", quarantined)
+	}
+	if body := clust.Body(nil); !strings.Contains(body, "1 of the failing tests are skipped or marked [Flaky]") || !strings.Contains(body, "- testname2\n") {
+		t.Errorf("Expected the body to list the quarantined test, got:\n%s", body)
+	}
+
+	// The cluster is only demoted once all of its tests are quarantined.
+	f.quarantineDemote = true
+	if prio, ok := clust.Priority(); ok {
+		t.Errorf("Expected no priority while testname1 still blocks merges, got %q", prio)
+	}
+	clust.Tests[0].Name = "testname1 " + flakyTag
+	if prio, ok := clust.Priority(); !ok || prio != quarantinedPriority {
+		t.Errorf("Expected priority %q once every test is quarantined, got %q (%t)", quarantinedPriority, prio, ok)
+	}
+
+	f.quarantineURL = "https://example.com/missing.txt"
+	if _, err := f.Issues(context.Background(), f.creator); err == nil {
+		t.Error("Expected an error when the quarantine list can't be read.")
+	}
+}
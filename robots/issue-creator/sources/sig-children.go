@@ -0,0 +1,174 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sources
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	githubapi "github.com/google/go-github/github"
+	"k8s.io/test-infra/robots/issue-creator/creator"
+)
+
+// sigChildIssue is the part of a cluster that spans several SIGs that one SIG owns. It is filed
+// next to the issue for the whole cluster so that each SIG gets an issue scoped to its tests.
+type sigChildIssue struct {
+	cluster *Cluster
+	sig     string
+	// tests are the names of the cluster's tests that the SIG owns.
+	tests []string
+}
+
+// sigChildren returns one child issue for each SIG that owns tests in the cluster, ordered by SIG,
+// or nil if the cluster's tests belong to fewer than two SIGs.
+func (c *Cluster) sigChildren() []*sigChildIssue {
+	if c.infraMatch() != "" {
+		return nil
+	}
+	testNames := make([]string, 0, len(c.Tests))
+	for _, test := range c.topTestsFailed(len(c.Tests)) {
+		testNames = append(testNames, test.Name)
+	}
+	sigs := c.filer.creator.TestsSIGs(testNames)
+	if len(sigs) < 2 {
+		return nil
+	}
+	children := make([]*sigChildIssue, 0, len(sigs))
+	for sig, tests := range sigs {
+		children = append(children, &sigChildIssue{cluster: c, sig: sig, tests: tests})
+	}
+	sort.Slice(children, func(i, j int) bool { return children[i].sig < children[j].sig })
+	return children
+}
+
+// childLinks describes the child issues of the cluster for the body of its issue, or returns ""
+// if it has none.
+func (c *Cluster) childLinks() string {
+	if !c.filer.sigChildren {
+		return ""
+	}
+	children := c.sigChildren()
+	if len(children) == 0 {
+		return ""
+	}
+	links := make([]string, 0, len(children))
+	for _, child := range children {
+		link := "sig/" + child.sig
+		if number := openReportNumber(c.filer.tracker, child.ID()); number != 0 {
+			link += fmt.Sprintf(" (#%d)", number)
+		}
+		links = append(links, link)
+	}
+	return fmt.Sprintf("The failing tests are owned by several SIGs, so each has an issue for its own tests: %s.\n", strings.Join(links, ", "))
+}
+
+// openReportNumber returns the number of the open issue marked with creator.ReportMarker(id), or 0.
+func openReportNumber(tracker issueTracker, id string) int {
+	if tracker == nil {
+		return 0
+	}
+	marker := creator.ReportMarker(id)
+	for _, issue := range tracker.OpenIssues() {
+		if issue.Body != nil && strings.Contains(*issue.Body, marker) {
+			return *issue.Number
+		}
+	}
+	return 0
+}
+
+// Title is the string to use as the github issue title.
+func (s *sigChildIssue) Title() string {
+	return fmt.Sprintf("Failure cluster [%s...] failed %d builds in %d sig/%s tests over %d days",
+		s.cluster.Identifier[0:6],
+		s.cluster.totalBuilds,
+		len(s.tests),
+		s.sig,
+		s.cluster.filer.windowDays,
+	)
+}
+
+// Body returns the body text of the github issue. Like the issue for the whole cluster, no issue
+// is created if one was closed within the current window.
+func (s *sigChildIssue) Body(closedIssues []*githubapi.Issue) string {
+	c := s.cluster
+	cutoffTime := c.filer.windowTime(c.filer.latestStart).AddDate(0, 0, -c.filer.windowDays)
+	for _, closed := range closedIssues {
+		if closed.ClosedAt.After(cutoffTime) || c.filer.ignored(closed) {
+			return ""
+		}
+	}
+
+	// The body mentions the cluster's ID, so it is marked as a report to only match its own ID.
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%s\n### sig/%s tests in failure cluster [%s](%s#%s)\n", creator.ReportMarker(s.ID()), s.sig, c.Identifier, triageURL, c.Identifier)
+	parent := "the issue for the whole cluster"
+	if c.filer.tracker != nil {
+		if number := clusterIssues(c.filer.tracker.OpenIssues())[c.Identifier]; number != 0 {
+			parent = fmt.Sprintf("#%d", number)
+		}
+	}
+	fmt.Fprintf(&buf, "This cluster's failing tests are owned by several SIGs. This issue covers the tests owned by sig/%s, see %s for the rest.\n", s.sig, parent)
+	fmt.Fprintf(&buf, "##### Error text:\n```\n%s\n```\n", c.Text)
+
+	var tests bytes.Buffer
+	fmt.Fprintf(&tests, "##### Failing tests owned by sig/%s:\n", s.sig)
+	fmt.Fprint(&tests, "\n| Test Name | Jobs Failed |\n| --- | --- |\n")
+	owned := map[string]bool{}
+	for _, test := range s.tests {
+		owned[test] = true
+	}
+	for _, test := range c.topTestsFailed(len(c.Tests)) {
+		if owned[test.Name] {
+			fmt.Fprintf(&tests, "| %s | %d |\n", test.Name, len(test.Jobs))
+		}
+	}
+	buf.WriteString(creator.Section("tests", tests.String()))
+
+	assign, mention := c.splitOwners(c.filer.creator.TestsOwners(s.tests))
+	if len(assign) > 0 {
+		fmt.Fprintf(&buf, "\n/assign @%s\n", strings.Join(assign, " @"))
+	}
+	if len(mention) > 0 {
+		fmt.Fprintf(&buf, "\ncc @%s\n", strings.Join(mention, " @"))
+	}
+	fmt.Fprintf(&buf, "\n[Current Status](%s#%s)", triageURL, c.Identifier)
+	return buf.String()
+}
+
+// ID yields the string identifier that uniquely identifies this issue.
+// DO NOT CHANGE how this ID is formatted or duplicate issues may be created on github.
+func (s *sigChildIssue) ID() string {
+	return fmt.Sprintf("%s sig/%s", s.cluster.Identifier, s.sig)
+}
+
+// Labels returns the labels to apply to the issue on github.
+func (s *sigChildIssue) Labels() []string {
+	return []string{"kind/flake", "sig/" + s.sig}
+}
+
+// Owners returns the list of usernames to assign to this issue on github. Like the issue for the
+// whole cluster, owners are assigned with a /assign command in the body.
+func (s *sigChildIssue) Owners() []string {
+	return nil
+}
+
+// Priority calculates and returns the priority of this issue.
+func (s *sigChildIssue) Priority() (string, bool) {
+	return s.cluster.Priority()
+}
@@ -0,0 +1,140 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sources
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/github"
+	"k8s.io/test-infra/robots/issue-creator/creator"
+	"k8s.io/test-infra/robots/issue-creator/testowner"
+)
+
+func TestTFSIGChildren(t *testing.T) {
+	f := NewTestTriageFiler()
+	f.sigChildren = true
+	// Move testname2 to another SIG so that the cluster spans two.
+	csv := bytes.Replace(sampleOwnerCSV, []byte("testname2,spxtr,1,sigarea"), []byte("testname2,spxtr,1,sigother"), 1)
+	owners, err := testowner.NewOwnerListFromCsv(bytes.NewReader(csv))
+	if err != nil {
+		t.Fatalf("Failed to create a new OwnerList.  errmsg: %v\n", err)
+	}
+	f.creator.Owners = owners
+	f.creator.MaxSIGCount = 3
+	f.creator.MaxAssignees = 3
+	clusters, err := f.loadClusters(json1issue2job2test)
+	if err != nil {
+		t.Fatalf("Error parsing triage data: %v\n", err)
+	}
+	clust := clusters[0]
+
+	children := clust.sigChildren()
+	if len(children) != 2 {
+		t.Fatalf("Expected a child issue for each of the 2 SIGs, got %d", len(children))
+	}
+	child := children[1]
+	if child.ID() != clust.Identifier+" sig/sigother" {
+		t.Errorf("Expected the second child to be for sig/sigother, got ID %q", child.ID())
+	}
+	if labels := child.Labels(); !reflect.DeepEqual(labels, []string{"kind/flake", "sig/sigother"}) {
+		t.Errorf("Expected the child to be labeled with its SIG, got %v", labels)
+	}
+
+	f.tracker = &fakeTracker{own: []*github.Issue{
+		makeIssue(4, "### Failure cluster ["+clust.Identifier+"](url)", "kind/flake"),
+		makeIssue(7, creator.ReportMarker(child.ID())+"\nsig/sigother", "kind/flake"),
+	}}
+	body := child.Body(nil)
+	for _, expected := range []string{
+		creator.ReportMarker(child.ID()),
+		"covers the tests owned by sig/sigother, see #4 for the rest",
+		"| testname2 | 1 |",
+		"/assign @spxtr\n",
+	} {
+		if !strings.Contains(body, expected) {
+			t.Errorf("Expected the child body to contain %q, got:\n%s", expected, body)
+		}
+	}
+	if strings.Contains(body, "testname1") || strings.Contains(body, "@cjwagner") {
+		t.Errorf("Expected the child body to only cover its SIG's tests, got:\n%s", body)
+	}
+	if clusterHeaderRegex.MatchString(body) {
+		t.Errorf("Expected the child body not to be mistaken for the cluster's issue, got:\n%s", body)
+	}
+
+	if parent := clust.Body(nil); !strings.Contains(parent, "sig/sigarea, sig/sigother (#7).") {
+		t.Errorf("Expected the cluster's issue to link its child issues, got:\n%s", parent)
+	}
+
+	// Clusters of a single SIG have no children.
+	f.creator.Owners, _ = testowner.NewOwnerListFromCsv(bytes.NewReader(sampleOwnerCSV))
+	if children := clust.sigChildren(); len(children) != 0 {
+		t.Errorf("Expected no child issues for a single SIG, got %d", len(children))
+	}
+}
+
+func TestTFSIGChildrenIgnored(t *testing.T) {
+	f := NewTestTriageFiler()
+	f.sigChildren = true
+	f.ignoreLabel = "triage/handled-offline"
+	f.dataURL = "https://example.com/failure_data.json"
+	f.Fetcher = mapFetcher{f.dataURL: string(json1issue2job2test)}
+	csv := bytes.Replace(sampleOwnerCSV, []byte("testname2,spxtr,1,sigarea"), []byte("testname2,spxtr,1,sigother"), 1)
+	owners, err := testowner.NewOwnerListFromCsv(bytes.NewReader(csv))
+	if err != nil {
+		t.Fatalf("Failed to create a new OwnerList.  errmsg: %v\n", err)
+	}
+	f.creator.Owners = owners
+	f.creator.MaxSIGCount = 3
+
+	countChildren := func() int {
+		issues, err := f.Issues(context.Background(), f.creator)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		count := 0
+		for _, issue := range issues {
+			if _, ok := issue.(*sigChildIssue); ok {
+				count++
+			}
+		}
+		return count
+	}
+	f.tracker = &fakeTracker{}
+	if count := countChildren(); count != 2 {
+		t.Fatalf("Expected a child issue for each of the 2 SIGs, got %d", count)
+	}
+	clusters, err := f.loadClusters(json1issue2job2test)
+	if err != nil {
+		t.Fatalf("Error parsing triage data: %v\n", err)
+	}
+	parentBody := "### Failure cluster [" + clusters[0].Identifier + "](url)"
+
+	// Children of a cluster that was opted out are not filed, whether its issue is open or closed.
+	f.tracker = &fakeTracker{own: []*github.Issue{makeIssue(4, parentBody, "kind/flake", f.ignoreLabel)}, updated: map[int]*github.IssueRequest{}}
+	if count := countChildren(); count != 0 {
+		t.Errorf("Expected no child issues when the open parent issue has the ignore label, got %d", count)
+	}
+	f.tracker = &fakeTracker{closed: []*github.Issue{makeIssue(4, parentBody, "kind/flake", f.ignoreLabel)}}
+	if count := countChildren(); count != 0 {
+		t.Errorf("Expected no child issues when the closed parent issue has the ignore label, got %d", count)
+	}
+}
@@ -0,0 +1,238 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sources
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+
+	githubapi "github.com/google/go-github/github"
+	"k8s.io/test-infra/robots/issue-creator/creator"
+)
+
+const (
+	// sigReportPrefix starts the ID of each SIG's flake report issue.
+	// DO NOT CHANGE or duplicate issues may be created on github.
+	sigReportPrefix = "Flake report for sig/"
+	// maxReportClusters is the maximum number of clusters listed in a SIG's report.
+	maxReportClusters = 10
+	// trendThreshold is how much failures must change between the halves of the window to count
+	// as rising or falling.
+	trendThreshold = 0.2
+)
+
+// sigReportIssue summarizes the failures routed to one SIG, and is updated in place every run.
+type sigReportIssue struct {
+	filer *TriageFiler
+	sig   string
+	// clusters are the clusters with a failing test owned by the SIG, worst first.
+	clusters []*Cluster
+	// tracked maps the IDs of clusters to the open issues that track them.
+	tracked map[string]int
+	// openFlakes is the number of open kind/flake issues labeled with the SIG.
+	openFlakes int
+	// earlier and later are the builds failed in the first and second half of the window.
+	earlier, later int
+}
+
+// sigReports groups the clusters by SIG and returns one report for each SIG, ordered by name.
+func (f *TriageFiler) sigReports(ctx context.Context, tracker issueTracker, clusters []*Cluster) ([]*sigReportIssue, error) {
+	others, err := tracker.OpenIssuesByOthers(ctx, "kind/flake")
+	if err != nil {
+		return nil, err
+	}
+	own := tracker.OpenIssues()
+	tracked := clusterIssues(own)
+	var flakes []*githubapi.Issue
+	for _, issue := range own {
+		if issue.Body == nil || creator.IsReport(*issue.Body) {
+			continue
+		}
+		if hasLabel(issueLabels(issue), "kind/flake") {
+			flakes = append(flakes, issue)
+		}
+	}
+	flakes = append(flakes, others...)
+
+	midpoint := f.latestStart - int64(f.windowDays)*24*60*60/2
+	reports := map[string]*sigReportIssue{}
+	for _, clust := range clusters {
+		if clust.trackedBy != 0 {
+			tracked[clust.Identifier] = clust.trackedBy
+		}
+		earlier, later := clust.buildsAround(midpoint)
+		for _, sig := range clust.sigs() {
+			report, ok := reports[sig]
+			if !ok {
+				report = &sigReportIssue{filer: f, sig: sig, tracked: tracked}
+				for _, issue := range flakes {
+					if hasLabel(issueLabels(issue), "sig/"+sig) {
+						report.openFlakes++
+					}
+				}
+				reports[sig] = report
+			}
+			report.clusters = append(report.clusters, clust)
+			report.earlier += earlier
+			report.later += later
+		}
+	}
+
+	result := make([]*sigReportIssue, 0, len(reports))
+	for _, report := range reports {
+		sort.SliceStable(report.clusters, func(i, j int) bool {
+			return report.clusters[i].totalBuilds > report.clusters[j].totalBuilds
+		})
+		result = append(result, report)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].sig < result[j].sig })
+	return result, nil
+}
+
+// updateSIGReports edits the open report of each SIG that has one, and returns the reports that
+// still need an issue filed.
+func (f *TriageFiler) updateSIGReports(ctx context.Context, tracker issueTracker, reports []*sigReportIssue) []*sigReportIssue {
+	var unfiled []*sigReportIssue
+	for _, report := range reports {
+		if !f.updateOpenReport(ctx, tracker, report) {
+			unfiled = append(unfiled, report)
+		}
+	}
+	return unfiled
+}
+
+// sigs returns the SIGs of the tests in the cluster, in order. Infra flakes belong to no SIG.
+func (c *Cluster) sigs() []string {
+	if c.infraMatch() != "" {
+		return nil
+	}
+	testNames := make([]string, len(c.Tests))
+	for i, test := range c.Tests {
+		testNames[i] = test.Name
+	}
+	var sigs []string
+	for sig := range c.filer.creator.TestsSIGs(testNames) {
+		sigs = append(sigs, sig)
+	}
+	sort.Strings(sigs)
+	return sigs
+}
+
+// buildsAround returns how many of the cluster's failed builds started before and after midpoint.
+func (c *Cluster) buildsAround(midpoint int64) (before, after int) {
+	for job, builds := range c.jobs {
+		rowMap := c.filer.data.Builds.Jobs[job]
+		for _, build := range builds {
+			row, _ := rowMap.rowForBuild(build) // Already validated start time lookup for all builds.
+			if c.filer.data.Builds.Cols.Started[row] < midpoint {
+				before++
+			} else {
+				after++
+			}
+		}
+	}
+	return before, after
+}
+
+// trend describes the change in failures between the halves of the window.
+func (r *sigReportIssue) trend() string {
+	switch {
+	case float64(r.later) > float64(r.earlier)*(1+trendThreshold):
+		return "rising"
+	case float64(r.later) < float64(r.earlier)*(1-trendThreshold):
+		return "falling"
+	default:
+		return "steady"
+	}
+}
+
+// untracked returns the number of the SIG's clusters that don't have an open issue.
+func (r *sigReportIssue) untracked() int {
+	count := 0
+	for _, clust := range r.clusters {
+		if r.tracked[clust.Identifier] == 0 {
+			count++
+		}
+	}
+	return count
+}
+
+// Title is the string to use as the github issue title.
+func (r *sigReportIssue) Title() string {
+	return fmt.Sprintf("sig/%s flake report: %d failure clusters and %d open flake issues", r.sig, len(r.clusters), r.openFlakes)
+}
+
+// Body returns the body text of the github issue. No issue is created if one was closed within
+// the current window.
+func (r *sigReportIssue) Body(closedIssues []*githubapi.Issue) string {
+	cutoffTime := r.filer.windowTime(r.filer.latestStart).AddDate(0, 0, -r.filer.windowDays)
+	for _, closed := range closedIssues {
+		if closed.ClosedAt.After(cutoffTime) {
+			return ""
+		}
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%s\n### %s\n", creator.ReportMarker(r.ID()), r.ID())
+	// The report is regenerated every run, so it is in a section to preserve anything added to the issue.
+	var report bytes.Buffer
+	fmt.Fprintf(&report, "Failure stats cover %d day time range '%s' to '%s'. This issue is updated with every triage run.\n",
+		r.filer.windowDays,
+		cutoffTime.Format(timeFormat),
+		r.filer.windowTime(r.filer.latestStart).Format(timeFormat))
+	fmt.Fprintf(&report, "- %d failure clusters include tests owned by sig/%s, and %d of them have no open issue.\n", len(r.clusters), r.sig, r.untracked())
+	fmt.Fprintf(&report, "- %d open flake issues are labeled sig/%s.\n", r.openFlakes, r.sig)
+	fmt.Fprintf(&report, "- Failures are **%s**: %d failed builds in the first half of the window and %d in the second.\n", r.trend(), r.earlier, r.later)
+
+	fmt.Fprint(&report, "\n##### Top failure clusters by builds failed:\n")
+	fmt.Fprint(&report, "\n| Cluster | Builds Failed | Issue |\n| --- | --- | --- |\n")
+	for i, clust := range r.clusters {
+		if i == maxReportClusters {
+			fmt.Fprintf(&report, "\n...and %d more clusters.\n", len(r.clusters)-maxReportClusters)
+			break
+		}
+		issue := "none"
+		if number := r.tracked[clust.Identifier]; number != 0 {
+			issue = fmt.Sprintf("#%d", number)
+		}
+		fmt.Fprintf(&report, "| [%s](%s#%s) | %d | %s |\n", clust.Identifier, triageURL, clust.Identifier, clust.totalBuilds, issue)
+	}
+	buf.WriteString(creator.Section("report", report.String()))
+	return buf.String()
+}
+
+// ID yields the string identifier that uniquely identifies this issue.
+func (r *sigReportIssue) ID() string {
+	return sigReportPrefix + r.sig
+}
+
+// Labels returns the labels to apply to the issue on github.
+func (r *sigReportIssue) Labels() []string {
+	return []string{"kind/cleanup", "sig/" + r.sig}
+}
+
+// Owners returns the list of usernames to assign to this issue on github.
+func (r *sigReportIssue) Owners() []string {
+	return nil
+}
+
+// Priority calculates and returns the priority of this issue.
+func (r *sigReportIssue) Priority() (string, bool) {
+	return "", false
+}
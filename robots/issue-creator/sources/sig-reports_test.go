@@ -0,0 +1,107 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sources
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/github"
+	"k8s.io/test-infra/robots/issue-creator/creator"
+	"k8s.io/test-infra/robots/issue-creator/testowner"
+)
+
+func TestTFSIGReports(t *testing.T) {
+	f := NewTestTriageFiler()
+	f.creator.MaxSIGCount = 3
+	var err error
+	if f.creator.Owners, err = testowner.NewOwnerListFromCsv(bytes.NewReader(sampleOwnerCSV)); err != nil {
+		t.Fatalf("Failed to create a new OwnersList.  errmsg: %v", err)
+	}
+	clusters, err := f.loadClusters(json1issue2job2test)
+	if err != nil {
+		t.Fatalf("Error parsing triage data: %v\n", err)
+	}
+
+	tracker := &fakeTracker{
+		own: []*github.Issue{
+			makeIssue(1, "### Failure cluster [key_hash](url)", "kind/flake", "sig/sigarea"),
+			makeIssue(2, "### Failure cluster [other_hash](url)", "kind/flake", "sig/node"),
+			makeIssue(3, creator.ReportMarker(sigReportPrefix+"sigarea")+"\nold report", "kind/cleanup", "sig/sigarea"),
+		},
+		others:  []*github.Issue{makeIssue(10, "Human filed flake.", "kind/flake", "sig/sigarea")},
+		updated: map[int]*github.IssueRequest{},
+	}
+	reports, err := f.sigReports(context.Background(), tracker, clusters)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(reports) != 1 || reports[0].sig != "sigarea" {
+		t.Fatalf("Expected a single report for sigarea, got %v", reports)
+	}
+	report := reports[0]
+	if report.openFlakes != 2 {
+		t.Errorf("Expected 2 open flake issues for sigarea, got %d", report.openFlakes)
+	}
+	if report.earlier+report.later != clusters[0].totalBuilds {
+		t.Errorf("Expected %d builds split across the window, got %d and %d", clusters[0].totalBuilds, report.earlier, report.later)
+	}
+	body := report.Body(nil)
+	for _, expected := range []string{
+		creator.ReportMarker(sigReportPrefix+"sigarea") + "\n### " + sigReportPrefix + "sigarea\n",
+		"1 failure clusters include tests owned by sig/sigarea, and 0 of them have no open issue.",
+		"2 open flake issues are labeled sig/sigarea.",
+		"| [key_hash](" + triageURL + "#key_hash) | 4 | #1 |",
+	} {
+		if !strings.Contains(body, expected) {
+			t.Errorf("Expected the report to contain %q, got:\n%s", expected, body)
+		}
+	}
+
+	if unfiled := f.updateSIGReports(context.Background(), tracker, reports); len(unfiled) != 0 {
+		t.Errorf("Expected the existing report to be updated rather than filed, got %d to file", len(unfiled))
+	}
+	if update := tracker.updated[3]; update == nil || *update.Body != body {
+		t.Errorf("Expected issue 3 to be updated with the new report, got %v", update)
+	}
+	tracker.own = tracker.own[:2]
+	if unfiled := f.updateSIGReports(context.Background(), tracker, reports); len(unfiled) != 1 {
+		t.Errorf("Expected the report to be filed when there is no open report, got %d to file", len(unfiled))
+	}
+}
+
+func TestSIGReportTrend(t *testing.T) {
+	tests := []struct {
+		earlier, later int
+		expected       string
+	}{
+		{earlier: 10, later: 10, expected: "steady"},
+		{earlier: 10, later: 11, expected: "steady"},
+		{earlier: 10, later: 13, expected: "rising"},
+		{earlier: 10, later: 7, expected: "falling"},
+		{earlier: 0, later: 1, expected: "rising"},
+		{earlier: 0, later: 0, expected: "steady"},
+	}
+	for _, test := range tests {
+		report := &sigReportIssue{earlier: test.earlier, later: test.later}
+		if trend := report.trend(); trend != test.expected {
+			t.Errorf("Expected %d then %d failures to be %s, got %s", test.earlier, test.later, test.expected, trend)
+		}
+	}
+}
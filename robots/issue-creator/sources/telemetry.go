@@ -0,0 +1,150 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sources
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// fromCacheHeader is set on responses that a DiskCache served from disk.
+const fromCacheHeader = "X-From-Cache"
+
+// fetchCounter counts finished fetches by URL scheme, status (the HTTP status code, "ok" for
+// other schemes, or "error"), and whether the body came from the DiskCache ("hit"), was downloaded
+// ("miss"), or was not cacheable ("none").
+var fetchCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "issue_creator_fetches_total",
+	Help: "Data fetches by URL scheme, status, and cache result.",
+}, []string{"scheme", "status", "cache"})
+
+// fetchDuration records how long fetches take, from the request until the body is closed.
+var fetchDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "issue_creator_fetch_duration_seconds",
+	Help:    "Time from starting a data fetch until its body is closed, by URL scheme.",
+	Buckets: prometheus.ExponentialBuckets(0.05, 2, 14),
+}, []string{"scheme"})
+
+// fetchBytes records the size of fetched bodies, so that unexpected growth stands out.
+var fetchBytes = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "issue_creator_fetch_bytes",
+	Help:    "Bytes read from the body of each data fetch, by URL scheme.",
+	Buckets: prometheus.ExponentialBuckets(1024, 4, 12),
+}, []string{"scheme"})
+
+func init() {
+	prometheus.MustRegister(fetchCounter)
+	prometheus.MustRegister(fetchDuration)
+	prometheus.MustRegister(fetchBytes)
+}
+
+// fetchRecord describes one fetch for the metrics and logs.
+type fetchRecord struct {
+	url    string
+	status string
+	cache  string
+	start  time.Time
+}
+
+// scheme returns the scheme of the fetched URL, or "file" for plain paths.
+func (f *fetchRecord) scheme() string {
+	if i := strings.Index(f.url, "://"); i > 0 {
+		return strings.ToLower(f.url[:i])
+	}
+	return "file"
+}
+
+// finish records the fetch once n bytes of its body were read.
+func (f *fetchRecord) finish(n int64) {
+	duration := time.Since(f.start)
+	fetchCounter.WithLabelValues(f.scheme(), f.status, f.cache).Inc()
+	fetchDuration.WithLabelValues(f.scheme()).Observe(duration.Seconds())
+	if f.status != "error" {
+		fetchBytes.WithLabelValues(f.scheme()).Observe(float64(n))
+	}
+	glog.Infof("Fetched url=%q status=%s cache=%s duration=%v bytes=%d", f.url, f.status, f.cache, duration, n)
+}
+
+// observedBody counts the bytes read from a fetched body and records the fetch when it is closed.
+type observedBody struct {
+	io.ReadCloser
+	record *fetchRecord
+	n      int64
+	once   sync.Once
+}
+
+func (b *observedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n += int64(n)
+	return n, err
+}
+
+func (b *observedBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(func() { b.record.finish(b.n) })
+	return err
+}
+
+// observeOpen records a fetch of url that started at start and returned body or err.
+func observeOpen(url string, start time.Time, body io.ReadCloser, err error) io.ReadCloser {
+	record := &fetchRecord{url: url, status: "ok", cache: "none", start: start}
+	if err != nil {
+		record.status = "error"
+		record.finish(0)
+		return body
+	}
+	return &observedBody{ReadCloser: body, record: record}
+}
+
+// InstrumentTransport records metrics and logs for every request sent with next. Place it in front
+// of a DiskCache to tell cache hits from downloads.
+func InstrumentTransport(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &instrumentedTransport{next: next}
+}
+
+type instrumentedTransport struct {
+	next http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *instrumentedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	record := &fetchRecord{url: req.URL.String(), cache: "none", start: time.Now()}
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		record.status = "error"
+		record.finish(0)
+		return nil, err
+	}
+	record.status = strconv.Itoa(resp.StatusCode)
+	if resp.Header.Get(fromCacheHeader) != "" {
+		record.cache = "hit"
+	} else if _, ok := resp.Body.(*cachingBody); ok {
+		record.cache = "miss"
+	}
+	resp.Body = &observedBody{ReadCloser: resp.Body, record: record}
+	return resp, nil
+}
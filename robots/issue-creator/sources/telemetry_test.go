@@ -0,0 +1,96 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sources
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestFetchRecordScheme(t *testing.T) {
+	tests := map[string]string{
+		"https://example.com/data.json": "https",
+		"HTTP://example.com/data.json":  "http",
+		"gs://bucket/data.json":         "gs",
+		"s3://bucket/data.json":         "s3",
+		"file:///tmp/data.json":         "file",
+		"/tmp/data.json":                "file",
+	}
+	for url, expected := range tests {
+		if scheme := (&fetchRecord{url: url}).scheme(); scheme != expected {
+			t.Errorf("%s: expected scheme %q, got %q", url, expected, scheme)
+		}
+	}
+}
+
+func TestInstrumentTransport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "telemetry")
+	if err != nil {
+		t.Fatalf("failed to create a temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte("data"))
+	}))
+	defer server.Close()
+
+	cache, err := NewDiskCache(dir, 0, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	client := &http.Client{Transport: InstrumentTransport(cache)}
+	tests := []struct {
+		path   string
+		status string
+		cache  string
+	}{
+		{path: "/data", status: "200", cache: "miss"},
+		{path: "/data", status: "200", cache: "hit"},
+		{path: "/missing", status: "404", cache: "none"},
+	}
+	for _, test := range tests {
+		resp, err := client.Get(server.URL + test.path)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", test.path, err)
+		}
+		body, ok := resp.Body.(*observedBody)
+		if !ok {
+			t.Fatalf("%s: expected the body to be observed, got %T", test.path, resp.Body)
+		}
+		ioutil.ReadAll(body)
+		body.Close()
+		if body.record.status != test.status || body.record.cache != test.cache {
+			t.Errorf("%s: expected status %s and cache %s, got %s and %s", test.path, test.status, test.cache, body.record.status, body.record.cache)
+		}
+		if test.status == "200" && body.n != 4 {
+			t.Errorf("%s: expected 4 bytes to be counted, got %d", test.path, body.n)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sources
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	githubapi "github.com/google/go-github/github"
+	"k8s.io/test-infra/robots/issue-creator/creator"
+)
+
+const (
+	// topFlakesID identifies the issue that ranks the current worst clusters.
+	// DO NOT CHANGE or duplicate issues may be created on github.
+	topFlakesID = "Top current flakes"
+	// topFlakesCount is the number of clusters ranked in the top flakes issue.
+	topFlakesCount = 10
+)
+
+// topFlakesIssue is a single evergreen issue that ranks the worst clusters and links to their
+// issues. It is edited every run, so it can be pinned as the place to look during flake storms.
+type topFlakesIssue struct {
+	filer    *TriageFiler
+	clusters []*Cluster
+	// tracked maps the IDs of clusters to the open issues that track them.
+	tracked map[string]int
+}
+
+// topFlakesIssue ranks the worst clusters. It reorders clusters like topClusters.
+func (f *TriageFiler) topFlakesIssue(tracker issueTracker, clusters []*Cluster) *topFlakesIssue {
+	tracked := clusterIssues(tracker.OpenIssues())
+	for _, clust := range clusters {
+		if clust.trackedBy != 0 {
+			tracked[clust.Identifier] = clust.trackedBy
+		}
+	}
+	return &topFlakesIssue{filer: f, clusters: topClusters(clusters, topFlakesCount), tracked: tracked}
+}
+
+// Title is the string to use as the github issue title.
+func (t *topFlakesIssue) Title() string {
+	return fmt.Sprintf("%s: the %d failure clusters that failed the most builds", topFlakesID, topFlakesCount)
+}
+
+// Body returns the body text of the github issue. The issue is filed again even if it was recently
+// closed, since it is meant to always be open.
+func (t *topFlakesIssue) Body(closedIssues []*githubapi.Issue) string {
+	cutoffTime := t.filer.windowTime(t.filer.latestStart).AddDate(0, 0, -t.filer.windowDays)
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%s\n### %s\n", creator.ReportMarker(t.ID()), topFlakesID)
+	var report bytes.Buffer
+	fmt.Fprintf(&report, "Failure stats cover %d day time range '%s' to '%s'. This issue is updated with every triage run.\n",
+		t.filer.windowDays,
+		cutoffTime.Format(timeFormat),
+		t.filer.windowTime(t.filer.latestStart).Format(timeFormat))
+	if len(t.clusters) == 0 {
+		fmt.Fprint(&report, "\nNo failure clusters in this time range.\n")
+		buf.WriteString(creator.Section("report", report.String()))
+		return buf.String()
+	}
+	fmt.Fprint(&report, "\n| Rank | Cluster | Builds Failed | Jobs Failed | Tests Failed | SIGs | Issue |\n| --- | --- | --- | --- | --- | --- | --- |\n")
+	for i, clust := range t.clusters {
+		issue := "none"
+		if number := t.tracked[clust.Identifier]; number != 0 {
+			issue = fmt.Sprintf("#%d", number)
+		}
+		sigs := clust.sigs()
+		for j, sig := range sigs {
+			sigs[j] = "sig/" + sig
+		}
+		fmt.Fprintf(&report, "| %d | [%s](%s#%s) | %d | %d | %d | %s | %s |\n",
+			i+1, clust.Identifier, triageURL, clust.Identifier, clust.totalBuilds, clust.totalJobs, clust.totalTests, strings.Join(sigs, " "), issue)
+	}
+	buf.WriteString(creator.Section("report", report.String()))
+	return buf.String()
+}
+
+// ID yields the string identifier that uniquely identifies this issue.
+func (t *topFlakesIssue) ID() string {
+	return topFlakesID
+}
+
+// Labels returns the labels to apply to the issue on github.
+func (t *topFlakesIssue) Labels() []string {
+	return []string{"kind/flake"}
+}
+
+// Owners returns the list of usernames to assign to this issue on github.
+func (t *topFlakesIssue) Owners() []string {
+	return nil
+}
+
+// Priority calculates and returns the priority of this issue.
+func (t *topFlakesIssue) Priority() (string, bool) {
+	return "", false
+}
@@ -0,0 +1,75 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sources
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/github"
+	"k8s.io/test-infra/robots/issue-creator/creator"
+)
+
+func TestTFTopFlakes(t *testing.T) {
+	f := NewTestTriageFiler()
+	f.dataURL = "https://example.com/failure_data.json"
+	f.Fetcher = &fakeFetcher{data: json1issue2job2test}
+	f.topFlakes = true
+	tracker := &fakeTracker{
+		own:     []*github.Issue{makeIssue(4, "### Failure cluster [key_hash](url)", "kind/flake")},
+		updated: map[int]*github.IssueRequest{},
+	}
+	f.tracker = tracker
+
+	issues, err := f.Issues(context.Background(), f.creator)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var top creator.Issue
+	for _, issue := range issues {
+		if issue.ID() == topFlakesID {
+			top = issue
+		}
+	}
+	if top == nil {
+		t.Fatalf("Expected the top flakes issue to be filed when none is open, got %d issues", len(issues))
+	}
+	body := top.Body(nil)
+	for _, expected := range []string{
+		creator.ReportMarker(topFlakesID),
+		"| 1 | [key_hash](" + triageURL + "#key_hash) | 4 | 2 | 2 |  | #4 |",
+	} {
+		if !strings.Contains(body, expected) {
+			t.Errorf("Expected the top flakes issue to contain %q, got:\n%s", expected, body)
+		}
+	}
+
+	// Once it is open it is edited instead of filed again.
+	tracker.own = append(tracker.own, makeIssue(9, creator.ReportMarker(topFlakesID)+"\nold ranking", "kind/flake"))
+	if issues, err = f.Issues(context.Background(), f.creator); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, issue := range issues {
+		if issue.ID() == topFlakesID {
+			t.Errorf("Expected the open top flakes issue to be edited rather than filed again.")
+		}
+	}
+	if update := tracker.updated[9]; update == nil || *update.Body != body {
+		t.Errorf("Expected #9 to be updated with the current ranking, got %v", update)
+	}
+}
@@ -18,15 +18,21 @@ package sources
 
 import (
 	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
-	"reflect"
+	"io"
+	"io/ioutil"
+	"regexp"
 	"sort"
-	"strconv"
 	"strings"
 	"time"
 
+	"github.com/golang/glog"
 	githubapi "github.com/google/go-github/github"
 	"k8s.io/test-infra/robots/issue-creator/creator"
 )
@@ -39,40 +45,340 @@ const (
 	topTestsCount  = 3
 	triageURL      = "https://go.k8s.io/triage"
 	clusterDataURL = "https://storage.googleapis.com/k8s-gubernator/triage/failure_data.json"
+	checksumSuffix = ".sha256"
 )
 
 // TriageFiler files issues for clustered test failures.
 type TriageFiler struct {
 	topClustersCount int
 	windowDays       int
+	ownershipGaps    bool
+	// longWindowDays is the length of a second, longer window that failures are also counted over
+	// so that issues show whether a flake is accelerating. It is unused unless longer than windowDays.
+	longWindowDays int
+	// fetch controls how the cluster data is downloaded.
+	fetch HTTPOptions
+	// fetchTokenFile and fetchBasicAuthFile hold the credentials for downloading the cluster data.
+	fetchTokenFile     string
+	fetchBasicAuthFile string
+	// fetchS3File holds the S3 endpoint and credentials for s3:// cluster data URLs.
+	fetchS3File string
+	// fetchCacheDir and fetchCacheSize configure an on-disk cache for the cluster data download.
+	fetchCacheDir  string
+	fetchCacheSize int64
+	// dataURL is the location of the cluster data.
+	dataURL string
+	// Fetcher downloads the cluster data. If nil, one is created from the fetch flags.
+	Fetcher DataFetcher
+	// flakeRateWeeks is the length of the trailing window for test failure rates, or 0 to not
+	// compute them.
+	flakeRateWeeks int
+	// flakeRates are the failure rates of the tests in the last data loaded, by test name.
+	flakeRates map[string]TestFlakeRate
+	// bigQueryTable is the project:dataset.table to export cluster rows to, if any.
+	bigQueryTable string
+	// Exporter stores the cluster rows after each run. If nil, it is created from bigQueryTable.
+	Exporter RowExporter
+	// culpritHints is true iff the issues of clusters that started failing abruptly should get a
+	// comment with the likely range of culprit commits.
+	culpritHints bool
+	// escalateDays is how long a filed issue may go without a human comment before it is
+	// escalated, or 0 to never escalate.
+	escalateDays int
+	// quarantineURL is the location of the list of quarantined tests, or empty for none.
+	quarantineURL string
+	// quarantineDemote is true iff clusters of only quarantined tests get a lower priority.
+	quarantineDemote bool
+	// quarantine holds the names of the quarantined tests from quarantineURL.
+	quarantine map[string]bool
+	// jobTiersURL is the location of the YAML with the tier of each job, or empty for none.
+	jobTiersURL string
+	// jobTiers maps job names to their tiers from jobTiersURL.
+	jobTiers map[string]string
+	// jobNamesURL is the location of the YAML with the display name of each job, or empty for none.
+	jobNamesURL string
+	// jobNames maps job names to their display names from jobNamesURL.
+	jobNames map[string]string
+	// ignoreLabel is the label that humans apply to an issue of the bot so that its cluster is never
+	// filed or updated again, or empty to not allow opting out.
+	ignoreLabel string
+	// stormClusters is how many clusters must spike at once to count as a flake storm, or 0 to
+	// never detect storms. stormHours is the period they must spike in.
+	stormClusters int
+	stormHours    int
+	// mentionOnlySIGs is a comma separated list of the SIGs whose test owners are only mentioned
+	// on issues, never assigned.
+	mentionOnlySIGs string
+	// junitExcerpts is true iff the bodies of the top clusters include the failure messages from
+	// the junit artifacts of their latest failed builds.
+	junitExcerpts bool
+	// sigChildren is true iff clusters with tests owned by several SIGs also get an issue per SIG.
+	sigChildren bool
+	// dedupeAuthors is a comma separated list of the bots whose issues can cover clusters.
+	dedupeAuthors string
+	// dedupeLabel is the label of the issues from dedupeAuthors to check.
+	dedupeLabel string
+	// dedupeMatch is the rule for when an issue from dedupeAuthors covers a cluster.
+	dedupeMatch string
+	// infraFlakes is true iff clusters should be classified as infra or product flakes.
+	infraFlakes bool
+	// infraPatternsFile holds the regexes that classify infra flakes, or is empty for the defaults.
+	infraPatternsFile string
+	// infraOncallNames is a comma separated list of the users and teams that infra flakes go to.
+	infraOncallNames string
+	// infraPatterns are the compiled infra flake regexes, or nil if classification is off.
+	infraPatterns []*regexp.Regexp
+	// sigTeamFormat formats a SIG name into the GitHub team to mention when escalating.
+	sigTeamFormat string
+	// topFlakes is true iff an issue ranking the worst clusters should be kept up to date.
+	topFlakes bool
+	// sigReportsEnabled is true iff a flake report issue should be kept up to date for every SIG.
+	sigReportsEnabled bool
+	// reconcile is true iff open flake issues should be updated to match the current clusters.
+	reconcile bool
+	// tracker is used to reconcile the open issues. If nil, the IssueCreator is used.
+	tracker issueTracker
+	// verifyChecksum is true iff the cluster data must match the SHA-256 in the file at dataURL
+	// with checksumSuffix appended.
+	verifyChecksum bool
 
-	nextSync    time.Time
+	nextSync time.Time
+	// windowEnd, if not zero, is the unix time to end the window at instead of the latest build.
+	windowEnd   int64
 	latestStart int64
+	// windowTimezone is the name of the timezone that days of the window and times in issues are in.
+	windowTimezone string
+	// windowBoundary, if set, is the time of day, like "00:00", that the window ends at. The window
+	// ends at the last boundary before the latest build, so that daily runs cover whole days.
+	windowBoundary string
+	// windowLoc is the location of windowTimezone, and boundary is the parsed windowBoundary, or
+	// nil if the window ends at the latest build.
+	windowLoc *time.Location
+	boundary  *time.Time
+	// implausible holds the failed builds of the last data whose start times are implausible.
+	implausible implausibleBuilds
 
 	creator *creator.IssueCreator
 	data    *triageData
 }
 
+// triageFilerName is the name that the TriageFiler is registered and logs under.
+const triageFilerName = "triage-filer"
+
 func init() {
-	creator.RegisterSourceOrDie("triage-filer", &TriageFiler{})
+	creator.RegisterSourceOrDie(triageFilerName, &TriageFiler{})
+}
+
+// log returns a Log for the cluster or report with the given ID.
+func (f *TriageFiler) log(id string) creator.Log {
+	return f.creator.Log(triageFilerName, id)
+}
+
+// UseSnapshot makes the TriageFiler read its triage data from the file at path, and stops it from
+// exporting cluster rows, so that it can be replayed over old snapshots.
+func (f *TriageFiler) UseSnapshot(path string) {
+	f.dataURL = path
+	f.bigQueryTable = ""
+	f.Exporter = nil
 }
 
 // Issues is the main work function of the TriageFiler.  It fetches and parses cluster data,
 // then syncs the top issues to github with the IssueCreator.
-func (f *TriageFiler) Issues(c *creator.IssueCreator) ([]creator.Issue, error) {
+func (f *TriageFiler) Issues(ctx context.Context, c *creator.IssueCreator) ([]creator.Issue, error) {
 	f.creator = c
-	rawjson, err := ReadHTTP(clusterDataURL)
+	if f.Fetcher == nil {
+		fetcher, err := f.newFetcher(c)
+		if err != nil {
+			return nil, err
+		}
+		f.Fetcher = fetcher
+	}
+	if f.infraFlakes && f.infraPatterns == nil {
+		patterns, err := loadInfraPatterns(f.infraPatternsFile)
+		if err != nil {
+			return nil, err
+		}
+		f.infraPatterns = patterns
+	}
+	if f.Exporter == nil && f.bigQueryTable != "" {
+		exporter, err := newBigQueryExporter(f.bigQueryTable)
+		if err != nil {
+			return nil, err
+		}
+		f.Exporter = exporter
+	}
+	f.windowEnd = 0
+	if end, ok := c.LatestBuildTime(); ok {
+		f.windowEnd = end.Unix()
+	}
+	loc, boundary, err := f.windowZone()
 	if err != nil {
 		return nil, err
 	}
-	clusters, err := f.loadClusters(rawjson)
+	f.windowLoc, f.boundary = loc, boundary
+	if f.quarantineURL != "" {
+		quarantine, err := f.loadQuarantine(ctx, f.quarantineURL)
+		if err != nil {
+			return nil, err
+		}
+		f.quarantine = quarantine
+	}
+	if f.jobTiersURL != "" {
+		tiers, err := f.loadJobTiers(ctx, f.jobTiersURL)
+		if err != nil {
+			return nil, err
+		}
+		f.jobTiers = tiers
+	}
+	if f.jobNamesURL != "" {
+		names, err := f.loadJobNames(ctx, f.jobNamesURL)
+		if err != nil {
+			return nil, err
+		}
+		f.jobNames = names
+	}
+	data, size, err := f.Fetcher.Open(ctx, f.dataURL)
 	if err != nil {
 		return nil, err
 	}
+	defer data.Close()
+	glog.Infof("Decoding %s of triage data from %s.", formatSize(size), f.dataURL)
+	var r io.Reader = data
+	var expectedSum string
+	sum := sha256.New()
+	if f.verifyChecksum {
+		if expectedSum, err = f.fetchChecksum(ctx); err != nil {
+			return nil, err
+		}
+		r = io.TeeReader(data, sum)
+	}
+	clusters, err := f.loadClustersFrom(r)
+	// One malformed cluster shouldn't stop the rest from being filed.
+	malformed, partial := err.(*malformedClustersError)
+	if err != nil && !partial {
+		return nil, err
+	}
+	if f.verifyChecksum {
+		// The decoder may stop before the end of the data, but the checksum covers all of it.
+		if _, err := io.Copy(ioutil.Discard, r); err != nil {
+			return nil, fmt.Errorf("failed to read the rest of the triage data: %v", err)
+		}
+		if actual := hex.EncodeToString(sum.Sum(nil)); actual != expectedSum {
+			return nil, fmt.Errorf("triage data from %s has SHA-256 %s but the checksum file expects %s", f.dataURL, actual, expectedSum)
+		}
+	}
+	if partial {
+		glog.Errorf("Filing the valid clusters of the triage data: %v", malformed)
+		c.RecordError(malformed)
+	}
+	// Don't start changing issues if the run was stopped while the data was read.
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if f.tracker == nil {
+		f.tracker = c
+	}
+	if f.junitExcerpts {
+		// Only the top clusters are filed, so only their artifacts are read. topClusters sorts in
+		// place, so it gets a copy to leave the order of clusters alone.
+		f.loadJUnitExcerpts(ctx, topClusters(append([]*Cluster(nil), clusters...), f.topClustersCount))
+	}
+	if f.reconcile {
+		// Failing to reconcile shouldn't stop new clusters from being filed.
+		if err := f.reconcileIssues(ctx, f.tracker, clusters); err != nil {
+			glog.Errorf("Failed to reconcile the open flake issues: %v", err)
+			c.RecordError(err)
+		}
+	}
+	if f.dedupeAuthors != "" {
+		if err := f.dedupeAgainstBots(ctx, f.tracker, clusters); err != nil {
+			glog.Errorf("Failed to check the issues of other bots for clusters: %v", err)
+			c.RecordError(err)
+		}
+	}
+	if f.Exporter != nil {
+		rows := f.clusterRows(clusters, clusterIssues(f.tracker.OpenIssues()))
+		// Failing to export shouldn't stop new clusters from being filed.
+		if err := f.Exporter.Export(ctx, rows); err != nil {
+			glog.Errorf("Failed to export %d cluster rows: %v", len(rows), err)
+			c.RecordError(err)
+		} else {
+			glog.Infof("Exported %d cluster rows.", len(rows))
+		}
+	}
+	if f.culpritHints {
+		if err := f.postCulpritHints(ctx, f.tracker, clusters); err != nil {
+			glog.Errorf("Failed to post culprit hints: %v", err)
+			c.RecordError(err)
+		}
+	}
+	if f.escalateDays > 0 {
+		now := time.Now()
+		if f.windowEnd != 0 {
+			now = f.windowTime(f.windowEnd)
+		}
+		if err := f.escalateIssues(ctx, f.tracker, now); err != nil {
+			glog.Errorf("Failed to escalate inactive issues: %v", err)
+			c.RecordError(err)
+		}
+	}
+	// Look for ownership gaps before topClusters reorders the clusters.
+	var gaps *ownershipGapsIssue
+	if f.ownershipGaps {
+		gaps = f.ownershipGapsIssue(clusters)
+	}
+	var reports []*sigReportIssue
+	if f.sigReportsEnabled {
+		all, err := f.sigReports(ctx, f.tracker, clusters)
+		if err != nil {
+			glog.Errorf("Failed to build the SIG flake reports: %v", err)
+			c.RecordError(err)
+		} else {
+			reports = f.updateSIGReports(ctx, f.tracker, all)
+		}
+	}
+	var topFlakes *topFlakesIssue
+	if f.topFlakes {
+		if topFlakes = f.topFlakesIssue(f.tracker, clusters); f.updateOpenReport(ctx, f.tracker, topFlakes) {
+			topFlakes = nil
+		}
+	}
+	stormActive := false
+	var storm *flakeStormIssue
+	if f.stormClusters > 0 {
+		storm = f.flakeStorm(clusters)
+		if stormActive = storm.active(); stormActive {
+			glog.Warningf("Flake storm: %d clusters spiked in the last %d hours, filing one issue for them instead of an issue for each.", len(storm.clusters), f.stormHours)
+		}
+		// The open issue is updated to say whether the storm goes on, but only an active storm is filed.
+		if f.updateOpenReport(ctx, f.tracker, storm) || !stormActive {
+			storm = nil
+		}
+	}
 	topclusters := topClusters(clusters, f.topClustersCount)
-	issues := make([]creator.Issue, 0, len(topclusters))
+	if stormActive {
+		topclusters = nil
+	}
+	issues := make([]creator.Issue, 0, len(topclusters)+len(reports)+3)
 	for _, clust := range topclusters {
 		issues = append(issues, clust)
+		if f.sigChildren && clust.trackedBy == 0 && !f.clusterIgnored(f.tracker, clust) {
+			for _, child := range clust.sigChildren() {
+				issues = append(issues, child)
+			}
+		}
+	}
+	if gaps != nil {
+		issues = append(issues, gaps)
+	}
+	for _, report := range reports {
+		issues = append(issues, report)
+	}
+	if topFlakes != nil {
+		issues = append(issues, topFlakes)
+	}
+	if storm != nil {
+		issues = append(issues, storm)
 	}
 	return issues, nil
 }
@@ -81,25 +387,228 @@ func (f *TriageFiler) Issues(c *creator.IssueCreator) ([]creator.Issue, error) {
 func (f *TriageFiler) RegisterFlags() {
 	flag.IntVar(&f.topClustersCount, "triage-count", 3, "The number of clusters to sync issues for on github.")
 	flag.IntVar(&f.windowDays, "triage-window", 1, "The size of the sliding time window (in days) that is used to determine which failures to consider.")
+	flag.StringVar(&f.windowTimezone, "triage-window-timezone", "UTC", "The timezone, e.g. 'UTC' or 'America/Los_Angeles', that the days of the window and the times shown in issues are in.")
+	flag.StringVar(&f.windowBoundary, "triage-window-boundary", "", "A time of day like '00:00' in '--triage-window-timezone' to end the window at, the last one before the latest build, so that daily runs compare whole days (default: end at the latest build).")
+	flag.BoolVar(&f.ownershipGaps, "triage-ownership-gaps", false, "Also file an issue listing failing tests that have no owner or SIG.")
+	flag.DurationVar(&f.fetch.Timeout, "triage-fetch-timeout", DefaultHTTPOptions.Timeout, "The timeout for each attempt at downloading the triage data.")
+	flag.IntVar(&f.fetch.Retries, "triage-fetch-retries", DefaultHTTPOptions.Retries, "The number of times a failed download of the triage data is retried.")
+	flag.DurationVar(&f.fetch.Backoff, "triage-fetch-backoff", DefaultHTTPOptions.Backoff, "The delay before the first retry of the triage data download. It doubles after every retry.")
+	flag.StringVar(&f.dataURL, "triage-data-url", clusterDataURL, "The URL of the triage cluster data. gs://bucket/object URLs are read with the GCS client, s3://bucket/object URLs with the S3 client, and file:// URLs and plain paths from the local disk.")
+	flag.StringVar(&f.fetchTokenFile, "triage-fetch-token-file", "", "A file containing a bearer token to download the triage data with.")
+	flag.StringVar(&f.fetchBasicAuthFile, "triage-fetch-basic-auth-file", "", "A file containing 'user:password' to download the triage data with.")
+	flag.StringVar(&f.fetchS3File, "triage-fetch-s3-credentials-file", "", "A JSON file with the region, endpoint and credentials for s3:// triage data URLs.")
+	flag.IntVar(&f.longWindowDays, "triage-long-window", 0, "The size (in days) of a second, longer window to also count failed builds over, to show in issues next to the count for '--triage-window' (0 for none).")
+	flag.IntVar(&f.flakeRateWeeks, "triage-flake-rate-weeks", 0, "The number of trailing weeks to compute the failure rate of each test over, for the issue body (0 to not compute rates).")
+	flag.StringVar(&f.bigQueryTable, "triage-bigquery-table", "", "A BigQuery table named like project:dataset.table to append the statistics of every cluster to after each run (default: no export).")
+	flag.BoolVar(&f.culpritHints, "triage-culprit-hints", false, "Comment on the issues of clusters that started failing abruptly with the commits between the last passing and first failing build.")
+	flag.IntVar(&f.escalateDays, "triage-escalate-days", 0, "The number of days a filed issue may go without a human comment before its SIGs' teams are mentioned, it is marked help wanted, and its priority is raised. Comments by '--triage-dedupe-authors' don't count as human (0 to never escalate).")
+	flag.StringVar(&f.mentionOnlySIGs, "triage-mention-only-sigs", "", "Comma separated SIGs, e.g. 'node,storage', whose test owners are only cc'd on issues instead of assigned, even if their tests are auto-assigned (default: none).")
+	flag.StringVar(&f.sigTeamFormat, "triage-sig-team-format", "kubernetes/sig-%s-bugs", "The GitHub team to mention for a SIG when escalating, with %s for the SIG name (empty to mention no one).")
+	flag.StringVar(&f.quarantineURL, "triage-quarantine-list", "", "The location of a list of quarantined (skipped) test names, one per line, read like '--triage-data-url'. Tests marked "+flakyTag+" are always quarantined.")
+	flag.StringVar(&f.jobTiersURL, "triage-job-tiers", "", "The location of a YAML listing the "+tierReleaseBlocking+", "+tierMergeBlocking+" and "+tierInforming+" jobs, or a testgrid config whose *-blocking and *-informing dashboards give the tiers, read like '--triage-data-url'. Clusters are labeled with the tier of their jobs and prioritized by it.")
+	flag.StringVar(&f.jobNamesURL, "triage-job-names", "", "The location of a YAML with a 'names' map from job names to the names to show in issue titles and tables, or a testgrid config whose tab names are used, read like '--triage-data-url'.")
+	flag.BoolVar(&f.quarantineDemote, "triage-quarantine-demote", false, "Give clusters whose failing tests are all quarantined priority/"+quarantinedPriority+", since they no longer block merges.")
+	flag.StringVar(&f.ignoreLabel, "triage-ignore-label", "triage/handled-offline", "A label that people can apply to a filed issue so that its cluster is never filed or updated again, even after the issue is closed (empty to not allow opting out).")
+	flag.IntVar(&f.stormClusters, "triage-storm-clusters", 0, "The number of clusters that must spike within '--triage-storm-hours' at once to count as a flake storm, e.g. an infra outage. During a storm one umbrella issue listing the affected jobs is filed instead of an issue for each cluster (0 to never detect storms).")
+	flag.IntVar(&f.stormHours, "triage-storm-hours", 6, "The period (in hours, before the latest build) that clusters must spike in to count towards a flake storm.")
+	flag.BoolVar(&f.junitExcerpts, "triage-junit-excerpts", false, "Include the failure message of the cluster's tests from the junit artifacts of the latest failed build of each top job in the issue body.")
+	flag.BoolVar(&f.sigChildren, "triage-sig-children", false, "Also file an issue for each SIG that owns some of the failing tests of a cluster whose tests are owned by several SIGs, linked to the cluster's issue.")
+	flag.StringVar(&f.dedupeAuthors, "triage-dedupe-authors", "", "Comma separated logins of other bots, e.g. CI signal tooling, whose open issues are linked to matching clusters instead of filing duplicates (default: none).")
+	flag.StringVar(&f.dedupeLabel, "triage-dedupe-label", "kind/failing-test", "The label of the issues from '--triage-dedupe-authors' to check.")
+	flag.StringVar(&f.dedupeMatch, "triage-dedupe-match", matchJobAndTest, "What an issue from '--triage-dedupe-authors' must mention to cover a cluster: "+matchJobAndTest+", "+matchTest+", or "+matchJob+".")
+	flag.BoolVar(&f.infraFlakes, "triage-infra-flakes", false, "Label clusters whose error text looks like an infra failure (provisioning timeouts, image pulls, quota) "+infraFlakeLabel+" and route them to '--triage-infra-oncall' instead of the test owners.")
+	flag.StringVar(&f.infraPatternsFile, "triage-infra-patterns", "", "A file of regexes, one per line, that match the error text of infra flakes (default: built in patterns).")
+	flag.StringVar(&f.infraOncallNames, "triage-infra-oncall", "", "Comma separated GitHub users to assign and org/team names to mention on infra flakes.")
+	flag.BoolVar(&f.topFlakes, "triage-top-flakes", false, "Keep a single issue up to date that ranks the worst failure clusters and links to their issues.")
+	flag.BoolVar(&f.sigReportsEnabled, "triage-sig-reports", false, "Keep an issue for every SIG up to date with its failure clusters, open flake issues, and failure trend.")
+	flag.BoolVar(&f.reconcile, "triage-reconcile", false, "Update the counts in open flake issues, mark issues for clusters that stopped failing as stale, and skip clusters that already have a human filed issue.")
+	flag.BoolVar(&f.verifyChecksum, "triage-verify-checksum", false, "Refuse triage data that does not match the SHA-256 in the checksum file next to it ('--triage-data-url' with '.sha256' appended).")
+	flag.StringVar(&f.fetchCacheDir, "triage-fetch-cache-dir", "", "A directory to cache the triage data in, so that unchanged data is revalidated instead of downloaded again.")
+	flag.Int64Var(&f.fetchCacheSize, "triage-fetch-cache-size", 1<<30, "The maximum size in bytes of the triage data cache (0 for no limit).")
+	flag.Var(&f.fetch.RetryStatuses, "triage-fetch-retry-codes", "Comma separated HTTP status codes that cause the triage data download to be retried (default: any 5xx).")
+}
+
+// Validate implements creator.SourceValidator.
+func (f *TriageFiler) Validate() []error {
+	var errs []error
+	if f.topClustersCount < 1 {
+		errs = append(errs, fmt.Errorf("'--triage-count' must be at least 1, got %d", f.topClustersCount))
+	}
+	if f.windowDays < 1 {
+		errs = append(errs, fmt.Errorf("'--triage-window' must be at least 1, got %d", f.windowDays))
+	}
+	if f.stormClusters < 0 {
+		errs = append(errs, fmt.Errorf("'--triage-storm-clusters' must not be negative, got %d", f.stormClusters))
+	}
+	if f.stormClusters > 0 && f.stormHours < 1 {
+		errs = append(errs, fmt.Errorf("'--triage-storm-hours' must be at least 1, got %d", f.stormHours))
+	}
+	if f.longWindowDays < 0 {
+		errs = append(errs, fmt.Errorf("'--triage-long-window' must not be negative, got %d", f.longWindowDays))
+	}
+	if err := validateDataURL("triage-data-url", f.dataURL); err != nil {
+		errs = append(errs, err)
+	}
+	if f.sigTeamFormat != "" && strings.Count(f.sigTeamFormat, "%s") != 1 {
+		errs = append(errs, fmt.Errorf("'--triage-sig-team-format' must contain %%s exactly once, got '%s'", f.sigTeamFormat))
+	}
+	if f.quarantineURL != "" {
+		if err := validateDataURL("triage-quarantine-list", f.quarantineURL); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if f.jobTiersURL != "" {
+		if err := validateDataURL("triage-job-tiers", f.jobTiersURL); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if f.jobNamesURL != "" {
+		if err := validateDataURL("triage-job-names", f.jobNamesURL); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if f.dedupeAuthors != "" {
+		switch f.dedupeMatch {
+		case matchJobAndTest, matchTest, matchJob:
+		default:
+			errs = append(errs, fmt.Errorf("'--triage-dedupe-match' must be %s, %s, or %s, got '%s'", matchJobAndTest, matchTest, matchJob, f.dedupeMatch))
+		}
+	}
+	if f.infraFlakes {
+		if _, err := loadInfraPatterns(f.infraPatternsFile); err != nil {
+			errs = append(errs, fmt.Errorf("'--triage-infra-patterns': %v", err))
+		}
+	}
+	if f.bigQueryTable != "" {
+		if _, err := newBigQueryExporter(f.bigQueryTable); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if _, _, err := f.windowZone(); err != nil {
+		errs = append(errs, err)
+	}
+	if f.fetchTokenFile != "" && f.fetchBasicAuthFile != "" {
+		errs = append(errs, errors.New("only one of '--triage-fetch-token-file' and '--triage-fetch-basic-auth-file' may be specified"))
+	}
+	for flagName, path := range map[string]string{
+		"triage-fetch-token-file":          f.fetchTokenFile,
+		"triage-fetch-basic-auth-file":     f.fetchBasicAuthFile,
+		"triage-fetch-s3-credentials-file": f.fetchS3File,
+	} {
+		if path == "" {
+			continue
+		}
+		if err := creator.ValidateFile(flagName, path); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// formatSize describes a size in bytes for logging, where -1 means the size is unknown.
+func formatSize(size int64) string {
+	if size < 0 {
+		return "an unknown amount"
+	}
+	return fmt.Sprintf("%.1f MB", float64(size)/(1<<20))
+}
+
+// newFetcher creates the DataFetcher described by the fetch flags, sending requests with the
+// transport of the IssueCreator.
+func (f *TriageFiler) newFetcher(c *creator.IssueCreator) (DataFetcher, error) {
+	if err := f.loadFetchAuth(); err != nil {
+		return nil, err
+	}
+	if f.fetch.Transport == nil {
+		f.fetch.Transport = c.Transport()
+		if f.fetchCacheDir != "" {
+			cache, err := NewDiskCache(f.fetchCacheDir, f.fetchCacheSize, f.fetch.Transport)
+			if err != nil {
+				return nil, err
+			}
+			f.fetch.Transport = cache
+		}
+		f.fetch.Transport = InstrumentTransport(f.fetch.Transport)
+	}
+	return &URLFetcher{Options: f.fetch}, nil
 }
 
-// triageData is a struct that represents the format of the JSON triage data and is used for parsing.
+// fetchChecksum returns the lowercase hex SHA-256 of the cluster data from its checksum file,
+// which is in the format written by sha256sum.
+func (f *TriageFiler) fetchChecksum(ctx context.Context) (string, error) {
+	url := f.dataURL + checksumSuffix
+	r, _, err := f.Fetcher.Open(ctx, url)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch the triage data checksum: %v", err)
+	}
+	defer r.Close()
+	// The checksum file is tiny, so anything much larger is not a checksum file.
+	b, err := ioutil.ReadAll(io.LimitReader(r, 4096))
+	if err != nil {
+		return "", fmt.Errorf("failed to read '%s': %v", url, err)
+	}
+	fields := strings.Fields(string(b))
+	if len(fields) == 0 {
+		return "", fmt.Errorf("checksum file '%s' is empty", url)
+	}
+	sum := strings.ToLower(fields[0])
+	if decoded, err := hex.DecodeString(sum); err != nil || len(decoded) != sha256.Size {
+		return "", fmt.Errorf("checksum file '%s' does not start with a SHA-256 checksum", url)
+	}
+	return sum, nil
+}
+
+// loadFetchAuth sets up authentication for the cluster data download from the credential files.
+func (f *TriageFiler) loadFetchAuth() error {
+	if f.fetchTokenFile != "" && f.fetchBasicAuthFile != "" {
+		return errors.New("only one of '--triage-fetch-token-file' and '--triage-fetch-basic-auth-file' may be specified")
+	}
+	if f.fetchTokenFile != "" {
+		b, err := ioutil.ReadFile(f.fetchTokenFile)
+		if err != nil {
+			return fmt.Errorf("failed to read token file '%s': %v", f.fetchTokenFile, err)
+		}
+		f.fetch.Auth = BearerAuth(strings.TrimSpace(string(b)))
+	}
+	if f.fetchBasicAuthFile != "" {
+		b, err := ioutil.ReadFile(f.fetchBasicAuthFile)
+		if err != nil {
+			return fmt.Errorf("failed to read basic auth file '%s': %v", f.fetchBasicAuthFile, err)
+		}
+		parts := strings.SplitN(strings.TrimSpace(string(b)), ":", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("basic auth file '%s' must contain 'user:password'", f.fetchBasicAuthFile)
+		}
+		f.fetch.Auth = BasicAuth(parts[0], parts[1])
+	}
+	if f.fetchS3File != "" {
+		b, err := ioutil.ReadFile(f.fetchS3File)
+		if err != nil {
+			return fmt.Errorf("failed to read S3 credentials file '%s': %v", f.fetchS3File, err)
+		}
+		if err := json.Unmarshal(b, &f.fetch.S3); err != nil {
+			return fmt.Errorf("failed to parse S3 credentials file '%s': %v", f.fetchS3File, err)
+		}
+	}
+	return nil
+}
+
+// triageData holds the parts of the JSON triage data that are used. It is read by parseTriageData,
+// and the json tags name the keys it reads.
 type triageData struct {
 	Builds struct {
 		Cols struct {
-			Elapsed     []int    `json:"elapsed"`
-			Executor    []string `json:"executor"`
-			PR          []string `json:"pr"`
-			Result      []string `json:"result"`
-			Started     []int64  `json:"started"`
-			TestsFailed []int    `json:"tests_failed"`
-			TestsRun    []int    `json:"tests_run"`
+			Result  []string `json:"result"`
+			Started []int64  `json:"started"`
 		} `json:"cols"`
-		JobsRaw  map[string]interface{} `json:"jobs"` // []int or map[string]int
-		Jobs     map[string]BuildIndexer
-		JobPaths map[string]string `json:"job_paths"`
+		Jobs     map[string]BuildIndexer `json:"jobs"` // [3]int or map[string]int in the JSON
+		JobPaths map[string]string       `json:"job_paths"`
 	} `json:"builds"`
 	Clustered []*Cluster `json:"clustered"`
+
+	// malformed describes the clusters that parseTriageData dropped for holding values of the wrong
+	// type, for filterAndValidate to report.
+	malformed []string
 }
 
 // Cluster holds information about a failure cluster.
@@ -114,6 +623,13 @@ type Cluster struct {
 	totalBuilds int
 	totalJobs   int
 	totalTests  int
+	// longWindowBuilds is the number of builds that failed in the long window, if there is one.
+	longWindowBuilds int
+	// excerpts are the junit failure messages of the cluster's tests in its latest failed builds.
+	excerpts []*junitExcerpt
+	// trackedBy is the number of an open issue not filed by this bot that already tracks the
+	// cluster, or 0.
+	trackedBy int
 }
 
 // Test holds a name and list of jobs
@@ -128,76 +644,200 @@ type Job struct {
 	Builds []int  `json:"builds"`
 }
 
+// maxMalformedErrors is the number of malformed clusters described in a malformedClustersError.
+const maxMalformedErrors = 10
+
+// malformedClustersError reports the clusters that were skipped because their entries in the triage
+// data are malformed. The rest of the clusters are still loaded.
+type malformedClustersError struct {
+	errs []string
+}
+
+func (e *malformedClustersError) Error() string {
+	errs := e.errs
+	more := ""
+	if len(errs) > maxMalformedErrors {
+		more = fmt.Sprintf("; and %d more", len(errs)-maxMalformedErrors)
+		errs = errs[:maxMalformedErrors]
+	}
+	return fmt.Sprintf("skipped %d malformed clusters: %s%s", len(e.errs), strings.Join(errs, "; "), more)
+}
+
 // filterAndValidate removes failure data that falls outside the time window and ensures that cluster
 // data is well formed. It also removes data for PR jobs so that only post-submit failures are considered.
+// Malformed clusters are skipped and reported by the *malformedClustersError that is returned.
 func (f *TriageFiler) filterAndValidate(windowDays int) error {
-	f.latestStart = int64(0)
-	for _, start := range f.data.Builds.Cols.Started {
-		if start > f.latestStart {
-			f.latestStart = start
-		}
+	var cutoffTime, longCutoff int64
+	f.latestStart, cutoffTime, longCutoff = f.window(f.data.Builds.Cols.Started, windowDays)
+	f.flakeRates = nil
+	if f.flakeRateWeeks > 0 {
+		f.flakeRates = f.computeFlakeRates(f.windowTime(f.latestStart).AddDate(0, 0, -7*f.flakeRateWeeks).Unix())
 	}
-	cutoffTime := time.Unix(f.latestStart, 0).AddDate(0, 0, -windowDays).Unix()
 
 	validClusts := []*Cluster{}
+	errs := append([]string(nil), f.data.malformed...)
+	f.implausible = implausibleBuilds{}
+	maxStart := latestPlausibleStart()
 	for clustIndex, clust := range f.data.Clustered {
-		if len(clust.Identifier) == 0 {
-			return fmt.Errorf("the cluster at index %d in the triage JSON data does not specify an ID", clustIndex)
+		valid, err := f.filterCluster(clust, clustIndex, cutoffTime, longCutoff, maxStart)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
 		}
-		if clust.Tests == nil {
-			return fmt.Errorf("cluster '%s' does not have a 'tests' key", clust.Identifier)
+		if valid {
+			validClusts = append(validClusts, clust)
 		}
-		validTests := []*Test{}
-		for _, test := range clust.Tests {
-			if len(test.Name) == 0 {
-				return fmt.Errorf("cluster '%s' contains a test without a name", clust.Identifier)
+	}
+	f.data.Clustered = validClusts
+	// Builds with implausible start times aren't counted, but are reported instead of being
+	// silently dropped with the builds that are outside the window.
+	implausibleGauge.Set(float64(f.implausible.count()))
+	if len(f.implausible) > 0 {
+		glog.Warningf("Left out of the window: %v", f.implausible)
+	}
+	if len(errs) > 0 {
+		return &malformedClustersError{errs: errs}
+	}
+	return nil
+}
+
+// filterCluster removes the failures of a cluster that fall outside the time window or that started
+// at implausible times after maxStart or long ago, and returns false if none are left. An error is
+// returned if the cluster is malformed.
+func (f *TriageFiler) filterCluster(clust *Cluster, clustIndex int, cutoffTime, longCutoff, maxStart int64) (bool, error) {
+	if len(clust.Identifier) == 0 {
+		return false, fmt.Errorf("the cluster at index %d in the triage JSON data does not specify an ID", clustIndex)
+	}
+	if clust.Tests == nil {
+		return false, fmt.Errorf("cluster '%s' does not have a 'tests' key", clust.Identifier)
+	}
+	validTests := []*Test{}
+	// A build can fail several of the cluster's tests, so it is only counted once.
+	longBuilds := map[string]map[int]bool{}
+	for _, test := range clust.Tests {
+		if len(test.Name) == 0 {
+			return false, fmt.Errorf("cluster '%s' contains a test without a name", clust.Identifier)
+		}
+		if test.Jobs == nil {
+			return false, fmt.Errorf("cluster '%s' does not have a 'jobs' key", clust.Identifier)
+		}
+		validJobs := []*Job{}
+		for _, job := range test.Jobs {
+			if len(job.Name) == 0 {
+				return false, fmt.Errorf("cluster '%s' contains a job without a name under test '%s'", clust.Identifier, test.Name)
 			}
-			if test.Jobs == nil {
-				return fmt.Errorf("cluster '%s' does not have a 'jobs' key", clust.Identifier)
+			// Filter out PR jobs
+			if strings.HasPrefix(job.Name, "pr:") {
+				continue
 			}
-			validJobs := []*Job{}
-			for _, job := range test.Jobs {
-				if len(job.Name) == 0 {
-					return fmt.Errorf("cluster '%s' contains a job without a name under test '%s'", clust.Identifier, test.Name)
+			if len(job.Builds) == 0 {
+				return false, fmt.Errorf("cluster '%s' contains job '%s' under test '%s' with no failing builds", clust.Identifier, job.Name, test.Name)
+			}
+			validBuilds := []int{}
+			rowMap, ok := f.data.Builds.Jobs[job.Name]
+			if !ok {
+				return false, fmt.Errorf("triage json data does not contain buildnum to row index mapping for job '%s'", job.Name)
+			}
+			for _, buildnum := range job.Builds {
+				row, err := rowMap.rowForBuild(buildnum)
+				if err != nil {
+					return false, fmt.Errorf("cluster '%s': %v", clust.Identifier, err)
+				}
+				if row < 0 || row >= len(f.data.Builds.Cols.Started) {
+					return false, fmt.Errorf("cluster '%s': row %d of build %d of job '%s' is out of range", clust.Identifier, row, buildnum, job.Name)
 				}
-				// Filter out PR jobs
-				if strings.HasPrefix(job.Name, "pr:") {
+				start := f.data.Builds.Cols.Started[row]
+				if !plausibleStart(start, maxStart) {
+					f.implausible.add(job.Name, buildnum, start)
 					continue
 				}
-				if len(job.Builds) == 0 {
-					return fmt.Errorf("cluster '%s' contains job '%s' under test '%s' with no failing builds", clust.Identifier, job.Name, test.Name)
+				if start > f.latestStart {
+					continue
 				}
-				validBuilds := []int{}
-				rowMap, ok := f.data.Builds.Jobs[job.Name]
-				if !ok {
-					return fmt.Errorf("triage json data does not contain buildnum to row index mapping for job '%s'", job.Name)
+				if start > cutoffTime {
+					validBuilds = append(validBuilds, buildnum)
 				}
-				for _, buildnum := range job.Builds {
-					row, err := rowMap.rowForBuild(buildnum)
-					if err != nil {
-						return err
+				if start > longCutoff {
+					if longBuilds[job.Name] == nil {
+						longBuilds[job.Name] = map[int]bool{}
 					}
-					if f.data.Builds.Cols.Started[row] > cutoffTime {
-						validBuilds = append(validBuilds, buildnum)
-					}
-				}
-				if len(validBuilds) > 0 {
-					job.Builds = validBuilds
-					validJobs = append(validJobs, job)
+					longBuilds[job.Name][buildnum] = true
 				}
 			}
-			if len(validJobs) > 0 {
-				test.Jobs = validJobs
-				validTests = append(validTests, test)
+			if len(validBuilds) > 0 {
+				job.Builds = validBuilds
+				validJobs = append(validJobs, job)
 			}
 		}
-		if len(validTests) > 0 {
-			clust.Tests = validTests
-			validClusts = append(validClusts, clust)
+		if len(validJobs) > 0 {
+			test.Jobs = validJobs
+			validTests = append(validTests, test)
 		}
 	}
-	f.data.Clustered = validClusts
-	return nil
+	clust.longWindowBuilds = 0
+	for _, builds := range longBuilds {
+		clust.longWindowBuilds += len(builds)
+	}
+	if len(validTests) == 0 {
+		return false, nil
+	}
+	clust.Tests = validTests
+	return true, nil
+}
+
+// window returns the start time of the latest build to consider, and the start times after which
+// builds are in the window of windowDays and in the long window, given the start times of all builds.
+// Implausible start times don't move the end of the window.
+func (f *TriageFiler) window(started []int64, windowDays int) (latest, cutoff, longCutoff int64) {
+	latest = f.windowEnd
+	if latest == 0 {
+		maxStart := latestPlausibleStart()
+		for _, start := range started {
+			if start > latest && plausibleStart(start, maxStart) {
+				latest = start
+			}
+		}
+	}
+	if f.boundary != nil {
+		end := f.windowTime(latest)
+		aligned := time.Date(end.Year(), end.Month(), end.Day(), f.boundary.Hour(), f.boundary.Minute(), 0, 0, end.Location())
+		if aligned.After(end) {
+			aligned = aligned.AddDate(0, 0, -1)
+		}
+		latest = aligned.Unix()
+	}
+	cutoff = f.windowTime(latest).AddDate(0, 0, -windowDays).Unix()
+	longCutoff = cutoff
+	if f.longWindowDays > windowDays {
+		longCutoff = f.windowTime(latest).AddDate(0, 0, -f.longWindowDays).Unix()
+	}
+	return latest, cutoff, longCutoff
+}
+
+// windowTime converts a unix time to a time in the timezone of the window.
+func (f *TriageFiler) windowTime(unix int64) time.Time {
+	loc := f.windowLoc
+	if loc == nil {
+		loc = time.UTC
+	}
+	return time.Unix(unix, 0).In(loc)
+}
+
+// windowZone parses '--triage-window-timezone' and '--triage-window-boundary'. The boundary is nil
+// if the window ends at the latest build.
+func (f *TriageFiler) windowZone() (*time.Location, *time.Time, error) {
+	loc, err := time.LoadLocation(f.windowTimezone)
+	if err != nil {
+		return nil, nil, fmt.Errorf("'--triage-window-timezone' is not a known timezone: %v", err)
+	}
+	if f.windowBoundary == "" {
+		return loc, nil, nil
+	}
+	boundary, err := time.Parse("15:04", f.windowBoundary)
+	if err != nil {
+		return nil, nil, fmt.Errorf("'--triage-window-boundary' must be a time of day like '00:00', got '%s'", f.windowBoundary)
+	}
+	return loc, &boundary, nil
 }
 
 // BuildIndexer is an interface that describes the buildnum to row index mapping used to retrieve data
@@ -205,6 +845,8 @@ func (f *TriageFiler) filterAndValidate(windowDays int) error {
 // This is an interface because the JSON format describing failure clusters has 2 ways of recording the mapping info.
 type BuildIndexer interface {
 	rowForBuild(buildnum int) (int, error)
+	// buildRows returns the row index of every build of the job, keyed by build number.
+	buildRows() map[int]int
 }
 
 // ContigIndexer is a BuildIndexer implementation for when the buildnum to row index mapping describes
@@ -220,34 +862,66 @@ func (rowMap ContigIndexer) rowForBuild(buildnum int) (int, error) {
 	return buildnum - rowMap.startBuild + rowMap.startRow, nil
 }
 
+func (rowMap ContigIndexer) buildRows() map[int]int {
+	rows := make(map[int]int, rowMap.count)
+	for i := 0; i < rowMap.count; i++ {
+		rows[rowMap.startBuild+i] = rowMap.startRow + i
+	}
+	return rows
+}
+
 // DictIndexer is a BuildIndexer implementation for when the buildnum to row index mapping is simply a dictionary.
-// The value type of this dictionary is interface instead of int so that we don't have to convert the original map.
-type DictIndexer map[string]interface{}
+type DictIndexer map[int]int
 
 func (rowMap DictIndexer) rowForBuild(buildnum int) (int, error) {
-	row, ok := rowMap[strconv.Itoa(buildnum)]
+	row, ok := rowMap[buildnum]
 	if !ok {
 		return 0, fmt.Errorf("failed to find row in JSON for buildnumber: %d. Row mapping or buildnumber is invalid", buildnum)
 	}
-	var irow float64
-	if irow, ok = row.(float64); !ok {
-		return 0, fmt.Errorf("failed to find row in JSON for buildnumber: %d. Row mapping contains invalid type", buildnum)
+	return row, nil
+}
+
+func (rowMap DictIndexer) buildRows() map[int]int {
+	return rowMap
+}
+
+// windowLabel is a short description of a window of days, e.g. "24h" or "7d".
+func windowLabel(days int) string {
+	if days == 1 {
+		return "24h"
 	}
-	return int(irow), nil
+	return fmt.Sprintf("%dd", days)
 }
 
 // loadClusters parses and filters the json data, then populates every Cluster struct with
 // aggregated job data and totals. The job data specifies all jobs that failed in a cluster and the
 // builds that failed for each job, independent of which tests the jobs or builds failed.
+// Malformed clusters are skipped, and the valid clusters are returned with a *malformedClustersError
+// that reports them.
 func (f *TriageFiler) loadClusters(jsonIn []byte) ([]*Cluster, error) {
+	return f.loadClustersFrom(bytes.NewReader(jsonIn))
+}
+
+// loadClustersFrom is loadClusters for triage data that is read from r.
+func (f *TriageFiler) loadClustersFrom(r io.Reader) ([]*Cluster, error) {
+	defer creator.TrackAllocs("load_clusters")()
 	var err error
-	f.data, err = parseTriageData(jsonIn)
+	// Builds that are in neither window nor the flake rate weeks are dropped as they are read, so
+	// that long histories aren't kept in memory only to be filtered out.
+	f.data, err = parseTriageData(r, func(started []int64) (int64, int64) {
+		latest, _, earliest := f.window(started, f.windowDays)
+		if f.flakeRateWeeks > 0 {
+			if rateCutoff := f.windowTime(latest).AddDate(0, 0, -7*f.flakeRateWeeks).Unix(); rateCutoff < earliest {
+				earliest = rateCutoff
+			}
+		}
+		return earliest, latest
+	})
 	if err != nil {
 		return nil, err
 	}
-	if err = f.filterAndValidate(f.windowDays); err != nil {
-		return nil, err
-	}
+	// Malformed clusters are reported after the rest are loaded.
+	malformed := f.filterAndValidate(f.windowDays)
 
 	// Aggregate failing builds in each cluster by job (independent of tests).
 	for _, clust := range f.data.Clustered {
@@ -277,48 +951,7 @@ func (f *TriageFiler) loadClusters(jsonIn []byte) ([]*Cluster, error) {
 			clust.totalBuilds += len(builds)
 		}
 	}
-	return f.data.Clustered, nil
-}
-
-// parseTriageData unmarshals raw json data into a triageData struct and creates a BuildIndexer for
-// every job.
-func parseTriageData(jsonIn []byte) (*triageData, error) {
-	var data triageData
-	if err := json.Unmarshal(jsonIn, &data); err != nil {
-		return nil, err
-	}
-
-	if data.Builds.Cols.Started == nil {
-		return nil, fmt.Errorf("triage data json is missing the builds.cols.started key")
-	}
-	if data.Builds.JobsRaw == nil {
-		return nil, fmt.Errorf("triage data is missing the builds.jobs key")
-	}
-	if data.Builds.JobPaths == nil {
-		return nil, fmt.Errorf("triage data is missing the builds.job_paths key")
-	}
-	if data.Clustered == nil {
-		return nil, fmt.Errorf("triage data is missing the clustered key")
-	}
-	// Populate 'Jobs' with the BuildIndexer for each job.
-	data.Builds.Jobs = make(map[string]BuildIndexer)
-	for jobID, mapper := range data.Builds.JobsRaw {
-		switch mapper := mapper.(type) {
-		case []interface{}:
-			// In this case mapper is a 3 member array. 0:first buildnum, 1:number of builds, 2:start index.
-			data.Builds.Jobs[jobID] = ContigIndexer{
-				startBuild: int(mapper[0].(float64)),
-				count:      int(mapper[1].(float64)),
-				startRow:   int(mapper[2].(float64)),
-			}
-		case map[string]interface{}:
-			// In this case mapper is a dictionary.
-			data.Builds.Jobs[jobID] = DictIndexer(mapper)
-		default:
-			return nil, fmt.Errorf("the build number to row index mapping for job '%s' is not an accepted type. Type is: %v", jobID, reflect.TypeOf(mapper))
-		}
-	}
-	return &data, nil
+	return f.data.Clustered, malformed
 }
 
 // topClusters gets the 'count' most important clusters from a slice of clusters based on number of build failures.
@@ -343,7 +976,7 @@ func (c *Cluster) topTestsFailed(count int) []*Test {
 	return c.Tests[0:count]
 }
 
-// topJobsFailed returns the top 'count' job names sorted by number of failing builds.
+// topJobsFailed returns the top 'count' job names sorted by number of failing builds, and then by name.
 func (c *Cluster) topJobsFailed(count int) []*Job {
 	slice := make([]*Job, len(c.jobs))
 	i := 0
@@ -351,8 +984,13 @@ func (c *Cluster) topJobsFailed(count int) []*Job {
 		slice[i] = &Job{Name: jobName, Builds: builds}
 		i++
 	}
-	less := func(i, j int) bool { return len(slice[i].Builds) > len(slice[j].Builds) }
-	sort.SliceStable(slice, less)
+	less := func(i, j int) bool {
+		if len(slice[i].Builds) != len(slice[j].Builds) {
+			return len(slice[i].Builds) > len(slice[j].Builds)
+		}
+		return slice[i].Name < slice[j].Name
+	}
+	sort.Slice(slice, less)
 
 	if len(slice) < count {
 		count = len(slice)
@@ -360,10 +998,34 @@ func (c *Cluster) topJobsFailed(count int) []*Job {
 	return slice[0:count]
 }
 
+// latestBuild returns the number and start time of the job's most recent failed build.
+func (c *Cluster) latestBuild(job *Job) (int, int64) {
+	latest := 0
+	latestTime := int64(0)
+	rowMap := c.filer.data.Builds.Jobs[job.Name]
+	for _, build := range job.Builds {
+		row, _ := rowMap.rowForBuild(build) // Already validated start time lookup for all builds.
+		buildTime := c.filer.data.Builds.Cols.Started[row]
+		if buildTime > latestTime {
+			latestTime = buildTime
+			latest = build
+		}
+	}
+	return latest, latestTime
+}
+
 // Title is the string to use as the github issue title.
 func (c *Cluster) Title() string {
-	return fmt.Sprintf("Failure cluster [%s...] failed %d builds, %d jobs, and %d tests over %d days",
+	// Name the job that failed the most builds if it has a display name.
+	where := ""
+	if top := c.topJobsFailed(1); len(top) > 0 {
+		if name := c.filer.jobDisplayName(top[0].Name); name != top[0].Name {
+			where = " in " + name
+		}
+	}
+	return fmt.Sprintf("Failure cluster [%s...]%s failed %d builds, %d jobs, and %d tests over %d days",
 		c.Identifier[0:6],
+		where,
 		c.totalBuilds,
 		c.totalJobs,
 		c.totalTests,
@@ -376,11 +1038,14 @@ func (c *Cluster) Title() string {
 // that contain ID() in their body.
 // If Body returns an empty string no issue is created.
 func (c *Cluster) Body(closedIssues []*githubapi.Issue) string {
+	if c.trackedBy != 0 {
+		return ""
+	}
 	// First check that the most recently closed issue (if any exist) was closed
-	// before the start of the sliding window.
-	cutoffTime := time.Unix(c.filer.latestStart, 0).AddDate(0, 0, -c.filer.windowDays)
+	// before the start of the sliding window, and that no one opted the cluster out.
+	cutoffTime := c.filer.windowTime(c.filer.latestStart).AddDate(0, 0, -c.filer.windowDays)
 	for _, closed := range closedIssues {
-		if closed.ClosedAt.After(cutoffTime) {
+		if closed.ClosedAt.After(cutoffTime) || c.filer.ignored(closed) {
 			return ""
 		}
 	}
@@ -388,36 +1053,62 @@ func (c *Cluster) Body(closedIssues []*githubapi.Issue) string {
 	var buf bytes.Buffer
 	fmt.Fprintf(&buf, "### Failure cluster [%s](%s#%s)\n", c.ID(), triageURL, c.Identifier)
 	fmt.Fprintf(&buf, "##### Error text:\n```\n%s\n```\n", c.Text)
-	// cluster stats
-	fmt.Fprint(&buf, "##### Failure cluster statistics:\n")
-	fmt.Fprintf(&buf, "%d tests failed,    %d jobs failed,    %d builds failed.\n", c.totalTests, c.totalJobs, c.totalBuilds)
-	fmt.Fprintf(&buf, "Failure stats cover %d day time range '%s' to '%s'.\n##### Top failed tests by jobs failed:\n",
+	infra := c.infraMatch()
+	if infra != "" {
+		fmt.Fprintf(&buf, "This looks like an infra flake rather than a product flake: the error text matches `%s`.\n", infra)
+	}
+	// cluster stats, in a section so that refreshing them keeps edits to the rest of the body
+	var stats bytes.Buffer
+	fmt.Fprint(&stats, "##### Failure cluster statistics:\n")
+	fmt.Fprintf(&stats, "%d tests failed,    %d jobs failed,    %d builds failed.\n", c.totalTests, c.totalJobs, c.totalBuilds)
+	if c.filer.longWindowDays > c.filer.windowDays {
+		fmt.Fprintf(&stats, "%d failures in the last %s, %d in the last %s.\n",
+			c.totalBuilds, windowLabel(c.filer.windowDays), c.longWindowBuilds, windowLabel(c.filer.longWindowDays))
+	}
+	fmt.Fprintf(&stats, "Failure stats cover %d day time range '%s' to '%s'.\n##### Top failed tests by jobs failed:\n",
 		c.filer.windowDays,
 		cutoffTime.Format(timeFormat),
-		time.Unix(c.filer.latestStart, 0).Format(timeFormat))
+		c.filer.windowTime(c.filer.latestStart).Format(timeFormat))
 	// top tests failed
-	fmt.Fprint(&buf, "\n| Test Name | Jobs Failed |\n| --- | --- |\n")
-	for _, test := range c.topTestsFailed(topTestsCount) {
-		fmt.Fprintf(&buf, "| %s | %d |\n", test.Name, len(test.Jobs))
+	if c.filer.flakeRates == nil {
+		fmt.Fprint(&stats, "\n| Test Name | Jobs Failed |\n| --- | --- |\n")
+		for _, test := range c.topTestsFailed(topTestsCount) {
+			fmt.Fprintf(&stats, "| %s | %d |\n", test.Name, len(test.Jobs))
+		}
+	} else {
+		fmt.Fprintf(&stats, "\n| Test Name | Jobs Failed | Failure Rate (%d weeks) |\n| --- | --- | --- |\n", c.filer.flakeRateWeeks)
+		for _, test := range c.topTestsFailed(topTestsCount) {
+			// Failures older than the rate window leave tests without runs in it.
+			if rate := c.filer.flakeRates[test.Name]; rate.Runs == 0 {
+				fmt.Fprintf(&stats, "| %s | %d | n/a |\n", test.Name, len(test.Jobs))
+			} else {
+				fmt.Fprintf(&stats, "| %s | %d | %.1f%% (%d/%d runs) |\n", test.Name, len(test.Jobs), 100*rate.Rate(), rate.Failures, rate.Runs)
+			}
+		}
 	}
 	// top jobs failed
-	fmt.Fprint(&buf, "\n##### Top failed jobs by builds failed:\n")
-	fmt.Fprint(&buf, "\n| Job Name | Builds Failed | Latest Failure |\n| --- | --- | --- |\n")
+	fmt.Fprint(&stats, "\n##### Top failed jobs by builds failed:\n")
+	fmt.Fprint(&stats, "\n| Job Name | Builds Failed | Latest Failure |\n| --- | --- | --- |\n")
 	for _, job := range c.topJobsFailed(topJobsCount) {
-		latest := 0
-		latestTime := int64(0)
-		rowMap := c.filer.data.Builds.Jobs[job.Name]
-		for _, build := range job.Builds {
-			row, _ := rowMap.rowForBuild(build) // Already validated start time lookup for all builds.
-			buildTime := c.filer.data.Builds.Cols.Started[row]
-			if buildTime > latestTime {
-				latestTime = buildTime
-				latest = build
-			}
-		}
+		latest, latestTime := c.latestBuild(job)
 		path := strings.TrimPrefix(c.filer.data.Builds.JobPaths[job.Name], "gs://")
-		fmt.Fprintf(&buf, "| %s | %d | [%s](https://prow.k8s.io/view/gcs/%s/%d) |\n", job.Name, len(job.Builds), time.Unix(latestTime, 0).Format(timeFormat), path, latest)
+		fmt.Fprintf(&stats, "| %s | %d | [%s](https://prow.k8s.io/view/gcs/%s/%d) |\n", c.filer.jobLabel(job.Name), len(job.Builds), c.filer.windowTime(latestTime).Format(timeFormat), path, latest)
+	}
+	if blocking := c.blockingJobs(); len(blocking) > 0 {
+		fmt.Fprint(&stats, "\n##### Blocking jobs affected:\n")
+		for _, job := range blocking {
+			fmt.Fprintf(&stats, "- %s (%s): %d builds failed\n", c.filer.jobLabel(job), c.filer.jobTiers[job], len(c.jobs[job]))
+		}
+	}
+	if quarantined := c.quarantinedTests(); len(quarantined) > 0 {
+		fmt.Fprintf(&stats, "\n##### Quarantined tests:\n%d of the failing tests are skipped or marked %s, so their failures no longer block merges:\n", len(quarantined), flakyTag)
+		for _, test := range quarantined {
+			fmt.Fprintf(&stats, "- %s\n", test)
+		}
 	}
+	fmt.Fprint(&stats, c.excerptsMarkdown())
+	fmt.Fprint(&stats, c.childLinks())
+	buf.WriteString(creator.Section("stats", stats.String()))
 	// previously closed issues if there are any
 	if len(closedIssues) > 0 {
 		fmt.Fprint(&buf, "\n##### Previously closed issues for this cluster:\n")
@@ -427,28 +1118,78 @@ func (c *Cluster) Body(closedIssues []*githubapi.Issue) string {
 		fmt.Fprint(&buf, "\n")
 	}
 
-	// Create /assign command.
+	// Create /assign command. Infra flakes go to the infra oncall instead of the test owners.
 	testNames := make([]string, 0, len(c.Tests))
 	for _, test := range c.topTestsFailed(len(c.Tests)) {
 		testNames = append(testNames, test.Name)
 	}
-	ownersMap := c.filer.creator.TestsOwners(testNames)
-	if len(ownersMap) > 0 {
+	var assign, mention []string
+	if infra != "" {
+		assign, mention = c.filer.infraOncall()
+	} else {
+		assign, mention = c.splitOwners(c.filer.creator.TestsOwners(testNames))
+	}
+	if len(assign) > 0 {
 		fmt.Fprint(&buf, "\n/assign")
-		for user := range ownersMap {
+		for _, user := range assign {
+			fmt.Fprintf(&buf, " @%s", user)
+		}
+		fmt.Fprint(&buf, "\n")
+	}
+	if len(mention) > 0 {
+		fmt.Fprint(&buf, "\ncc")
+		for _, user := range mention {
 			fmt.Fprintf(&buf, " @%s", user)
 		}
 		fmt.Fprint(&buf, "\n")
 	}
 
 	// Explanations of assignees and sigs
-	fmt.Fprint(&buf, c.filer.creator.ExplainTestAssignments(testNames))
+	if infra == "" {
+		fmt.Fprint(&buf, c.filer.creator.ExplainTestAssignments(testNames))
+	}
 
 	fmt.Fprintf(&buf, "\n[Current Status](%s#%s)", triageURL, c.Identifier)
 
 	return buf.String()
 }
 
+// mentionOnly returns true iff the SIG of the test asked for its owners to only be mentioned.
+func (f *TriageFiler) mentionOnly(test string) bool {
+	if f.mentionOnlySIGs == "" {
+		return false
+	}
+	sig := f.creator.TestSIG(test)
+	for _, name := range strings.Split(f.mentionOnlySIGs, ",") {
+		if name = strings.TrimPrefix(strings.TrimSpace(name), "sig/"); name != "" && name == sig {
+			return true
+		}
+	}
+	return false
+}
+
+// splitOwners sorts the owners of the cluster's tests into the users to assign and the users who
+// only opted into tracking all of their tests and are just mentioned.
+func (c *Cluster) splitOwners(ownersMap map[string][]string) (assign, mention []string) {
+	for user, tests := range ownersMap {
+		auto := false
+		for _, test := range tests {
+			if c.filer.creator.TestAutoAssigned(test) && !c.filer.mentionOnly(test) {
+				auto = true
+				break
+			}
+		}
+		if auto {
+			assign = append(assign, user)
+		} else {
+			mention = append(mention, user)
+		}
+	}
+	sort.Strings(assign)
+	sort.Strings(mention)
+	return assign, mention
+}
+
 // ID yields the string identifier that uniquely identifies this issue.
 // This ID must appear in the body of the issue.
 // DO NOT CHANGE how this ID is formatted or duplicate issues may be created on github.
@@ -459,12 +1200,19 @@ func (c *Cluster) ID() string {
 // Labels returns the labels to apply to the issue created for this cluster on github.
 func (c *Cluster) Labels() []string {
 	labels := []string{"kind/flake"}
+	if tier := c.tier(); tier != "" {
+		labels = append(labels, "tier/"+tier)
+	}
+	if c.infraMatch() != "" {
+		// The SIGs that own the tests aren't responsible for infra failures.
+		return append(labels, infraFlakeLabel)
+	}
 
 	topTests := make([]string, len(c.Tests))
 	for i, test := range c.topTestsFailed(len(c.Tests)) {
 		topTests[i] = test.Name
 	}
-	for sig := range c.filer.creator.TestsSIGs(topTests) {
+	for _, sig := range creator.SortedKeys(c.filer.creator.TestsSIGs(topTests)) {
 		labels = append(labels, "sig/"+sig)
 	}
 
@@ -482,6 +1230,11 @@ func (c *Cluster) Owners() []string {
 // Priority calculates and returns the priority of this issue.
 // The returned bool indicates if the returned priority is valid and can be used.
 func (c *Cluster) Priority() (string, bool) {
-	// TODO implement priority calcs later.
+	if c.filer.quarantineDemote && len(c.Tests) > 0 && len(c.quarantinedTests()) == len(c.Tests) {
+		return quarantinedPriority, true
+	}
+	if prio, ok := tierPriority[c.tier()]; ok {
+		return prio, true
+	}
 	return "", false
 }
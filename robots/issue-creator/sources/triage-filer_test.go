@@ -18,6 +18,13 @@ package sources
 
 import (
 	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"regexp"
 	"strconv"
 	"strings"
 	"testing"
@@ -200,6 +207,109 @@ func TestTFParserSimple(t *testing.T) {
 	checkCluster(issues[0], t)
 }
 
+func TestTFTopJobsFailedOrder(t *testing.T) {
+	c := &Cluster{jobs: map[string][]int{"job-b": {1}, "job-c": {1, 2}, "job-a": {2}, "job-d": {3}}}
+	var names []string
+	for _, job := range c.topJobsFailed(3) {
+		names = append(names, job.Name)
+	}
+	if expected := []string{"job-c", "job-a", "job-b"}; strings.Join(names, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected jobs sorted by builds failed and then by name %q, got %q", expected, names)
+	}
+}
+
+func TestTFWindowEnd(t *testing.T) {
+	f := NewTestTriageFiler()
+	// End the window at build 52, so the builds of jobname2 are too new and build 41 is still too old.
+	f.windowEnd = buildTimes[52]
+	issues, err := f.loadClusters(json1issue2job2test)
+	if err != nil {
+		t.Fatalf("Error parsing triage data: %v\n", err)
+	}
+	if f.latestStart != buildTimes[52] {
+		t.Errorf("Expected the window to end at %d, got %d", buildTimes[52], f.latestStart)
+	}
+	if len(issues) != 1 {
+		t.Fatalf("Expected 1 issue, got %d", len(issues))
+	}
+	if issues[0].totalBuilds != 3 {
+		t.Errorf("Expected builds 42, 43 and 52 to be in the window, got %d builds", issues[0].totalBuilds)
+	}
+	if issues[0].totalJobs != 1 || len(issues[0].jobs["jobname1"]) != 3 {
+		t.Errorf("Expected only jobname1 to have failed in the window, got %v", issues[0].jobs)
+	}
+}
+
+func TestTFWindowBoundary(t *testing.T) {
+	tests := []struct {
+		name     string
+		loc      *time.Location
+		boundary string
+		end      time.Time
+	}{
+		// The latest build, 144, starts at 23:00 UTC, an hour before latestBuildTime.
+		{name: "no boundary", loc: time.UTC, end: time.Unix(buildTimes[144], 0)},
+		{name: "midnight UTC", loc: time.UTC, boundary: "00:00", end: time.Date(2000, 1, 9, 0, 0, 0, 0, time.UTC)},
+		{name: "boundary at the latest build", loc: time.UTC, boundary: "23:00", end: time.Unix(buildTimes[144], 0)},
+		{name: "midnight EST", loc: time.FixedZone("EST", -5*60*60), boundary: "00:00", end: time.Date(2000, 1, 9, 5, 0, 0, 0, time.UTC)},
+		{name: "morning EST", loc: time.FixedZone("EST", -5*60*60), boundary: "06:00", end: time.Date(2000, 1, 9, 11, 0, 0, 0, time.UTC)},
+	}
+	for _, test := range tests {
+		f := NewTestTriageFiler()
+		f.windowLoc = test.loc
+		if test.boundary != "" {
+			boundary, err := time.Parse("15:04", test.boundary)
+			if err != nil {
+				t.Fatalf("%s: bad boundary: %v", test.name, err)
+			}
+			f.boundary = &boundary
+		}
+		clusters, err := f.loadClusters(json1issue2job2test)
+		if err != nil {
+			t.Fatalf("%s: error parsing triage data: %v", test.name, err)
+		}
+		if f.latestStart != test.end.Unix() {
+			t.Errorf("%s: expected the window to end at %v, got %v", test.name, test.end, f.windowTime(f.latestStart))
+		}
+		// Build 144 is only in the window if it ends at the latest build.
+		expected := 3
+		if test.end.Unix() == buildTimes[144] {
+			expected = 4
+		}
+		if len(clusters) != 1 || clusters[0].totalBuilds != expected {
+			t.Errorf("%s: expected 1 cluster with %d builds, got %d clusters", test.name, expected, len(clusters))
+		}
+	}
+}
+
+func TestTFLongWindow(t *testing.T) {
+	f := NewTestTriageFiler()
+	f.longWindowDays = 14
+	issues, err := f.loadClusters(json1issue2job2test)
+	if err != nil {
+		t.Fatalf("Error parsing triage data: %v\n", err)
+	}
+	if len(issues) != 1 {
+		t.Fatalf("Expected 1 issue, got %d", len(issues))
+	}
+	// Build 41 is outside the 5 day window but inside the 14 day one.
+	if issues[0].longWindowBuilds != 5 {
+		t.Errorf("Expected 5 builds to fail in the long window, got %d", issues[0].longWindowBuilds)
+	}
+	if body := issues[0].Body(nil); !strings.Contains(body, "4 failures in the last 5d, 5 in the last 14d.") {
+		t.Errorf("Expected the body to show the failures in both windows, got:\n%s", body)
+	}
+
+	f.windowDays = 1
+	f.longWindowDays = 1
+	if issues, err = f.loadClusters(json1issue2job2test); err != nil {
+		t.Fatalf("Error parsing triage data: %v\n", err)
+	}
+	if body := issues[0].Body(nil); strings.Contains(body, "failures in the last") {
+		t.Errorf("Expected no long window when it is not longer than the window, got:\n%s", body)
+	}
+}
+
 func checkBuildStart(t *testing.T, f *TriageFiler, jobName string, build int, expected int64) {
 	row, err := f.data.Builds.Jobs[jobName].rowForBuild(build)
 	if err != nil {
@@ -300,6 +410,239 @@ func TestTFOwnersAndSIGs(t *testing.T) {
 	}
 }
 
+// fakeFetcher serves fixed data for any URL and records the URLs it was asked for. URLs ending in
+// checksumSuffix are served sum instead.
+type fakeFetcher struct {
+	data []byte
+	sum  string
+	urls []string
+}
+
+func (f *fakeFetcher) Open(ctx context.Context, url string) (io.ReadCloser, int64, error) {
+	f.urls = append(f.urls, url)
+	data := f.data
+	if strings.HasSuffix(url, checksumSuffix) {
+		data = []byte(f.sum)
+	}
+	return ioutil.NopCloser(bytes.NewReader(data)), int64(len(data)), nil
+}
+
+func TestTFIssuesFetcher(t *testing.T) {
+	f := NewTestTriageFiler()
+	f.dataURL = "https://example.com/failure_data.json"
+	fetcher := &fakeFetcher{data: json1issue2job2test}
+	f.Fetcher = fetcher
+
+	issues, err := f.Issues(context.Background(), f.creator)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(issues) != 1 {
+		t.Errorf("Expected 1 issue from the fetched data, got %d", len(issues))
+	}
+	if len(fetcher.urls) != 1 || fetcher.urls[0] != f.dataURL {
+		t.Errorf("Expected the fetcher to be asked for %q once, got %q", f.dataURL, fetcher.urls)
+	}
+}
+
+func TestTFVerifyChecksum(t *testing.T) {
+	sum := sha256.Sum256(json1issue2job2test)
+	good := hex.EncodeToString(sum[:]) + "  failure_data.json\n"
+	tests := []struct {
+		name string
+		data []byte
+		sum  string
+		err  bool
+	}{
+		{name: "match", data: json1issue2job2test, sum: good},
+		{name: "uppercase", data: json1issue2job2test, sum: strings.ToUpper(good[:64])},
+		{name: "truncated", data: json1issue2job2test[:len(json1issue2job2test)-10], sum: good, err: true},
+		{name: "trailing data", data: append(append([]byte{}, json1issue2job2test...), " "...), sum: good, err: true},
+		{name: "empty checksum", data: json1issue2job2test, sum: "", err: true},
+		{name: "bad checksum", data: json1issue2job2test, sum: "abc  failure_data.json", err: true},
+	}
+	for _, test := range tests {
+		f := NewTestTriageFiler()
+		f.dataURL = "https://example.com/failure_data.json"
+		f.verifyChecksum = true
+		f.Fetcher = &fakeFetcher{data: test.data, sum: test.sum}
+		_, err := f.Issues(context.Background(), f.creator)
+		if test.err && err == nil {
+			t.Errorf("%s: expected an error", test.name)
+		} else if !test.err && err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+		}
+	}
+}
+
+func TestTFMalformedClusters(t *testing.T) {
+	malformed := `"clustered":
+		[
+			{"key": "no id", "tests": [{"name": "testname1", "jobs": [{"name": "jobname1", "builds": [42]}]}], "text": "no id"},
+			{"id": "no_test_name", "tests": [{"jobs": [{"name": "jobname1", "builds": [42]}]}], "text": "no test name"},
+			{"id": "unknown_job", "tests": [{"name": "testname1", "jobs": [{"name": "jobname4", "builds": [42]}]}], "text": "unknown job"},
+			{"id": "null_tests", "tests": null},
+			{"id": "string_build", "tests": [{"name": "testname1", "jobs": [{"name": "jobname1", "builds": [42, "43"]}]}]},
+			{"id": "float_build", "tests": [{"name": "testname1", "jobs": [{"name": "jobname1", "builds": [42.5]}]}]},
+			{"id": "string_job", "tests": [{"name": "testname1", "jobs": ["jobname1"]}]},
+			{"id": "null_job", "tests": [{"name": "testname1", "jobs": [null]}]},
+			{"id": "null_test", "tests": [null]},
+			{"id": 7, "tests": [{"name": "testname1", "jobs": [{"name": "jobname1", "builds": [42]}]}]},
+			null,`
+	data := regexp.MustCompile(`"clustered":\s*\[`).ReplaceAllLiteral(json1issue2job2test, []byte(malformed))
+
+	f := NewTestTriageFiler()
+	clusters, err := f.loadClusters(data)
+	merr, ok := err.(*malformedClustersError)
+	if !ok {
+		t.Fatalf("Expected a *malformedClustersError, got %v", err)
+	}
+	expected := []string{
+		"the cluster at index 0 in the triage JSON data does not specify an ID",
+		"cluster 'no_test_name' contains a test without a name",
+		"does not contain buildnum to row index mapping for job 'jobname4'",
+		"cluster 'null_tests' does not have a 'tests' key",
+		"cluster 'string_build' is malformed: invalid triage data at offset ",
+		"cluster 'float_build' is malformed: invalid triage data at offset ",
+		"cluster 'string_job' is malformed: invalid triage data at offset ",
+		"cluster 'null_job' is malformed: invalid triage data at offset ",
+		"cluster 'null_test' is malformed: invalid triage data at offset ",
+		"the cluster at index 9 in the triage JSON data is malformed: invalid triage data at offset ",
+		"the cluster at index 10 in the triage JSON data is malformed: invalid triage data at offset ",
+	}
+	if len(merr.errs) != len(expected) {
+		t.Fatalf("Expected %d malformed clusters to be reported, got %d: %v", len(expected), len(merr.errs), merr.errs)
+	}
+	for _, want := range expected {
+		found := false
+		for _, e := range merr.errs {
+			found = found || strings.Contains(e, want)
+		}
+		if !found {
+			t.Errorf("Expected a malformed cluster to be reported with %q, got %v", want, merr.errs)
+		}
+	}
+	for _, want := range []string{"expected a number", "number 42.5 is not an integer", "expected an object", "a job is null", "a test is null", "expected a string", "the cluster is null"} {
+		if !strings.Contains(strings.Join(merr.errs, "\n"), want) {
+			t.Errorf("Expected the type error %q to be reported, got %v", want, merr.errs)
+		}
+	}
+	if len(clusters) != 1 || clusters[0].Identifier != "key_hash" {
+		t.Fatalf("Expected only cluster 'key_hash' to be loaded, got %d clusters", len(clusters))
+	}
+	if clusters[0].totalBuilds != 4 {
+		t.Errorf("Expected the valid cluster to have 4 builds, got %d", clusters[0].totalBuilds)
+	}
+
+	// Filing goes on with the valid clusters.
+	f = NewTestTriageFiler()
+	f.dataURL = "https://example.com/failure_data.json"
+	f.Fetcher = &fakeFetcher{data: data}
+	issues, err := f.Issues(context.Background(), f.creator)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(issues) != 1 {
+		t.Errorf("Expected 1 issue from the valid cluster, got %d", len(issues))
+	}
+}
+
+func TestMalformedClustersError(t *testing.T) {
+	var errs []string
+	for i := 0; i < maxMalformedErrors+2; i++ {
+		errs = append(errs, fmt.Sprintf("error %d", i))
+	}
+	msg := (&malformedClustersError{errs: errs}).Error()
+	if !strings.HasPrefix(msg, fmt.Sprintf("skipped %d malformed clusters: error 0; ", len(errs))) {
+		t.Errorf("Unexpected error message %q", msg)
+	}
+	if !strings.HasSuffix(msg, "; and 2 more") || strings.Contains(msg, fmt.Sprintf("error %d", maxMalformedErrors)) {
+		t.Errorf("Expected only %d errors to be listed, got %q", maxMalformedErrors, msg)
+	}
+}
+
+func TestTFMentionsTrackingOwners(t *testing.T) {
+	f := NewTestTriageFiler()
+	var err error
+	f.creator.MaxAssignees = 3
+	f.creator.Owners, err = testowner.NewOwnerListFromCsv(bytes.NewReader([]byte(
+		"name,owner,auto-assigned,sig\ntestname1,cjwagner,1,sigarea\ntestname2,spxtr,0,sigarea\n")))
+	if err != nil {
+		t.Fatalf("Failed to create a new OwnersList.  errmsg: %v", err)
+	}
+	clusters, err := f.loadClusters(json1issue2job2test)
+	if err != nil {
+		t.Fatalf("Failed to load clusters: %v", err)
+	}
+
+	body := clusters[0].Body(nil)
+	if !strings.Contains(body, "\n/assign @cjwagner\n") {
+		t.Errorf("Expected only cjwagner to be assigned in the body of cluster %s:\n%s", clusters[0].Identifier, body)
+	}
+	if !strings.Contains(body, "\ncc @spxtr\n") {
+		t.Errorf("Expected spxtr to be mentioned in the body of cluster %s:\n%s", clusters[0].Identifier, body)
+	}
+}
+
+func TestTFMentionOnlySIGs(t *testing.T) {
+	f := NewTestTriageFiler()
+	var err error
+	f.creator.MaxAssignees = 3
+	f.creator.Owners, err = testowner.NewOwnerListFromCsv(bytes.NewReader([]byte(
+		"name,owner,auto-assigned,sig\ntestname1,cjwagner,1,sigarea\ntestname2,spxtr,1,other\n")))
+	if err != nil {
+		t.Fatalf("Failed to create a new OwnersList.  errmsg: %v", err)
+	}
+	f.mentionOnlySIGs = "sig/other, storage"
+	clusters, err := f.loadClusters(json1issue2job2test)
+	if err != nil {
+		t.Fatalf("Failed to load clusters: %v", err)
+	}
+
+	body := clusters[0].Body(nil)
+	if !strings.Contains(body, "\n/assign @cjwagner\n") {
+		t.Errorf("Expected only cjwagner to be assigned in the body of cluster %s:\n%s", clusters[0].Identifier, body)
+	}
+	if !strings.Contains(body, "\ncc @spxtr\n") {
+		t.Errorf("Expected the owner from a mention-only SIG to be mentioned in the body of cluster %s:\n%s", clusters[0].Identifier, body)
+	}
+}
+
+func TestTFOwnershipGaps(t *testing.T) {
+	f := NewTestTriageFiler()
+	var err error
+	f.creator.Owners, err = testowner.NewOwnerListFromCsv(bytes.NewReader([]byte(
+		"name,owner,auto-assigned,sig\ntestname1,cjwagner,1,\nother,spxtr,1,sigarea\n")))
+	if err != nil {
+		t.Fatalf("Failed to create a new OwnersList.  errmsg: %v", err)
+	}
+	clusters, err := f.loadClusters(json1issue2job2test)
+	if err != nil {
+		t.Fatalf("Failed to load clusters: %v", err)
+	}
+
+	gaps := f.ownershipGapsIssue(clusters)
+	if gaps == nil {
+		t.Fatal("Expected an ownership gaps issue.")
+	}
+	body := gaps.Body(nil)
+	if !strings.Contains(body, gaps.ID()) {
+		t.Errorf("The body text for the ownership gaps issue does not contain its ID!")
+	}
+	if !strings.Contains(body, "##### Tests without an owner (1):\n- testname2\n") {
+		t.Errorf("Body should list 'testname2' as having no owner:\n%s", body)
+	}
+	if !strings.Contains(body, "##### Tests without a SIG (2):\n- testname1\n- testname2\n") {
+		t.Errorf("Body should list 'testname1' and 'testname2' as having no SIG:\n%s", body)
+	}
+
+	// Without owners data there is nothing to report.
+	f.creator.Owners = nil
+	if gaps := f.ownershipGapsIssue(clusters); gaps != nil {
+		t.Errorf("Expected no ownership gaps issue without owners data, got %q.", gaps.Title())
+	}
+}
+
 // TestTFPrevCloseInWindow checks that Cluster issues will abort issue creation by returning an empty
 // body if there is a recently closed issue for the cluster.
 func TestTFPrevCloseInWindow(t *testing.T) {
@@ -323,6 +666,13 @@ func TestTFPrevCloseInWindow(t *testing.T) {
 	if clust.Body(prevIssues) == "" {
 		t.Errorf("Cluster returned an empty issue body when it should have returned a valid body.")
 	}
+
+	// An old closed issue that someone opted out of the bot stops the cluster from being filed.
+	f.ignoreLabel = "triage/handled-offline"
+	prevIssues[0].Labels = []github.Label{{Name: &f.ignoreLabel}}
+	if clust.Body(prevIssues) != "" {
+		t.Errorf("Cluster returned an issue body when a closed issue for the cluster has the ignore label.")
+	}
 }
 
 func checkTopFailingsSorted(issue *Cluster) bool {
@@ -348,3 +698,124 @@ func checkTopTestsFailedSorted(issue *Cluster) bool {
 	}
 	return true
 }
+
+func TestTFValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		filer  TriageFiler
+		errors int
+	}{
+		{name: "valid", filer: TriageFiler{topClustersCount: 3, windowDays: 1, dataURL: clusterDataURL}},
+		{name: "bucket URL", filer: TriageFiler{topClustersCount: 3, windowDays: 1, dataURL: "gs://bucket/failure_data.json"}},
+		{name: "bucket without object", filer: TriageFiler{topClustersCount: 3, windowDays: 1, dataURL: "s3://bucket"}, errors: 1},
+		{name: "missing file", filer: TriageFiler{topClustersCount: 3, windowDays: 1, dataURL: "/no/such/failure_data.json"}, errors: 1},
+		{name: "bad counts", filer: TriageFiler{dataURL: clusterDataURL, longWindowDays: -1}, errors: 3},
+		{name: "bad storm period", filer: TriageFiler{topClustersCount: 3, windowDays: 1, dataURL: clusterDataURL, stormClusters: 5}, errors: 1},
+		{name: "bad timezone", filer: TriageFiler{topClustersCount: 3, windowDays: 1, dataURL: clusterDataURL, windowTimezone: "Mars/Olympus_Mons"}, errors: 1},
+		{name: "bad window boundary", filer: TriageFiler{topClustersCount: 3, windowDays: 1, dataURL: clusterDataURL, windowBoundary: "midnight"}, errors: 1},
+		{name: "bad dedupe rule", filer: TriageFiler{topClustersCount: 3, windowDays: 1, dataURL: clusterDataURL, dedupeAuthors: "bot", dedupeMatch: "title"}, errors: 1},
+		{
+			name: "conflicting auth files",
+			filer: TriageFiler{
+				topClustersCount:   3,
+				windowDays:         1,
+				dataURL:            clusterDataURL,
+				fetchTokenFile:     "/no/such/token",
+				fetchBasicAuthFile: "/no/such/basic-auth",
+			},
+			errors: 3,
+		},
+	}
+	for _, test := range tests {
+		if errs := test.filer.Validate(); len(errs) != test.errors {
+			t.Errorf("%s: expected %d errors, got %v", test.name, test.errors, errs)
+		}
+	}
+}
+
+// benchmarkTriageData generates triage data shaped like production data: every build has all of
+// the columns, half of the jobs map build numbers to rows with a dictionary, and every cluster
+// fails several builds of several jobs in each of its tests.
+func benchmarkTriageData(jobs, builds, clusters int) []byte {
+	var buf bytes.Buffer
+	cols := map[string][]string{}
+	jobRows := make([]string, 0, jobs)
+	jobPaths := make([]string, 0, jobs)
+	for j := 0; j < jobs; j++ {
+		for b := 0; b < builds; b++ {
+			cols["started"] = append(cols["started"], strconv.FormatInt(latestBuildTime-int64(b*3600), 10))
+			cols["elapsed"] = append(cols["elapsed"], "1800")
+			cols["executor"] = append(cols["executor"], `"prow"`)
+			cols["pr"] = append(cols["pr"], `""`)
+			cols["result"] = append(cols["result"], `"FAILURE"`)
+			cols["tests_failed"] = append(cols["tests_failed"], "1")
+			cols["tests_run"] = append(cols["tests_run"], "1000")
+		}
+		start := j * builds
+		if j%2 == 0 {
+			jobRows = append(jobRows, fmt.Sprintf(`"ci-job-%d": [1000, %d, %d]`, j, builds, start))
+		} else {
+			rows := make([]string, builds)
+			for b := range rows {
+				rows[b] = fmt.Sprintf(`"%d": %d`, 1000+b, start+b)
+			}
+			jobRows = append(jobRows, fmt.Sprintf(`"ci-job-%d": {%s}`, j, strings.Join(rows, ", ")))
+		}
+		jobPaths = append(jobPaths, fmt.Sprintf(`"ci-job-%d": "gs://bucket/logs/ci-job-%d"`, j, j))
+	}
+	buf.WriteString(`{"builds": {"cols": {`)
+	first := true
+	for name, values := range cols {
+		if !first {
+			buf.WriteString(", ")
+		}
+		first = false
+		fmt.Fprintf(&buf, `"%s": [%s]`, name, strings.Join(values, ", "))
+	}
+	fmt.Fprintf(&buf, `}, "jobs": {%s}, "job_paths": {%s}}, "clustered": [`, strings.Join(jobRows, ", "), strings.Join(jobPaths, ", "))
+	for c := 0; c < clusters; c++ {
+		if c > 0 {
+			buf.WriteString(", ")
+		}
+		fmt.Fprintf(&buf, `{"id": "cluster%d", "key": "key%d", "text": "error text %d", "tests": [`, c, c, c)
+		for t := 0; t < 3; t++ {
+			if t > 0 {
+				buf.WriteString(", ")
+			}
+			fmt.Fprintf(&buf, `{"name": "test %d of cluster %d", "jobs": [`, t, c)
+			for j := 0; j < 4; j++ {
+				if j > 0 {
+					buf.WriteString(", ")
+				}
+				fmt.Fprintf(&buf, `{"name": "ci-job-%d", "builds": [%d, %d, %d, %d, %d]}`, (c+t+j)%jobs, 1000+t, 1001+t, 1002+t, 1010, 1020)
+			}
+			buf.WriteString("]}")
+		}
+		buf.WriteString("]}")
+	}
+	buf.WriteString("]}")
+	return buf.Bytes()
+}
+
+func BenchmarkLoadClusters(b *testing.B) {
+	data := benchmarkTriageData(300, 300, 2000)
+	b.SetBytes(int64(len(data)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		f := NewTestTriageFiler()
+		if _, err := f.loadClusters(data); err != nil {
+			b.Fatalf("Error parsing triage data: %v", err)
+		}
+	}
+}
+
+func BenchmarkParseTriageData(b *testing.B) {
+	data := benchmarkTriageData(300, 300, 2000)
+	b.SetBytes(int64(len(data)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := parseTriageData(bytes.NewReader(data), nil); err != nil {
+			b.Fatalf("Error parsing triage data: %v", err)
+		}
+	}
+}
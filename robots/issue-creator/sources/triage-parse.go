@@ -0,0 +1,797 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sources
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"sync"
+)
+
+// The triage data is tens of megabytes, and decoding it with reflection into interface{} values
+// for the two encodings of the build number to row mappings dominated each run. It is scanned by
+// hand instead, as it is read, keeping only the keys that are used and skipping the rest without
+// allocating. Like encoding/json, null leaves a value empty, and a value of the wrong type is
+// skipped and reported once the rest of the data is read. Inside a cluster, it only drops that
+// cluster, which is recorded in triageData.malformed.
+
+// readerSize is the size of the buffer that the triage data is read through.
+const readerSize = 64 << 10
+
+// readers holds the buffered readers that the triage data is read through, for reuse between runs.
+var readers = sync.Pool{New: func() interface{} { return bufio.NewReaderSize(nil, readerSize) }}
+
+// triageScanner reads the JSON triage data from a buffered reader.
+type triageScanner struct {
+	r *bufio.Reader
+	// window is the data buffered in r when it was last read, and pos is the number of bytes of it
+	// that were consumed since. They are only discarded from r when the window is used up, as calling
+	// into r for every token dominated the scan.
+	window []byte
+	pos    int
+	// offset is the number of bytes discarded from r, for errors.
+	offset int64
+	// readErr is the error that stopped reading, unless it was the end of the data.
+	readErr error
+	// scratch holds the last string or number read. It is reused for the next one.
+	scratch []byte
+	// typeErr is the first value of the wrong type, which was skipped.
+	typeErr error
+	// rows is the length of the first column read, which the other columns are expected to share.
+	rows int
+	// interned holds the strings that repeat throughout the data, like results and job names.
+	interned map[string]string
+	// triage is the data read so far.
+	triage *triageData
+	// after and until bound the start times of the failed builds that are kept, if filtering.
+	// Builds that started at implausible times, after maxStart or long ago, are kept to be reported.
+	after, until, maxStart int64
+	filtering              bool
+}
+
+func (s *triageScanner) errorf(format string, args ...interface{}) error {
+	if s.readErr != nil {
+		return fmt.Errorf("failed to read triage data at offset %d: %v", s.offset+int64(s.pos), s.readErr)
+	}
+	return fmt.Errorf("invalid triage data at offset %d: %s", s.offset+int64(s.pos), fmt.Sprintf(format, args...))
+}
+
+// invalid records a value of the wrong type, unless one was already recorded.
+func (s *triageScanner) invalid(format string, args ...interface{}) {
+	if s.typeErr == nil {
+		s.typeErr = s.errorf(format, args...)
+	}
+}
+
+// mismatch records that the next value isn't what was expected and skips it.
+func (s *triageScanner) mismatch(expected string) error {
+	s.invalid("expected %s", expected)
+	return s.skip()
+}
+
+// buffered returns the bytes that are read but not consumed yet, reading more if there are none.
+// It returns nothing at the end of the data.
+func (s *triageScanner) buffered() []byte {
+	if s.pos < len(s.window) {
+		return s.window[s.pos:]
+	}
+	s.fill(1)
+	return s.window
+}
+
+// fill discards the consumed bytes from r and reads until at least n bytes are buffered, or the
+// data ends.
+func (s *triageScanner) fill(n int) {
+	s.r.Discard(s.pos)
+	s.offset += int64(s.pos)
+	s.pos = 0
+	if _, err := s.r.Peek(n); err != nil && err != io.EOF && err != bufio.ErrBufferFull && s.readErr == nil {
+		s.readErr = err
+	}
+	s.window, _ = s.r.Peek(s.r.Buffered())
+}
+
+// discard consumes n buffered bytes.
+func (s *triageScanner) discard(n int) {
+	s.pos += n
+}
+
+func (s *triageScanner) skipSpace() {
+	for {
+		b := s.buffered()
+		i := 0
+		for i < len(b) && (b[i] == ' ' || b[i] == '\t' || b[i] == '\n' || b[i] == '\r') {
+			i++
+		}
+		s.discard(i)
+		if i < len(b) || len(b) == 0 {
+			return
+		}
+	}
+}
+
+// peek returns the next non-space byte without consuming it, or 0 at the end of the data.
+func (s *triageScanner) peek() byte {
+	if s.pos < len(s.window) && s.window[s.pos] > ' ' {
+		return s.window[s.pos]
+	}
+	s.skipSpace()
+	b := s.buffered()
+	if len(b) == 0 {
+		return 0
+	}
+	return b[0]
+}
+
+func (s *triageScanner) expect(c byte) error {
+	if s.peek() != c {
+		return s.errorf("expected '%c'", c)
+	}
+	s.discard(1)
+	return nil
+}
+
+// literal consumes the literal true, false or null.
+func (s *triageScanner) literal(word string) error {
+	s.skipSpace()
+	if len(s.window)-s.pos < len(word) {
+		s.fill(len(word))
+	}
+	if b := s.window[s.pos:]; len(b) < len(word) || string(b[:len(word)]) != word {
+		return s.errorf("expected '%s'", word)
+	}
+	s.discard(len(word))
+	return nil
+}
+
+// null consumes a null and returns true if it is the next value.
+func (s *triageScanner) null() (bool, error) {
+	if s.peek() != 'n' {
+		return false, nil
+	}
+	return true, s.literal("null")
+}
+
+// more reports whether an object or array has another element, consuming the separating comma or
+// the closing delimiter. first is true before the first element.
+func (s *triageScanner) more(end byte, first bool) (bool, error) {
+	switch c := s.peek(); {
+	case c == end:
+		s.discard(1)
+		return false, nil
+	case first:
+		return true, nil
+	case c == ',':
+		s.discard(1)
+		return true, nil
+	default:
+		return false, s.errorf("expected ',' or '%c'", end)
+	}
+}
+
+// object calls field with each key of an object, which must consume the key's value. The key is
+// only valid until then. A null object has no keys.
+func (s *triageScanner) object(field func(key []byte) error) error {
+	switch s.peek() {
+	case '{':
+	case 'n':
+		return s.literal("null")
+	default:
+		return s.mismatch("an object")
+	}
+	s.discard(1)
+	for first := true; ; first = false {
+		ok, err := s.more('}', first)
+		if err != nil || !ok {
+			return err
+		}
+		if s.peek() != '"' {
+			return s.errorf("expected a key")
+		}
+		key, err := s.rawString()
+		if err != nil {
+			return err
+		}
+		if err := s.expect(':'); err != nil {
+			return err
+		}
+		if err := field(key); err != nil {
+			return err
+		}
+	}
+}
+
+// array calls elem for each element of an array, which must consume the element. A null array
+// has no elements.
+func (s *triageScanner) array(elem func() error) error {
+	switch s.peek() {
+	case '[':
+	case 'n':
+		return s.literal("null")
+	default:
+		return s.mismatch("an array")
+	}
+	s.discard(1)
+	for first := true; ; first = false {
+		ok, err := s.more(']', first)
+		if err != nil || !ok {
+			return err
+		}
+		if err := elem(); err != nil {
+			return err
+		}
+	}
+}
+
+// rawString consumes a string and returns its bytes between the quotes, still escaped, in scratch.
+// Escape sequences are checked when the string is unescaped or skipped.
+func (s *triageScanner) rawString() ([]byte, error) {
+	if err := s.expect('"'); err != nil {
+		return nil, err
+	}
+	s.scratch = s.scratch[:0]
+	for {
+		b := s.buffered()
+		if len(b) == 0 {
+			return nil, s.errorf("unterminated string")
+		}
+		i := 0
+		for i < len(b) && b[i] != '"' && b[i] != '\\' && b[i] >= 0x20 {
+			i++
+		}
+		s.scratch = append(s.scratch, b[:i]...)
+		if i == len(b) {
+			s.discard(i)
+			continue
+		}
+		switch c := b[i]; {
+		case c == '"':
+			s.discard(i + 1)
+			return s.scratch, nil
+		case c == '\\':
+			s.discard(i + 1)
+			next := s.buffered()
+			if len(next) == 0 {
+				return nil, s.errorf("unterminated string")
+			}
+			s.scratch = append(s.scratch, '\\', next[0])
+			s.discard(1)
+		default:
+			s.discard(i)
+			return nil, s.errorf("invalid character %q in string", c)
+		}
+	}
+}
+
+// unescape returns the string in scratch with its escape sequences replaced.
+func (s *triageScanner) unescape(raw []byte) (string, error) {
+	quoted := make([]byte, 0, len(raw)+2)
+	quoted = append(append(append(quoted, '"'), raw...), '"')
+	// Escaped strings are rare, so they are left to encoding/json.
+	var str string
+	if err := json.Unmarshal(quoted, &str); err != nil {
+		return "", s.errorf("%v", err)
+	}
+	return str, nil
+}
+
+// length estimates the number of elements in the flat array or object that starts at the next byte
+// and ends with end, for preallocating. It only looks at the data that is already buffered.
+func (s *triageScanner) length(end byte) int {
+	s.skipSpace()
+	b := s.buffered()
+	n := bytes.IndexByte(b, end)
+	if n < 0 {
+		return 0
+	}
+	return bytes.Count(b[:n], []byte{','}) + 1
+}
+
+// capacity estimates the length of the column that starts at the next byte, for preallocating.
+func (s *triageScanner) capacity() int {
+	if n := s.length(']'); n > s.rows {
+		return n
+	}
+	return s.rows
+}
+
+// intern returns a string with the contents of raw, reusing an earlier one if possible.
+func (s *triageScanner) intern(raw []byte) string {
+	if str, ok := s.interned[string(raw)]; ok {
+		return str
+	}
+	str := string(raw)
+	s.interned[str] = str
+	return str
+}
+
+// str consumes a string, or a null as "". intern is true for strings that are likely to repeat.
+func (s *triageScanner) str(intern bool) (string, error) {
+	switch s.peek() {
+	case '"':
+	case 'n':
+		return "", s.literal("null")
+	default:
+		return "", s.mismatch("a string")
+	}
+	raw, err := s.rawString()
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case bytes.IndexByte(raw, '\\') >= 0:
+		return s.unescape(raw)
+	case intern:
+		return s.intern(raw), nil
+	default:
+		return string(raw), nil
+	}
+}
+
+func (s *triageScanner) internedString() (string, error) {
+	return s.str(true)
+}
+
+func (s *triageScanner) string() (string, error) {
+	return s.str(false)
+}
+
+// number consumes a JSON number and returns it in scratch.
+func (s *triageScanner) number() ([]byte, error) {
+	s.skipSpace()
+	s.scratch = s.scratch[:0]
+	for {
+		b := s.buffered()
+		i := 0
+		for i < len(b) && (b[i] >= '0' && b[i] <= '9' || b[i] == '-' || b[i] == '+' || b[i] == '.' || b[i] == 'e' || b[i] == 'E') {
+			i++
+		}
+		s.scratch = append(s.scratch, b[:i]...)
+		s.discard(i)
+		if i < len(b) || len(b) == 0 {
+			break
+		}
+	}
+	if !validNumber(s.scratch) {
+		return nil, s.errorf("invalid number '%s'", s.scratch)
+	}
+	return s.scratch, nil
+}
+
+// validNumber returns true iff num follows the JSON grammar for numbers.
+func validNumber(num []byte) bool {
+	i := 0
+	if i < len(num) && num[i] == '-' {
+		i++
+	}
+	digits := func() int {
+		start := i
+		for i < len(num) && num[i] >= '0' && num[i] <= '9' {
+			i++
+		}
+		return i - start
+	}
+	if i < len(num) && num[i] == '0' {
+		i++
+	} else if digits() == 0 {
+		return false
+	}
+	if i < len(num) && num[i] == '.' {
+		i++
+		if digits() == 0 {
+			return false
+		}
+	}
+	if i < len(num) && (num[i] == 'e' || num[i] == 'E') {
+		i++
+		if i < len(num) && (num[i] == '+' || num[i] == '-') {
+			i++
+		}
+		if digits() == 0 {
+			return false
+		}
+	}
+	return i == len(num)
+}
+
+// int consumes an integer, or a null as 0. Like encoding/json, numbers with a fraction or exponent
+// and numbers that overflow are of the wrong type.
+func (s *triageScanner) int() (int64, error) {
+	switch c := s.peek(); {
+	case c == '-' || c >= '0' && c <= '9':
+	case c == 'n':
+		return 0, s.literal("null")
+	default:
+		return 0, s.mismatch("a number")
+	}
+	num, err := s.number()
+	if err != nil {
+		return 0, err
+	}
+	digits := num
+	if digits[0] == '-' {
+		digits = digits[1:]
+	}
+	var n int64
+	for _, d := range digits {
+		if d < '0' || d > '9' {
+			s.invalid("number %s is not an integer", num)
+			return 0, nil
+		}
+		if n > (math.MaxInt64-int64(d-'0'))/10 {
+			s.invalid("number %s overflows", num)
+			return 0, nil
+		}
+		n = n*10 + int64(d-'0')
+	}
+	if num[0] == '-' {
+		n = -n
+	}
+	return n, nil
+}
+
+// skip consumes any value, checking that it is valid JSON.
+func (s *triageScanner) skip() error {
+	switch c := s.peek(); {
+	case c == '{':
+		return s.object(func([]byte) error { return s.skip() })
+	case c == '[':
+		return s.array(s.skip)
+	case c == '"':
+		raw, err := s.rawString()
+		if err == nil && bytes.IndexByte(raw, '\\') >= 0 {
+			_, err = s.unescape(raw)
+		}
+		return err
+	case c == '-' || c >= '0' && c <= '9':
+		_, err := s.number()
+		return err
+	case c == 't':
+		return s.literal("true")
+	case c == 'f':
+		return s.literal("false")
+	case c == 'n':
+		return s.literal("null")
+	case c == 0:
+		return s.errorf("unexpected end of data")
+	default:
+		return s.errorf("unexpected '%c'", c)
+	}
+}
+
+// ints consumes an array of integers, or a null as nil.
+func (s *triageScanner) ints() ([]int, error) {
+	if null, err := s.null(); null || err != nil {
+		return nil, err
+	}
+	values := make([]int, 0, s.length(']'))
+	err := s.array(func() error {
+		n, err := s.int()
+		values = append(values, int(n))
+		return err
+	})
+	return values, err
+}
+
+// int64s consumes an array of integers, or a null as nil.
+func (s *triageScanner) int64s() ([]int64, error) {
+	if null, err := s.null(); null || err != nil {
+		return nil, err
+	}
+	values := make([]int64, 0, s.capacity())
+	err := s.array(func() error {
+		n, err := s.int()
+		values = append(values, n)
+		return err
+	})
+	return values, err
+}
+
+// strings consumes an array of strings, or a null as nil.
+func (s *triageScanner) strings() ([]string, error) {
+	if null, err := s.null(); null || err != nil {
+		return nil, err
+	}
+	values := make([]string, 0, s.capacity())
+	err := s.array(func() error {
+		str, err := s.internedString()
+		values = append(values, str)
+		return err
+	})
+	return values, err
+}
+
+// buildIndexer reads the build number to row mapping of a job, which is either an array of the
+// first build number, the number of builds and the first row, or an object from build numbers to rows.
+func (s *triageScanner) buildIndexer(job string) (BuildIndexer, error) {
+	switch s.peek() {
+	case '[':
+		values, err := s.ints()
+		if err != nil {
+			return nil, err
+		}
+		if len(values) != 3 {
+			return nil, fmt.Errorf("the build number to row index mapping for job '%s' has %d values instead of 3", job, len(values))
+		}
+		return ContigIndexer{startBuild: values[0], count: values[1], startRow: values[2]}, nil
+	case '{':
+		rows := make(DictIndexer, s.length('}'))
+		err := s.object(func(key []byte) error {
+			build, err := strconv.Atoi(string(key))
+			if err != nil {
+				return s.errorf("build number '%s' of job '%s' is not a number", key, job)
+			}
+			row, err := s.int()
+			rows[build] = int(row)
+			return err
+		})
+		return rows, err
+	default:
+		return nil, fmt.Errorf("the build number to row index mapping for job '%s' is not an accepted type", job)
+	}
+}
+
+func (s *triageScanner) builds() error {
+	data := s.triage
+	return s.object(func(key []byte) error {
+		var err error
+		switch string(key) {
+		case "cols":
+			cols := &data.Builds.Cols
+			return s.object(func(key []byte) error {
+				switch string(key) {
+				case "started":
+					cols.Started, err = s.int64s()
+					s.rows = len(cols.Started)
+				case "result":
+					cols.Result, err = s.strings()
+					s.rows = len(cols.Result)
+				default:
+					err = s.skip()
+				}
+				return err
+			})
+		case "jobs":
+			if null, err := s.null(); null || err != nil {
+				return err
+			}
+			data.Builds.Jobs = map[string]BuildIndexer{}
+			return s.object(func(key []byte) error {
+				job := string(key)
+				data.Builds.Jobs[job], err = s.buildIndexer(job)
+				return err
+			})
+		case "job_paths":
+			if null, err := s.null(); null || err != nil {
+				return err
+			}
+			data.Builds.JobPaths = map[string]string{}
+			return s.object(func(key []byte) error {
+				job := string(key)
+				data.Builds.JobPaths[job], err = s.string()
+				return err
+			})
+		default:
+			return s.skip()
+		}
+	})
+}
+
+// cluster consumes a cluster. A null cluster is of the wrong type, and nil is returned for it.
+func (s *triageScanner) cluster() (*Cluster, error) {
+	if null, err := s.null(); null || err != nil {
+		s.invalid("the cluster is null")
+		return nil, err
+	}
+	clust := &Cluster{}
+	err := s.object(func(key []byte) error {
+		var err error
+		switch string(key) {
+		case "id":
+			clust.Identifier, err = s.string()
+		case "key":
+			clust.Key, err = s.string()
+		case "text":
+			clust.Text, err = s.string()
+		case "tests":
+			if null, err := s.null(); null || err != nil {
+				return err
+			}
+			clust.Tests = []*Test{}
+			err = s.array(func() error {
+				test, err := s.test()
+				if test != nil {
+					clust.Tests = append(clust.Tests, test)
+				}
+				return err
+			})
+		default:
+			err = s.skip()
+		}
+		return err
+	})
+	return clust, err
+}
+
+// test consumes a test of a cluster. A null test is of the wrong type, and nil is returned for it.
+func (s *triageScanner) test() (*Test, error) {
+	if null, err := s.null(); null || err != nil {
+		s.invalid("a test is null")
+		return nil, err
+	}
+	test := &Test{}
+	err := s.object(func(key []byte) error {
+		var err error
+		switch string(key) {
+		case "name":
+			test.Name, err = s.string()
+		case "jobs":
+			if null, err := s.null(); null || err != nil {
+				return err
+			}
+			test.Jobs = []*Job{}
+			err = s.array(func() error {
+				if null, err := s.null(); null || err != nil {
+					s.invalid("a job is null")
+					return err
+				}
+				job := &Job{}
+				err := s.object(func(key []byte) error {
+					var err error
+					switch string(key) {
+					case "name":
+						job.Name, err = s.internedString()
+					case "builds":
+						job.Builds, err = s.ints()
+					default:
+						err = s.skip()
+					}
+					return err
+				})
+				if len(job.Builds) == 0 || len(s.inWindow(job)) > 0 {
+					test.Jobs = append(test.Jobs, job)
+				}
+				return err
+			})
+		default:
+			err = s.skip()
+		}
+		return err
+	})
+	return test, err
+}
+
+// inWindow drops the builds of job that started outside the window and returns the rest. Builds
+// whose start time can't be found or is implausible are kept for filterAndValidate to report.
+func (s *triageScanner) inWindow(job *Job) []int {
+	if !s.filtering {
+		return job.Builds
+	}
+	rowMap, ok := s.triage.Builds.Jobs[job.Name]
+	if !ok {
+		return job.Builds
+	}
+	started := s.triage.Builds.Cols.Started
+	kept := job.Builds[:0]
+	for _, build := range job.Builds {
+		row, err := rowMap.rowForBuild(build)
+		if err != nil || row < 0 || row >= len(started) || (started[row] > s.after && started[row] <= s.until) || !plausibleStart(started[row], s.maxStart) {
+			kept = append(kept, build)
+		}
+	}
+	job.Builds = kept
+	return kept
+}
+
+// outOfWindow returns true if every build of the cluster was dropped by inWindow, so that
+// filterAndValidate would drop the cluster without reporting an error.
+func outOfWindow(clust *Cluster) bool {
+	if clust.Identifier == "" || clust.Tests == nil {
+		return false
+	}
+	for _, test := range clust.Tests {
+		if test.Name == "" || test.Jobs == nil || len(test.Jobs) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// parseTriageData reads json data from r into a triageData struct and creates a BuildIndexer for
+// every job. If window is set, it is called with the start times of all builds and the failed builds
+// that didn't start after the first bound it returns and by the second are dropped. Filtering
+// needs the builds to come before the clusters in the data, as they do in the triage output.
+func parseTriageData(r io.Reader, window func(started []int64) (after, until int64)) (*triageData, error) {
+	reader := readers.Get().(*bufio.Reader)
+	reader.Reset(r)
+	defer func() {
+		reader.Reset(nil)
+		readers.Put(reader)
+	}()
+
+	s := &triageScanner{r: reader, interned: map[string]string{}, triage: &triageData{}}
+	data := s.triage
+	err := s.object(func(key []byte) error {
+		switch string(key) {
+		case "builds":
+			return s.builds()
+		case "clustered":
+			if window != nil && data.Builds.Cols.Started != nil && data.Builds.Jobs != nil {
+				s.after, s.until = window(data.Builds.Cols.Started)
+				s.maxStart = latestPlausibleStart()
+				s.filtering = true
+			}
+			if null, err := s.null(); null || err != nil {
+				return err
+			}
+			data.Clustered = []*Cluster{}
+			index := 0
+			return s.array(func() error {
+				// A value of the wrong type only spoils its own cluster, which is skipped to its
+				// end and reported by filterAndValidate with the clusters that fail validation.
+				outer := s.typeErr
+				s.typeErr = nil
+				clust, err := s.cluster()
+				switch {
+				case err != nil:
+				case s.typeErr != nil && clust != nil && clust.Identifier != "":
+					data.malformed = append(data.malformed, fmt.Sprintf("cluster '%s' is malformed: %v", clust.Identifier, s.typeErr))
+				case s.typeErr != nil:
+					data.malformed = append(data.malformed, fmt.Sprintf("the cluster at index %d in the triage JSON data is malformed: %v", index, s.typeErr))
+				case !s.filtering || !outOfWindow(clust):
+					data.Clustered = append(data.Clustered, clust)
+				}
+				s.typeErr = outer
+				index++
+				return err
+			})
+		default:
+			return s.skip()
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	if s.peek() != 0 {
+		return nil, s.errorf("unexpected data after the end")
+	}
+	if s.readErr != nil {
+		return nil, fmt.Errorf("failed to read triage data: %v", s.readErr)
+	}
+	if s.typeErr != nil {
+		return nil, s.typeErr
+	}
+
+	if data.Builds.Cols.Started == nil {
+		return nil, fmt.Errorf("triage data json is missing the builds.cols.started key")
+	}
+	if data.Builds.Jobs == nil {
+		return nil, fmt.Errorf("triage data is missing the builds.jobs key")
+	}
+	if data.Builds.JobPaths == nil {
+		return nil, fmt.Errorf("triage data is missing the builds.job_paths key")
+	}
+	if data.Clustered == nil {
+		return nil, fmt.Errorf("triage data is missing the clustered key")
+	}
+	return data, nil
+}
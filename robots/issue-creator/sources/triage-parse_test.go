@@ -0,0 +1,338 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sources
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+const parseTestData = `{
+		"builds": {
+			"cols": {"elapsed": [1.5, -2e3], "executor": ["a\"]", null], "result": ["SUCCESS", "FAILURE"], "started": [10, 20]},
+			"jobs": {"contig": [100, 2, 0], "dict": {"7": 1, "9": 0}},
+			"job_paths": {"contig": "gs://bucket/contig", "dict": "gs://bucket/dict"},
+			"extra": {"nested": [{"a": [true, false, null]}, "]}"]}
+		},
+		"clustered": [{"id": "id1", "key": "key\n1", "text": "text1", "spans": [[1, 2]], "tests": [
+			{"name": "test1", "jobs": [{"name": "contig", "builds": [100, 101], "pr": false}]}
+		]}]
+	}`
+
+func TestParseTriageData(t *testing.T) {
+	// Reading a byte at a time splits every string and number between reads.
+	for _, r := range []io.Reader{strings.NewReader(parseTestData), iotest.OneByteReader(strings.NewReader(parseTestData))} {
+		checkParseTestData(t, r)
+	}
+}
+
+func checkParseTestData(t *testing.T, r io.Reader) {
+	data, err := parseTriageData(r, nil)
+	if err != nil {
+		t.Fatalf("Error parsing triage data: %v", err)
+	}
+	if expected := []string{"SUCCESS", "FAILURE"}; !reflect.DeepEqual(data.Builds.Cols.Result, expected) {
+		t.Errorf("Expected results %v, got %v.", expected, data.Builds.Cols.Result)
+	}
+	if expected := []int64{10, 20}; !reflect.DeepEqual(data.Builds.Cols.Started, expected) {
+		t.Errorf("Expected start times %v, got %v.", expected, data.Builds.Cols.Started)
+	}
+	expectedJobs := map[string]BuildIndexer{
+		"contig": ContigIndexer{startBuild: 100, count: 2, startRow: 0},
+		"dict":   DictIndexer{7: 1, 9: 0},
+	}
+	if !reflect.DeepEqual(data.Builds.Jobs, expectedJobs) {
+		t.Errorf("Expected jobs %v, got %v.", expectedJobs, data.Builds.Jobs)
+	}
+	if path := data.Builds.JobPaths["dict"]; path != "gs://bucket/dict" {
+		t.Errorf("Expected the path of job 'dict' to be 'gs://bucket/dict', got '%s'.", path)
+	}
+	expectedClusters := []*Cluster{{
+		Identifier: "id1",
+		Key:        "key\n1",
+		Text:       "text1",
+		Tests:      []*Test{{Name: "test1", Jobs: []*Job{{Name: "contig", Builds: []int{100, 101}}}}},
+	}}
+	if !reflect.DeepEqual(data.Clustered, expectedClusters) {
+		t.Errorf("Expected clusters %v, got %v.", expectedClusters, data.Clustered)
+	}
+}
+
+func TestParseTriageDataErrors(t *testing.T) {
+	tcs := []struct {
+		name string
+		json string
+		err  string
+	}{
+		{
+			name: "truncated",
+			json: `{"builds": {"cols": {"started": [1, 2`,
+			err:  "expected ',' or ']'",
+		},
+		{
+			name: "unterminated string",
+			json: `{"clustered": [{"id": "id1`,
+			err:  "unterminated string",
+		},
+		{
+			name: "trailing data",
+			json: `{"builds": {"cols": {"started": []}, "jobs": {}, "job_paths": {}}, "clustered": []} {}`,
+			err:  "unexpected data after the end",
+		},
+		{
+			name: "mapping type",
+			json: `{"builds": {"jobs": {"job1": "1-10"}}}`,
+			err:  "the build number to row index mapping for job 'job1' is not an accepted type",
+		},
+		{
+			name: "mapping length",
+			json: `{"builds": {"jobs": {"job1": [1, 10]}}}`,
+			err:  "has 2 values instead of 3",
+		},
+		{
+			name: "mapping build number",
+			json: `{"builds": {"jobs": {"job1": {"latest": 1}}}}`,
+			err:  "build number 'latest' of job 'job1' is not a number",
+		},
+		{
+			name: "fraction",
+			json: `{"builds": {"cols": {"started": [1, 2.5]}}}`,
+			err:  "number 2.5 is not an integer",
+		},
+		{
+			name: "exponent",
+			json: `{"builds": {"cols": {"started": [1e9]}}}`,
+			err:  "number 1e9 is not an integer",
+		},
+		{
+			name: "overflow",
+			json: `{"builds": {"cols": {"started": [99999999999999999999]}}}`,
+			err:  "number 99999999999999999999 overflows",
+		},
+		{
+			name: "string for number",
+			json: `{"builds": {"cols": {"started": ["1"]}, "jobs": {}, "job_paths": {}}, "clustered": []}`,
+			err:  "expected a number",
+		},
+		{
+			name: "number for array",
+			json: `{"builds": {"cols": {"started": 1}, "jobs": {}, "job_paths": {}}, "clustered": []}`,
+			err:  "expected an array",
+		},
+		{
+			name: "invalid skipped number",
+			json: `{"builds": {"cols": {"elapsed": [1.]}}}`,
+			err:  "invalid number '1.'",
+		},
+		{
+			name: "invalid skipped literal",
+			json: `{"builds": {"cols": {"elapsed": [tru]}}}`,
+			err:  "expected 'true'",
+		},
+		{
+			name: "invalid skipped escape",
+			json: `{"builds": {"extra": "\x"}}`,
+			err:  "escape",
+		},
+		{
+			name: "control character",
+			json: "{\"builds\": {\"extra\": \"a\tb\"}}",
+			err:  "invalid character '\\t' in string",
+		},
+		{
+			name: "missing clustered",
+			json: `{"builds": {"cols": {"started": []}, "jobs": {}, "job_paths": {}}}`,
+			err:  "triage data is missing the clustered key",
+		},
+	}
+	for _, tc := range tcs {
+		_, err := parseTriageData(strings.NewReader(tc.json), nil)
+		if err == nil {
+			t.Errorf("%s: expected an error containing '%s', got none.", tc.name, tc.err)
+		} else if !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("%s: expected an error containing '%s', got '%v'.", tc.name, tc.err, err)
+		}
+	}
+}
+
+func TestParseTriageDataWindow(t *testing.T) {
+	data, err := parseTriageData(strings.NewReader(`{
+		"builds": {
+			"cols": {"started": [1000000010, 1000000020, 1000000030, 1000000040, 10000000]},
+			"jobs": {"job1": [1, 5, 0]},
+			"job_paths": {"job1": "gs://bucket/job1"}
+		},
+		"clustered": [
+			{"id": "recent", "tests": [
+				{"name": "test1", "jobs": [{"name": "job1", "builds": [1, 2, 3, 4, 5]}]},
+				{"name": "test2", "jobs": [{"name": "job1", "builds": [1]}, {"name": "unknown", "builds": [1]}]}
+			]},
+			{"id": "old", "tests": [{"name": "test1", "jobs": [{"name": "job1", "builds": [1, 4]}]}]},
+			{"id": "", "tests": [{"name": "test1", "jobs": [{"name": "job1", "builds": [1]}]}]}
+		]
+	}`), func(started []int64) (int64, int64) {
+		if expected := []int64{1000000010, 1000000020, 1000000030, 1000000040, 10000000}; !reflect.DeepEqual(started, expected) {
+			t.Errorf("Expected the window to be given start times %v, got %v.", expected, started)
+		}
+		return 1000000010, 1000000030
+	})
+	if err != nil {
+		t.Fatalf("Error parsing triage data: %v", err)
+	}
+	expected := []*Cluster{
+		{
+			Identifier: "recent",
+			Tests: []*Test{
+				// Build 5 started at an implausible time, so it is kept for filterAndValidate to report.
+				{Name: "test1", Jobs: []*Job{{Name: "job1", Builds: []int{2, 3, 5}}}},
+				{Name: "test2", Jobs: []*Job{{Name: "unknown", Builds: []int{1}}}},
+			},
+		},
+		// Kept so that filterAndValidate reports the missing ID.
+		{Identifier: "", Tests: []*Test{{Name: "test1", Jobs: []*Job{}}}},
+	}
+	if !reflect.DeepEqual(data.Clustered, expected) {
+		t.Errorf("Expected clusters %v, got %v.", expected, data.Clustered)
+	}
+}
+
+func TestParseTriageDataNull(t *testing.T) {
+	// Like encoding/json, null leaves values empty for filterAndValidate to report.
+	data, err := parseTriageData(strings.NewReader(`{
+		"builds": {"cols": {"started": [10], "result": null}, "jobs": {"job1": [1, 1, 0]}, "job_paths": {"job1": null}},
+		"clustered": [
+			{"id": null, "tests": null},
+			{"id": "id2", "tests": [{"name": "test1", "jobs": null}]},
+			{"id": "id3", "tests": [{"name": null, "jobs": [{"name": "job1", "builds": null}]}]},
+			null
+		]
+	}`), nil)
+	if err != nil {
+		t.Fatalf("Error parsing triage data: %v", err)
+	}
+	if data.Builds.Cols.Result != nil || data.Builds.JobPaths["job1"] != "" {
+		t.Errorf("Expected null results and paths to be empty, got %v and %v.", data.Builds.Cols.Result, data.Builds.JobPaths)
+	}
+	expected := []*Cluster{
+		{},
+		{Identifier: "id2", Tests: []*Test{{Name: "test1"}}},
+		{Identifier: "id3", Tests: []*Test{{Jobs: []*Job{{Name: "job1"}}}}},
+	}
+	if !reflect.DeepEqual(data.Clustered, expected) {
+		t.Errorf("Expected clusters %v, got %v.", expected, data.Clustered)
+	}
+	// Unlike the other values, a null cluster is of the wrong type, and only it is dropped.
+	if len(data.malformed) != 1 || !strings.Contains(data.malformed[0], "the cluster at index 3 in the triage JSON data is malformed") || !strings.Contains(data.malformed[0], "the cluster is null") {
+		t.Errorf("Expected the null cluster to be reported as malformed, got %v.", data.malformed)
+	}
+}
+
+func TestParseTriageDataReadError(t *testing.T) {
+	r := io.MultiReader(strings.NewReader(`{"builds": {"cols": {"started": [1, `), iotest.ErrReader(errors.New("connection reset")))
+	_, err := parseTriageData(r, nil)
+	if err == nil || !strings.Contains(err.Error(), "connection reset") {
+		t.Errorf("Expected the read error to be returned, got %v", err)
+	}
+}
+
+func TestParseTriageDataMemory(t *testing.T) {
+	// Only one cluster in a hundred has builds in the window, and the rest are long gone.
+	const jobs, builds, clusters, sampleEvery = 10, 1000, 20000, 1000
+	var base runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&base)
+
+	r, pw := io.Pipe()
+	w := bufio.NewWriter(pw)
+	var written int64
+	var peak uint64
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		write := func(format string, args ...interface{}) {
+			n, _ := fmt.Fprintf(w, format, args...)
+			written += int64(n)
+		}
+		started := make([]string, 0, jobs*builds)
+		jobRows := make([]string, 0, jobs)
+		for j := 0; j < jobs; j++ {
+			for b := 0; b < builds; b++ {
+				started = append(started, strconv.FormatInt(latestBuildTime-int64(b*3600), 10))
+			}
+			jobRows = append(jobRows, fmt.Sprintf(`"job%d": [1000, %d, %d]`, j, builds, j*builds))
+		}
+		write(`{"builds": {"cols": {"started": [%s]}, "jobs": {%s}, "job_paths": {}}, "clustered": [`, strings.Join(started, ", "), strings.Join(jobRows, ", "))
+		for c := 0; c < clusters; c++ {
+			if c%sampleEvery == 0 {
+				var m runtime.MemStats
+				runtime.GC()
+				runtime.ReadMemStats(&m)
+				if m.HeapAlloc > peak {
+					peak = m.HeapAlloc
+				}
+			}
+			build := 1200 + c%builds/2
+			if c%100 == 0 {
+				build = 1001
+			}
+			if c > 0 {
+				write(", ")
+			}
+			write(`{"id": "cluster%d", "key": "key%d", "text": "error text of cluster %d", "tests": [`, c, c, c)
+			for test := 0; test < 3; test++ {
+				if test > 0 {
+					write(", ")
+				}
+				write(`{"name": "test %d of cluster %d", "jobs": [`, test, c)
+				for j := 0; j < 4; j++ {
+					if j > 0 {
+						write(", ")
+					}
+					write(`{"name": "job%d", "builds": [%d, %d, %d, %d, %d]}`, (c+j)%jobs, build, build+1, build+2, build+3, build+4)
+				}
+				write("]}")
+			}
+			write("]}")
+		}
+		write("]}")
+		w.Flush()
+		pw.Close()
+	}()
+
+	data, err := parseTriageData(r, func([]int64) (int64, int64) {
+		return latestBuildTime - 5*24*3600, latestBuildTime
+	})
+	<-done
+	if err != nil {
+		t.Fatalf("Error parsing triage data: %v", err)
+	}
+	if len(data.Clustered) != clusters/100 {
+		t.Errorf("Expected %d clusters in the window, got %d.", clusters/100, len(data.Clustered))
+	}
+	// The payload is never held in memory, only the builds and the clusters that are kept.
+	if used := int64(peak) - int64(base.HeapAlloc); used > written/10 {
+		t.Errorf("Expected parsing %d bytes of triage data to use at most a tenth of that, but the heap grew by %d bytes.", written, used)
+	}
+}
@@ -0,0 +1,213 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sources
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	githubapi "github.com/google/go-github/github"
+	"k8s.io/test-infra/robots/issue-creator/creator"
+)
+
+const (
+	// staleLabel marks flake issues whose cluster is no longer in the triage data, so that they are
+	// closed by the usual lifecycle rules unless someone objects.
+	staleLabel = "lifecycle/stale"
+	// notSeenNote is appended to the body of an issue whose cluster is no longer in the triage data.
+	notSeenNote = "\n**This failure cluster has not been seen in the last %d days.** It will be closed as stale unless it recurs.\n"
+)
+
+// clusterHeaderRegex finds the cluster ID in the header of the body of a triage issue.
+var clusterHeaderRegex = regexp.MustCompile(`(?m)^### Failure cluster \[([^\]]+)\]`)
+
+// issueTracker is the part of the IssueCreator that updating existing issues needs. It is an interface
+// so that tests can substitute a fake.
+type issueTracker interface {
+	OpenIssues() []*githubapi.Issue
+	IssuesWithID(id string) []*githubapi.Issue
+	OpenIssuesByOthers(ctx context.Context, label string) ([]*githubapi.Issue, error)
+	UpdateIssue(ctx context.Context, number int, body string, labels []string) error
+	IssueComments(ctx context.Context, number int) ([]*githubapi.IssueComment, error)
+	CommentOnIssue(ctx context.Context, number int, body string) error
+}
+
+// reconcileIssues brings the open flake issues in line with the current clusters. Issues filed by
+// this bot get up to date counts if their cluster is still failing, or are marked stale if it is
+// not. Clusters that a human already filed an issue for are not filed again.
+func (f *TriageFiler) reconcileIssues(ctx context.Context, tracker issueTracker, clusters []*Cluster) error {
+	byID := make(map[string]*Cluster, len(clusters))
+	for _, clust := range clusters {
+		byID[clust.Identifier] = clust
+	}
+
+	others, err := tracker.OpenIssuesByOthers(ctx, "kind/flake")
+	if err != nil {
+		return err
+	}
+	for _, issue := range others {
+		if issue.Body == nil {
+			continue
+		}
+		for id, clust := range byID {
+			if clust.trackedBy == 0 && strings.Contains(*issue.Body, id) {
+				clust.trackedBy = *issue.Number
+				f.log(id).WithNumber(*issue.Number).Infof("Already tracked, not filing it.")
+			}
+		}
+	}
+
+	var errs []string
+	for _, issue := range f.updatableIssues(tracker) {
+		if issue.Body == nil {
+			continue
+		}
+		match := clusterHeaderRegex.FindStringSubmatch(*issue.Body)
+		if match == nil {
+			// Not an issue from the triage filer.
+			continue
+		}
+		labels := issueLabels(issue)
+		body := *issue.Body
+		if clust, ok := byID[match[1]]; ok {
+			body, _ = creator.UpdateSections(*issue.Body, clust.refreshedBody())
+			body = strings.Replace(body, fmt.Sprintf(notSeenNote, f.windowDays), "", 1)
+			labels = removeLabel(labels, staleLabel)
+		} else if !hasLabel(labels, staleLabel) {
+			body += fmt.Sprintf(notSeenNote, f.windowDays)
+			labels = append(labels, staleLabel)
+		}
+		if body == "" || (body == *issue.Body && len(labels) == len(issue.Labels)) {
+			continue
+		}
+		if err := tracker.UpdateIssue(ctx, *issue.Number, body, labels); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to update %d issues: %s", len(errs), strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// refreshedBody renders the body of the cluster's issue with the current counts, ignoring whether
+// it was tracked by a human filed issue. Only its sections replace those of the open issue.
+func (c *Cluster) refreshedBody() string {
+	trackedBy := c.trackedBy
+	c.trackedBy = 0
+	defer func() { c.trackedBy = trackedBy }()
+	defer creator.TrackAllocs("render")()
+	return c.Body(nil)
+}
+
+// updateOpenReport edits the generated sections of the open issue of a report, if there is one, to
+// those of the report's current body. It returns false if the report has no open issue and needs one filed.
+func (f *TriageFiler) updateOpenReport(ctx context.Context, tracker issueTracker, report creator.Issue) bool {
+	marker := creator.ReportMarker(report.ID())
+	for _, issue := range tracker.OpenIssues() {
+		if issue.Body == nil || !strings.Contains(*issue.Body, marker) {
+			continue
+		}
+		doneRendering := creator.TrackAllocs("render")
+		rendered := report.Body(nil)
+		doneRendering()
+		if body, changed := creator.UpdateSections(*issue.Body, rendered); changed {
+			if err := tracker.UpdateIssue(ctx, *issue.Number, body, issueLabels(issue)); err != nil {
+				f.log(report.ID()).WithNumber(*issue.Number).Errorf("Failed to update the report: %v", err)
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// updatableIssues returns the open issues of the bot that it may update, leaving out those that
+// a human opted out of updates with ignoreLabel.
+func (f *TriageFiler) updatableIssues(tracker issueTracker) []*githubapi.Issue {
+	var issues []*githubapi.Issue
+	for _, issue := range tracker.OpenIssues() {
+		if !f.ignored(issue) {
+			issues = append(issues, issue)
+		}
+	}
+	return issues
+}
+
+// ignored returns true iff a human applied ignoreLabel to the issue, so that its cluster must
+// never be filed or updated again.
+func (f *TriageFiler) ignored(issue *githubapi.Issue) bool {
+	return f.ignoreLabel != "" && hasLabel(issueLabels(issue), f.ignoreLabel)
+}
+
+// clusterIgnored returns true iff a human applied ignoreLabel to an issue of the bot for the
+// cluster, open or closed, so that nothing more may be filed for it.
+func (f *TriageFiler) clusterIgnored(tracker issueTracker, clust *Cluster) bool {
+	if tracker == nil {
+		return false
+	}
+	for _, issue := range tracker.IssuesWithID(clust.Identifier) {
+		if f.ignored(issue) {
+			return true
+		}
+	}
+	return false
+}
+
+// clusterIssues maps the IDs of clusters to the numbers of the triage issues among issues.
+func clusterIssues(issues []*githubapi.Issue) map[string]int {
+	numbers := map[string]int{}
+	for _, issue := range issues {
+		if issue.Body == nil {
+			continue
+		}
+		if match := clusterHeaderRegex.FindStringSubmatch(*issue.Body); match != nil {
+			numbers[match[1]] = *issue.Number
+		}
+	}
+	return numbers
+}
+
+func issueLabels(issue *githubapi.Issue) []string {
+	labels := make([]string, 0, len(issue.Labels))
+	for _, label := range issue.Labels {
+		if label.Name != nil {
+			labels = append(labels, *label.Name)
+		}
+	}
+	return labels
+}
+
+func hasLabel(labels []string, label string) bool {
+	for _, l := range labels {
+		if l == label {
+			return true
+		}
+	}
+	return false
+}
+
+func removeLabel(labels []string, label string) []string {
+	result := make([]string, 0, len(labels))
+	for _, l := range labels {
+		if l != label {
+			result = append(result, l)
+		}
+	}
+	return result
+}
@@ -0,0 +1,155 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sources
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/github"
+	"k8s.io/test-infra/robots/issue-creator/creator"
+)
+
+type fakeTracker struct {
+	own      []*github.Issue
+	closed   []*github.Issue
+	others   []*github.Issue
+	updated  map[int]*github.IssueRequest
+	comments map[int][]*github.IssueComment
+	// commentErr is returned by CommentOnIssue if set.
+	commentErr error
+}
+
+func (f *fakeTracker) OpenIssues() []*github.Issue {
+	return f.own
+}
+
+func (f *fakeTracker) IssuesWithID(id string) []*github.Issue {
+	var issues []*github.Issue
+	for _, issue := range append(append([]*github.Issue(nil), f.closed...), f.own...) {
+		if creator.IsReport(*issue.Body) {
+			if strings.Contains(*issue.Body, creator.ReportMarker(id)) {
+				issues = append(issues, issue)
+			}
+		} else if strings.Contains(*issue.Body, id) {
+			issues = append(issues, issue)
+		}
+	}
+	return issues
+}
+
+func (f *fakeTracker) OpenIssuesByOthers(ctx context.Context, label string) ([]*github.Issue, error) {
+	return f.others, nil
+}
+
+func (f *fakeTracker) UpdateIssue(ctx context.Context, number int, body string, labels []string) error {
+	f.updated[number] = &github.IssueRequest{Body: &body, Labels: &labels}
+	return nil
+}
+
+func (f *fakeTracker) IssueComments(ctx context.Context, number int) ([]*github.IssueComment, error) {
+	return f.comments[number], nil
+}
+
+func (f *fakeTracker) CommentOnIssue(ctx context.Context, number int, body string) error {
+	if f.commentErr != nil {
+		return f.commentErr
+	}
+	if f.comments == nil {
+		f.comments = map[int][]*github.IssueComment{}
+	}
+	f.comments[number] = append(f.comments[number], &github.IssueComment{Body: &body})
+	return nil
+}
+
+func makeIssue(number int, body string, labels ...string) *github.Issue {
+	issue := &github.Issue{Number: &number, Body: &body}
+	for i := range labels {
+		issue.Labels = append(issue.Labels, github.Label{Name: &labels[i]})
+	}
+	return issue
+}
+
+func TestTFReconcileIssues(t *testing.T) {
+	f := NewTestTriageFiler()
+	clusters, err := f.loadClusters(json1issue2job2test)
+	if err != nil {
+		t.Fatalf("Error parsing triage data: %v\n", err)
+	}
+	current := clusters[0].Body(nil)
+	// Issues 7 and 8 were opted out of the bot, so they are neither refreshed nor marked stale.
+	f.ignoreLabel = "triage/handled-offline"
+	// An issue that a human edited keeps the edits when its stats are refreshed.
+	edited := "### Failure cluster [key_hash](url)\nA human note.\n"
+	currentStats, _ := creator.UpdateSections(creator.Section("stats", ""), current)
+
+	tracker := &fakeTracker{
+		own: []*github.Issue{
+			makeIssue(1, "### Failure cluster [key_hash](url)\nold counts", "kind/flake", staleLabel),
+			makeIssue(2, current, "kind/flake"),
+			makeIssue(3, "### Failure cluster [gone_hash](url)\nold counts", "kind/flake"),
+			makeIssue(4, "### Failure cluster [stale_hash](url)\nold counts", "kind/flake", staleLabel),
+			makeIssue(5, "Not a triage issue.", "kind/flake"),
+			makeIssue(6, edited+creator.Section("stats", "old counts")+fmt.Sprintf(notSeenNote, 5), "kind/flake", staleLabel),
+			makeIssue(7, "### Failure cluster [key_hash](url)\nold counts", "kind/flake", "triage/handled-offline"),
+			makeIssue(8, "### Failure cluster [ignored_hash](url)\nold counts", "kind/flake", "triage/handled-offline"),
+		},
+		others:  []*github.Issue{makeIssue(10, "Flaking, see key_hash on triage.", "kind/flake")},
+		updated: map[int]*github.IssueRequest{},
+	}
+	if err := f.reconcileIssues(context.Background(), tracker, clusters); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(tracker.updated) != 3 {
+		t.Errorf("Expected issues 1, 3 and 6 to be updated, got %v", tracker.updated)
+	}
+	if update := tracker.updated[1]; update == nil {
+		t.Error("Expected issue 1 to be updated with the current counts.")
+	} else {
+		if *update.Body != current {
+			t.Errorf("Expected issue 1 to get the current body:\n%s\ngot:\n%s", current, *update.Body)
+		}
+		if !reflect.DeepEqual(*update.Labels, []string{"kind/flake"}) {
+			t.Errorf("Expected the stale label to be removed from issue 1, got %v", *update.Labels)
+		}
+	}
+	if update := tracker.updated[6]; update == nil {
+		t.Error("Expected issue 6 to be updated with the current counts.")
+	} else if expected := edited + currentStats; *update.Body != expected {
+		t.Errorf("Expected issue 6 to keep its edits and get the current stats:\n%s\ngot:\n%s", expected, *update.Body)
+	}
+	if update := tracker.updated[3]; update == nil {
+		t.Error("Expected issue 3 to be marked stale.")
+	} else {
+		if !strings.Contains(*update.Body, "has not been seen in the last 5 days") {
+			t.Errorf("Expected issue 3 to note that the cluster was not seen, got:\n%s", *update.Body)
+		}
+		if !reflect.DeepEqual(*update.Labels, []string{"kind/flake", staleLabel}) {
+			t.Errorf("Expected issue 3 to get the stale label, got %v", *update.Labels)
+		}
+	}
+	if clusters[0].trackedBy != 10 {
+		t.Errorf("Expected cluster key_hash to be tracked by #10, got %d", clusters[0].trackedBy)
+	}
+	if body := clusters[0].Body(nil); body != "" {
+		t.Errorf("Expected no body for a cluster tracked by a human filed issue, got:\n%s", body)
+	}
+}
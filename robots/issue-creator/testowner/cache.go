@@ -0,0 +1,110 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package testowner
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	cacheLookups = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "issue_creator_owner_cache_lookups_total",
+		Help: "Lookups in the remote test owners cache, by result (hit, miss, or expired).",
+	}, []string{"result"})
+	cacheEvictions = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "issue_creator_owner_cache_evictions_total",
+		Help: "Entries evicted from the remote test owners cache to make room for new ones.",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(cacheLookups)
+	prometheus.MustRegister(cacheEvictions)
+}
+
+// lookupCache is a size-bounded, least recently used cache of remote lookup results. Entries
+// expire after a fixed time to live.
+type lookupCache struct {
+	size int
+	ttl  time.Duration
+	now  func() time.Time
+
+	lock    sync.Mutex
+	order   *list.List // of *cacheEntry, most recently used first
+	entries map[string]*list.Element
+}
+
+type cacheEntry struct {
+	key     string
+	value   string
+	expires time.Time
+}
+
+func newLookupCache(size int, ttl time.Duration) *lookupCache {
+	return &lookupCache{
+		size:    size,
+		ttl:     ttl,
+		now:     time.Now,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// get returns the cached value for key, and whether there was an unexpired one.
+func (c *lookupCache) get(key string) (string, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	elem, ok := c.entries[key]
+	if !ok {
+		cacheLookups.WithLabelValues("miss").Inc()
+		return "", false
+	}
+	entry := elem.Value.(*cacheEntry)
+	if c.now().After(entry.expires) {
+		cacheLookups.WithLabelValues("expired").Inc()
+		c.order.Remove(elem)
+		delete(c.entries, key)
+		return "", false
+	}
+	cacheLookups.WithLabelValues("hit").Inc()
+	c.order.MoveToFront(elem)
+	return entry.value, true
+}
+
+// put stores value for key, evicting the least recently used entry if the cache is full.
+func (c *lookupCache) put(key, value string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	expires := c.now().Add(c.ttl)
+	if elem, ok := c.entries[key]; ok {
+		entry := elem.Value.(*cacheEntry)
+		entry.value, entry.expires = value, expires
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&cacheEntry{key: key, value: value, expires: expires})
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+		cacheEvictions.Inc()
+	}
+}
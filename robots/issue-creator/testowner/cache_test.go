@@ -0,0 +1,97 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package testowner
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestLookupCache(t *testing.T) {
+	now := time.Now()
+	cache := newLookupCache(2, time.Minute)
+	cache.now = func() time.Time { return now }
+
+	cache.put("a", "alice")
+	cache.put("b", "bob")
+	if value, ok := cache.get("a"); !ok || value != "alice" {
+		t.Errorf("get(a) = %q, %t; expected alice", value, ok)
+	}
+	// "b" is now the least recently used entry.
+	cache.put("c", "carol")
+	if _, ok := cache.get("b"); ok {
+		t.Errorf("expected b to be evicted")
+	}
+	if value, ok := cache.get("a"); !ok || value != "alice" {
+		t.Errorf("get(a) = %q, %t; expected alice", value, ok)
+	}
+	if value, ok := cache.get("c"); !ok || value != "carol" {
+		t.Errorf("get(c) = %q, %t; expected carol", value, ok)
+	}
+
+	now = now.Add(time.Minute + time.Second)
+	if _, ok := cache.get("a"); ok {
+		t.Errorf("expected a to be expired")
+	}
+	cache.put("a", "anne")
+	if value, ok := cache.get("a"); !ok || value != "anne" {
+		t.Errorf("get(a) = %q, %t; expected anne", value, ok)
+	}
+}
+
+func TestOwnerServiceCache(t *testing.T) {
+	hits := 0
+	failing := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		if failing {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		if r.URL.Query().Get("test") == "unowned" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, "alice")
+	}))
+	defer server.Close()
+
+	service := NewOwnerService(server.URL, time.Second)
+	service.EnableCache(10, time.Minute)
+	for i := 0; i < 3; i++ {
+		if owner := service.TestOwner("test"); owner != "alice" {
+			t.Errorf("TestOwner() = %q, expected alice", owner)
+		}
+		if owner := service.TestOwner("unowned"); owner != "" {
+			t.Errorf("TestOwner(unowned) = %q, expected no owner", owner)
+		}
+	}
+	if hits != 2 {
+		t.Errorf("expected 2 requests, got %d", hits)
+	}
+
+	// Failures are not cached.
+	failing = true
+	service.TestSIG("test")
+	service.TestSIG("test")
+	if hits != 4 {
+		t.Errorf("expected failed lookups to be retried, got %d requests", hits)
+	}
+}
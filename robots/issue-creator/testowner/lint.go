@@ -0,0 +1,113 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package testowner
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/golang/glog"
+)
+
+// teamOwnerPrefix marks an owner that is a GitHub team rather than a user.
+const teamOwnerPrefix = "team:"
+
+// LintProblem is a mistake found in the test owners data.
+type LintProblem struct {
+	// Line is the line of the owners file holding the row.
+	Line int
+	// Name is the test name of the row.
+	Name string
+	// Message describes the problem.
+	Message string
+}
+
+func (p LintProblem) String() string {
+	return fmt.Sprintf("line %d: %q: %s", p.Line, p.Name, p.Message)
+}
+
+// Linter checks test owners data for mistakes.
+type Linter struct {
+	// Matching is the matching mode used to find rows for the same test.
+	Matching MatchMode
+	// KnownSIGs lists the valid SIG names. If empty, SIG names are not checked.
+	KnownSIGs []string
+	// UserExists reports whether a GitHub user exists. If nil, users are not checked.
+	UserExists func(login string) (bool, error)
+}
+
+// Lint reads a test owners CSV file and returns its problems in line order: rows that duplicate,
+// conflict with, or are shadowed by an earlier row for the same test, rows without an owner, and
+// rows naming an unknown SIG or a GitHub user that does not exist.
+func (l *Linter) Lint(r io.Reader) ([]LintProblem, error) {
+	rows, err := readOwnerRows(r)
+	if err != nil {
+		return nil, err
+	}
+	knownSIGs := make(map[string]bool)
+	for _, sig := range l.KnownSIGs {
+		knownSIGs[strings.ToLower(strings.TrimSpace(sig))] = true
+	}
+	users := make(map[string]bool)
+
+	var problems []LintProblem
+	report := func(row ownerRow, format string, args ...interface{}) {
+		problems = append(problems, LintProblem{Line: row.line, Name: row.name, Message: fmt.Sprintf(format, args...)})
+	}
+	first := make(map[string]ownerRow)
+	for _, row := range rows {
+		key := row.key(l.Matching)
+		if prev, ok := first[key]; !ok {
+			first[key] = row
+		} else if prev.name != row.name {
+			report(row, "unreachable, matches the same tests as %q on line %d", prev.name, prev.line)
+		} else if sameOwner(prev.info, row.info) {
+			report(row, "duplicate of line %d", prev.line)
+		} else {
+			report(row, "conflicts with line %d", prev.line)
+		}
+
+		if strings.TrimSpace(row.info.User) == "" {
+			report(row, "no owner")
+		}
+		if sig := strings.ToLower(strings.TrimSpace(row.info.SIG)); sig != "" && len(knownSIGs) > 0 && !knownSIGs[sig] {
+			report(row, "unknown SIG %q", row.info.SIG)
+		}
+		if l.UserExists == nil {
+			continue
+		}
+		for _, entry := range strings.Split(row.info.User, "/") {
+			user, _ := parseWeightedOwner(entry)
+			if user == "" || strings.HasPrefix(user, teamOwnerPrefix) {
+				continue
+			}
+			exists, checked := users[strings.ToLower(user)]
+			if !checked {
+				if exists, err = l.UserExists(user); err != nil {
+					glog.Warningf("Unable to check whether GitHub user %q exists: %v", user, err)
+					exists = true
+				}
+				users[strings.ToLower(user)] = exists
+			}
+			if !exists {
+				report(row, "GitHub user %q does not exist", user)
+			}
+		}
+	}
+	return problems, nil
+}
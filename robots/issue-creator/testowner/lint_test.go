@@ -0,0 +1,90 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package testowner
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestLint(t *testing.T) {
+	csv := `name,owner,sig
+DEFAULT,dflt,
+Volumes should mount,alice,storage
+Volumes should mount,alice,storage
+Volumes [Feature:Volumes] should mount,bob,storage
+Kubelet restarts pods,carol:2/ghost,node
+Kubelet restarts pods,dave,node
+Scheduler spreads pods,,scheduling
+Networking works,team:sig-network/erin,sig-netwrok
+Apps deploy,flaky,apps
+`
+	checked := make(map[string]int)
+	linter := &Linter{
+		KnownSIGs: []string{"storage", "node", "Scheduling", "apps"},
+		UserExists: func(login string) (bool, error) {
+			checked[login]++
+			switch login {
+			case "ghost":
+				return false, nil
+			case "flaky":
+				return false, errors.New("github is down")
+			}
+			return true, nil
+		},
+	}
+	problems, err := linter.Lint(bytes.NewReader([]byte(csv)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []LintProblem{
+		{Line: 4, Name: "Volumes should mount", Message: "duplicate of line 3"},
+		{Line: 5, Name: "Volumes [Feature:Volumes] should mount", Message: `unreachable, matches the same tests as "Volumes should mount" on line 3`},
+		{Line: 6, Name: "Kubelet restarts pods", Message: `GitHub user "ghost" does not exist`},
+		{Line: 7, Name: "Kubelet restarts pods", Message: "conflicts with line 6"},
+		{Line: 8, Name: "Scheduler spreads pods", Message: "no owner"},
+		{Line: 9, Name: "Networking works", Message: `unknown SIG "sig-netwrok"`},
+	}
+	if !reflect.DeepEqual(problems, expected) {
+		t.Errorf("Lint() returned:\n%v\nexpected:\n%v", problems, expected)
+	}
+	for login, count := range checked {
+		if count != 1 {
+			t.Errorf("GitHub user %q was checked %d times", login, count)
+		}
+	}
+	if _, ok := checked["team:sig-network"]; ok {
+		t.Errorf("team owner was checked as a GitHub user")
+	}
+}
+
+func TestLintExactMatching(t *testing.T) {
+	csv := `name,owner,sig
+Volumes should mount,alice,storage
+volumes should mount,bob,storage
+`
+	linter := &Linter{Matching: MatchExact}
+	problems, err := linter.Lint(bytes.NewReader([]byte(csv)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(problems) != 0 {
+		t.Errorf("expected no problems with exact matching, got %v", problems)
+	}
+}
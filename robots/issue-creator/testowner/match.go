@@ -0,0 +1,164 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package testowner
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// MatchMode determines how strictly test names are compared to the names in the owners data.
+type MatchMode int
+
+const (
+	// MatchNormalized ignores tags, case, unicode presentation differences, and extra whitespace.
+	MatchNormalized MatchMode = iota
+	// MatchExact requires the test name to be identical to the owners entry.
+	MatchExact
+	// MatchWhitespace requires identical names after leading, trailing, and repeated whitespace
+	// is collapsed.
+	MatchWhitespace
+	// MatchPunctuation is MatchNormalized that also ignores punctuation other than '*'.
+	MatchPunctuation
+	// MatchFuzzy is MatchPunctuation that falls back to the closest entry within a few edits
+	// when no entry matches.
+	MatchFuzzy
+)
+
+// ParseMatchMode converts "exact", "whitespace", "normalized", "punctuation", or "fuzzy" into a
+// MatchMode.
+func ParseMatchMode(mode string) (MatchMode, error) {
+	switch mode {
+	case "exact":
+		return MatchExact, nil
+	case "whitespace":
+		return MatchWhitespace, nil
+	case "normalized":
+		return MatchNormalized, nil
+	case "punctuation":
+		return MatchPunctuation, nil
+	case "fuzzy":
+		return MatchFuzzy, nil
+	}
+	return MatchNormalized, fmt.Errorf("unknown test owners matching mode %q", mode)
+}
+
+// key turns a test name into the form that is compared against the owners data under mode.
+func (mode MatchMode) key(name string) string {
+	switch mode {
+	case MatchExact:
+		return name
+	case MatchWhitespace:
+		return strings.TrimSpace(whiteSpaceRegex.ReplaceAllString(name, " "))
+	case MatchPunctuation, MatchFuzzy:
+		return stripPunctuation(normalize(name))
+	}
+	return normalize(name)
+}
+
+// stripPunctuation removes punctuation, except for the '*' wildcard, and collapses the whitespace
+// that is left behind.
+func stripPunctuation(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if r != '*' && unicode.IsPunct(r) {
+			return ' '
+		}
+		return r
+	}, name)
+	return strings.TrimSpace(whiteSpaceRegex.ReplaceAllString(name, " "))
+}
+
+// fuzzyNames lists the keys of the mapping that aren't patterns in alphabetical order, which is the
+// order in which equally close candidates are preferred.
+func fuzzyNames(mapping map[string]*OwnerInfo) []string {
+	var names []string
+	for name := range mapping {
+		if !isPattern(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// closest returns the candidate with the fewest edits from name, or the empty string if every
+// candidate needs more than maxFuzzyEdits(name) edits.
+func closest(name string, candidates []string) string {
+	best := ""
+	bestDistance := maxFuzzyEdits(name) + 1
+	for _, candidate := range candidates {
+		if d := editDistance(name, candidate, bestDistance); d < bestDistance {
+			best, bestDistance = candidate, d
+		}
+	}
+	return best
+}
+
+// maxFuzzyEdits allows one edit per ten characters of the name, and at least one.
+func maxFuzzyEdits(name string) int {
+	if n := len([]rune(name)) / 10; n > 1 {
+		return n
+	}
+	return 1
+}
+
+// editDistance returns the Levenshtein distance between a and b, or limit if it is at least limit.
+func editDistance(a, b string, limit int) int {
+	ra, rb := []rune(a), []rune(b)
+	if d := len(ra) - len(rb); d >= limit || -d >= limit {
+		return limit
+	}
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		rowMin := cur[0]
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if cur[j] < rowMin {
+				rowMin = cur[j]
+			}
+		}
+		if rowMin >= limit {
+			return limit
+		}
+		prev, cur = cur, prev
+	}
+	if prev[len(rb)] < limit {
+		return prev[len(rb)]
+	}
+	return limit
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
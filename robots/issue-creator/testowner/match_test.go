@@ -0,0 +1,156 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package testowner
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestMatchModes(t *testing.T) {
+	csv := `name,owner,sig
+DEFAULT,dflt,
+Volumes [Feature:Volumes] should mount,alice,storage
+Kubelet: pods should be restarted,bob,node
+Upgrade *,carol,cluster-lifecycle
+`
+	tests := []struct {
+		name     string
+		test     string
+		expected map[MatchMode]string
+	}{
+		{
+			name: "identical",
+			test: "Volumes [Feature:Volumes] should mount",
+			expected: map[MatchMode]string{
+				MatchExact: "alice", MatchWhitespace: "alice", MatchNormalized: "alice", MatchPunctuation: "alice", MatchFuzzy: "alice",
+			},
+		},
+		{
+			name: "extra whitespace",
+			test: "  Volumes  [Feature:Volumes]   should mount ",
+			expected: map[MatchMode]string{
+				MatchExact: "dflt", MatchWhitespace: "alice", MatchNormalized: "alice", MatchPunctuation: "alice", MatchFuzzy: "alice",
+			},
+		},
+		{
+			name: "tags and case",
+			test: "volumes should mount",
+			expected: map[MatchMode]string{
+				MatchExact: "dflt", MatchWhitespace: "dflt", MatchNormalized: "alice", MatchPunctuation: "alice", MatchFuzzy: "alice",
+			},
+		},
+		{
+			name: "punctuation",
+			test: "Kubelet pods should be restarted.",
+			expected: map[MatchMode]string{
+				MatchExact: "dflt", MatchWhitespace: "dflt", MatchNormalized: "dflt", MatchPunctuation: "bob", MatchFuzzy: "bob",
+			},
+		},
+		{
+			name: "typo",
+			test: "Kubelet: pods shuold be restarted",
+			expected: map[MatchMode]string{
+				MatchExact: "dflt", MatchWhitespace: "dflt", MatchNormalized: "dflt", MatchPunctuation: "dflt", MatchFuzzy: "bob",
+			},
+		},
+		{
+			name: "too different",
+			test: "Kubelet: nodes should be rebooted",
+			expected: map[MatchMode]string{
+				MatchExact: "dflt", MatchWhitespace: "dflt", MatchNormalized: "dflt", MatchPunctuation: "dflt", MatchFuzzy: "dflt",
+			},
+		},
+		{
+			name: "pattern",
+			test: "Upgrade master",
+			expected: map[MatchMode]string{
+				MatchExact: "carol", MatchWhitespace: "carol", MatchNormalized: "carol", MatchPunctuation: "carol", MatchFuzzy: "carol",
+			},
+		},
+	}
+	for mode := MatchNormalized; mode <= MatchFuzzy; mode++ {
+		list, _, err := NewOwnerListFromCsvWithOptions(bytes.NewReader([]byte(csv)), Options{Matching: mode})
+		if err != nil {
+			t.Fatalf("mode %d: unexpected error: %v", mode, err)
+		}
+		for _, test := range tests {
+			if owner := list.TestOwner(test.test); owner != test.expected[mode] {
+				t.Errorf("%s: mode %d: TestOwner(%q) = %q, expected %q", test.name, mode, test.test, owner, test.expected[mode])
+			}
+		}
+	}
+}
+
+func TestParseMatchMode(t *testing.T) {
+	for input, expected := range map[string]MatchMode{
+		"exact":       MatchExact,
+		"whitespace":  MatchWhitespace,
+		"normalized":  MatchNormalized,
+		"punctuation": MatchPunctuation,
+		"fuzzy":       MatchFuzzy,
+	} {
+		if mode, err := ParseMatchMode(input); err != nil || mode != expected {
+			t.Errorf("ParseMatchMode(%q) = %v, %v; expected %v", input, mode, err, expected)
+		}
+	}
+	if _, err := ParseMatchMode("loose"); err == nil {
+		t.Errorf("ParseMatchMode(\"loose\") returned no error")
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		limit    int
+		expected int
+	}{
+		{"", "", 5, 0},
+		{"abc", "abc", 5, 0},
+		{"kitten", "sitting", 5, 3},
+		{"kitten", "sitting", 2, 2},
+		{"should", "shuold", 5, 2},
+		{"short", "a much longer name", 3, 3},
+		{"café", "cafe", 5, 1},
+	}
+	for _, test := range tests {
+		if d := editDistance(test.a, test.b, test.limit); d != test.expected {
+			t.Errorf("editDistance(%q, %q, %d) = %d, expected %d", test.a, test.b, test.limit, d, test.expected)
+		}
+	}
+}
+
+func TestMatchResults(t *testing.T) {
+	csv := `name,owner,sig
+Kubelet: pods should be restarted,bob,node
+Upgrade *,carol,cluster-lifecycle
+`
+	list, _, err := NewOwnerListFromCsvWithOptions(bytes.NewReader([]byte(csv)), Options{Matching: MatchFuzzy})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for test, expected := range map[string]string{
+		"Kubelet: pods should be restarted": "hit",
+		"Upgrade master":                    "hit",
+		"Kubelet: pods shuold be restarted": "fuzzy",
+		"Something else entirely":           "miss",
+	} {
+		if result := list.resolve(list.mode.key(test)).result(); result != expected {
+			t.Errorf("lookup(%q) result = %q, expected %q", test, result, expected)
+		}
+	}
+}
@@ -18,6 +18,7 @@ package testowner
 
 import (
 	"encoding/csv"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -26,20 +27,70 @@ import (
 	"path/filepath"
 	"regexp"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
+	"unicode"
 
 	"github.com/golang/glog"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/text/unicode/norm"
 )
 
+// lastReloadGauge records when a ReloadingOwnerList last successfully loaded its owners file.
+var lastReloadGauge = prometheus.NewGauge(prometheus.GaugeOpts{
+	Name: "issue_creator_test_owners_last_reload_timestamp_seconds",
+	Help: "Unix time of the last successful load of the test owners file.",
+})
+
+// lookupCounter counts test owner lookups by the type of mapper ("list" or "service") and whether
+// the test was matched exactly or by a pattern ("hit"), only by fuzzy matching ("fuzzy"), not at
+// all ("miss"), or could not be looked up ("error").
+var lookupCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "issue_creator_owner_lookups_total",
+	Help: "Test owner lookups by mapper type and result (hit, fuzzy, miss, or error).",
+}, []string{"mapper", "result"})
+
+func init() {
+	prometheus.MustRegister(lastReloadGauge)
+	prometheus.MustRegister(lookupCounter)
+}
+
 var tagRegex = regexp.MustCompile(`\[.*?\]|\{.*?\}`)
 var whiteSpaceRegex = regexp.MustCompile(`\s+`)
 
+// punctuationFolds maps typographic punctuation found in test names to its ASCII equivalent, so
+// that names copied through docs or spreadsheets still match.
+var punctuationFolds = strings.NewReplacer(
+	"\u2018", "'", "\u2019", "'", "\u201a", "'", "\u201b", "'", "\u2032", "'",
+	"\u201c", `"`, "\u201d", `"`, "\u201e", `"`, "\u201f", `"`, "\u2033", `"`,
+	"\u2010", "-", "\u2011", "-", "\u2012", "-", "\u2013", "-", "\u2014", "-", "\u2015", "-", "\u2212", "-",
+	"\uff3b", "[", "\uff3d", "]", "\u3010", "[", "\u3011", "]", "\u3014", "[", "\u3015", "]",
+	"\uff5b", "{", "\uff5d", "}",
+	"\u2026", "...",
+)
+
+// foldRune maps any unicode space to ' ' and drops emoji, symbols, and invisible formatting runes.
+func foldRune(r rune) rune {
+	switch {
+	case unicode.IsSpace(r):
+		return ' '
+	case unicode.In(r, unicode.So, unicode.Cf, unicode.Variation_Selector):
+		return -1
+	case r >= 0x1f3fb && r <= 0x1f3ff: // Emoji skin tone modifiers.
+		return -1
+	}
+	return r
+}
+
 // Turn a test name into a canonical form (without tags, lowercase, etc.)
 func normalize(name string) string {
-	tagLess := tagRegex.ReplaceAll([]byte(name), []byte(""))
-	squeezed := whiteSpaceRegex.ReplaceAll(tagLess, []byte(" "))
-	return strings.ToLower(strings.TrimSpace(string(squeezed)))
+	name = punctuationFolds.Replace(norm.NFC.String(name))
+	name = strings.Map(foldRune, name)
+	tagLess := tagRegex.ReplaceAllString(name, "")
+	squeezed := whiteSpaceRegex.ReplaceAllString(tagLess, " ")
+	return strings.ToLower(strings.TrimSpace(squeezed))
 }
 
 // OwnerInfo stores the SIG and user which have responsibility for the test.
@@ -48,41 +99,204 @@ type OwnerInfo struct {
 	User string
 	// SIG holding responsibility for this test.
 	SIG string
+	// MentionOnly is true iff User only opted into tracking the test, and is mentioned on its issues
+	// rather than assigned. The zero value assigns User, like an owners CSV row without an
+	// 'auto-assigned' value.
+	MentionOnly bool
+	// Until is the date after which User is no longer responsible for the test. The zero value
+	// means the assignment does not expire.
+	Until time.Time
 }
 
 func (o OwnerInfo) String() string {
 	return "OwnerInfo{User:'" + o.User + "', SIG:'" + o.SIG + "'}"
 }
 
+// defaultName is the (normalized) test name of the row that supplies owners for tests that are
+// not matched by any other row or whose assignment has expired. A DEFAULT row with a SIG only
+// supplies owners for that SIG's tests.
+const defaultName = "default"
+
+// untilFormat is the date format of the optional 'until' column.
+const untilFormat = "2006-01-02"
+
 // OwnerList uses a map to get owners for a given test name.
 type OwnerList struct {
 	mapping map[string]*OwnerInfo
-	rng     *rand.Rand
+	// patterns holds the keys of mapping that are patterns, most specific first.
+	patterns []string
+	// defaultOwner is the DEFAULT row without a SIG, if there is one.
+	defaultOwner *OwnerInfo
+	// sigDefaults maps lowercase SIG names to the DEFAULT row for that SIG.
+	sigDefaults map[string]*OwnerInfo
+	rng         *rand.Rand
+	// mode determines how test names are compared to the keys of mapping.
+	mode MatchMode
+	// fuzzy holds the keys of mapping that aren't patterns, which MatchFuzzy falls back to.
+	fuzzy []string
+	// now returns the current time and is used to check for expired assignments.
+	now func() time.Time
+
+	lock sync.Mutex
+	// memo holds the pattern and fuzzy matches of up to maxMemoized keys, so that repeated lookups
+	// of tests without an exact entry skip the scan. It is guarded by lock.
+	memo map[string]match
 }
 
-// get returns the Owner for the test with the exact name or the first blob match. Nil is returned
-// if none are matched.
-func (o *OwnerList) get(testName string) (owner *OwnerInfo) {
-	name := normalize(testName)
+// maxMemoized bounds the number of keys in OwnerList.memo. When it is full it is emptied, which
+// is cheaper than tracking use for the few runs that look up that many distinct tests.
+const maxMemoized = 10000
 
-	// exact mapping
-	owner, _ = o.mapping[name]
+// match is the result of resolving a test name.
+type match struct {
+	// owner is the matched entry, or nil if there is none.
+	owner *OwnerInfo
+	// fuzzy is true iff the entry was only found by fuzzy matching.
+	fuzzy bool
+}
 
-	// glob matching
+// result returns the lookupCounter result of the match.
+func (m match) result() string {
+	switch {
+	case m.owner == nil:
+		return "miss"
+	case m.fuzzy:
+		return "fuzzy"
+	}
+	return "hit"
+}
+
+// get returns the Owner for the test with the exact name or the most specific pattern match. If
+// none are matched the DEFAULT row is returned. If the match has no user or its assignment has
+// expired, the user comes from the DEFAULT row for the test's SIG, or else the DEFAULT row. Nil
+// is returned if there is no owner and no DEFAULT row.
+func (o *OwnerList) get(testName string) *OwnerInfo {
+	owner := o.lookup(testName)
 	if owner == nil {
-		keys := []string{}
-		for k := range o.mapping {
-			keys = append(keys, k)
-		}
-		sort.Strings(keys)
-		for _, k := range keys {
-			if match, _ := filepath.Match(k, name); match {
-				owner = o.mapping[k]
-				return
+		return o.defaultOwner
+	}
+	if strings.TrimSpace(owner.User) != "" && !o.expired(owner) {
+		return owner
+	}
+	// The SIG is still responsible for the test, only the individual is replaced.
+	fallback := &OwnerInfo{SIG: owner.SIG}
+	if def := o.defaultFor(owner.SIG); def != nil {
+		fallback.User = def.User
+		fallback.MentionOnly = def.MentionOnly
+	}
+	return fallback
+}
+
+// defaultFor returns the DEFAULT row for the SIG, or the global DEFAULT row if the SIG has none.
+func (o *OwnerList) defaultFor(sig string) *OwnerInfo {
+	if def, ok := o.sigDefaults[strings.ToLower(strings.TrimSpace(sig))]; ok {
+		return def
+	}
+	return o.defaultOwner
+}
+
+// expired returns true iff the owner's assignment has an 'until' date that has passed.
+func (o *OwnerList) expired(owner *OwnerInfo) bool {
+	return !owner.Until.IsZero() && o.now().After(owner.Until)
+}
+
+// lookup returns the Owner for the test with the exact name or the most specific pattern match.
+// Nil is returned if none are matched.
+func (o *OwnerList) lookup(testName string) *OwnerInfo {
+	m := o.resolve(o.mode.key(testName))
+	lookupCounter.WithLabelValues("list", m.result()).Inc()
+	return m.owner
+}
+
+// resolve finds the Owner for a test name that has already been turned into a key.
+func (o *OwnerList) resolve(name string) match {
+	// exact mapping
+	if owner, ok := o.mapping[name]; ok {
+		return match{owner: owner}
+	}
+	o.lock.Lock()
+	m, ok := o.memo[name]
+	o.lock.Unlock()
+	if ok {
+		return m
+	}
+	m = o.scan(name)
+	o.lock.Lock()
+	if len(o.memo) >= maxMemoized {
+		o.memo = make(map[string]match)
+	}
+	o.memo[name] = m
+	o.lock.Unlock()
+	return m
+}
+
+// scan matches a key without an exact entry against the patterns, and then the closest entry.
+func (o *OwnerList) scan(name string) match {
+	// pattern matching
+	for _, pattern := range o.patterns {
+		if matchPattern(pattern, name) {
+			return match{owner: o.mapping[pattern]}
+		}
+	}
+	// closest entry
+	if o.mode == MatchFuzzy {
+		if nearest := closest(name, o.fuzzy); nearest != "" {
+			return match{owner: o.mapping[nearest], fuzzy: true}
+		}
+	}
+	return match{}
+}
+
+// isPattern returns true iff an owners entry uses the syntax of filepath.Match, which is how the
+// entry is matched against test names.
+func isPattern(name string) bool {
+	return strings.ContainsAny(name, `*?[\`)
+}
+
+// sortPatterns orders pattern entries so that the one with the most literal characters comes
+// first (e.g. "volume disk format *" before "volume *"). Ties are broken alphabetically, which
+// used to be the only order, so entries that match the same tests with as many literal characters
+// keep their old precedence.
+func sortPatterns(patterns []string) {
+	sort.Slice(patterns, func(i, j int) bool {
+		if li, lj := literalLength(patterns[i]), literalLength(patterns[j]); li != lj {
+			return li > lj
+		}
+		return patterns[i] < patterns[j]
+	})
+}
+
+// literalLength counts the characters of a pattern that only match themselves. Wildcards and
+// character classes don't count.
+func literalLength(pattern string) int {
+	n := 0
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '*', '?':
+		case '[':
+			if end := strings.IndexByte(pattern[i+1:], ']'); end >= 0 {
+				i += end + 1
 			}
+		case '\\':
+			i++
+			n++
+		default:
+			n++
 		}
 	}
-	return
+	return n
+}
+
+// matchPattern reports whether the normalized test name is matched by an owners entry, with the
+// syntax of filepath.Match: '*' matches any run of characters other than '/', '?' matches one such
+// character, and '[...]' matches a character class. An entry like "upgrade *" claims every test
+// whose name starts with "upgrade " and has no '/' after it. Malformed patterns match nothing.
+//
+// Tags like "[sig-node]" are normalized away, so character classes only survive in the exact and
+// whitespace matching modes. The punctuation and fuzzy modes also drop '?'.
+func matchPattern(pattern, name string) bool {
+	match, err := filepath.Match(pattern, name)
+	return err == nil && match
 }
 
 // TestOwner returns the owner for a test or the empty string if none is found.
@@ -91,14 +305,91 @@ func (o *OwnerList) TestOwner(testName string) (owner string) {
 	if ownerInfo != nil {
 		owner = ownerInfo.User
 	}
+	return o.pickUser(owner)
+}
 
-	if strings.Contains(owner, "/") {
-		ownerSet := strings.Split(owner, "/")
-		owner = ownerSet[o.rng.Intn(len(ownerSet))]
+// TestInfo returns the owner, SIG, and whether the owner should be assigned for a test. The zero
+// OwnerInfo is returned if none is found.
+func (o *OwnerList) TestInfo(testName string) OwnerInfo {
+	ownerInfo := o.get(testName)
+	if ownerInfo == nil {
+		return OwnerInfo{}
+	}
+	info := *ownerInfo
+	info.User = o.pickUser(info.User)
+	info.SIG = strings.TrimSpace(info.SIG)
+	return info
+}
+
+// pickUser chooses a single user from an owners entry.
+func (o *OwnerList) pickUser(owner string) string {
+	if strings.Contains(owner, "/") || strings.Contains(owner, ":") {
+		owner = o.pickOwner(owner)
 	}
 	return strings.TrimSpace(owner)
 }
 
+// BatchTestOwners returns the owner of each test, in the same order as testNames.
+func (o *OwnerList) BatchTestOwners(testNames []string) []string {
+	owners := make([]string, len(testNames))
+	for i, test := range testNames {
+		owners[i] = o.TestOwner(test)
+	}
+	return owners
+}
+
+// pickOwner chooses one user from a '/' separated list of owners at random. Each owner may carry
+// a weight, e.g. "alice:3/bob:1" assigns alice three times as often as bob. Owners without a
+// valid positive weight count as 1.
+func (o *OwnerList) pickOwner(owners string) string {
+	var users []string
+	var weights []int
+	total := 0
+	for _, entry := range strings.Split(owners, "/") {
+		user, weight := parseWeightedOwner(entry)
+		if user == "" {
+			continue
+		}
+		users = append(users, user)
+		weights = append(weights, weight)
+		total += weight
+	}
+	if total == 0 {
+		return ""
+	}
+
+	o.lock.Lock()
+	n := o.rng.Intn(total)
+	o.lock.Unlock()
+	for i, weight := range weights {
+		if n < weight {
+			return users[i]
+		}
+		n -= weight
+	}
+	return users[len(users)-1]
+}
+
+// parseWeightedOwner splits an owner entry of the form "user" or "user:weight". A suffix that is
+// not a number is not a weight, so entries like "team:sig-node" are returned whole.
+func parseWeightedOwner(entry string) (string, int) {
+	entry = strings.TrimSpace(entry)
+	i := strings.LastIndex(entry, ":")
+	if i < 0 {
+		return entry, 1
+	}
+	weight, err := strconv.Atoi(strings.TrimSpace(entry[i+1:]))
+	if err != nil {
+		return entry, 1
+	}
+	user := strings.TrimSpace(entry[:i])
+	if weight < 1 {
+		glog.Warningf("Invalid weight in test owner %q, using 1.", entry)
+		return user, 1
+	}
+	return user, weight
+}
+
 // TestSIG returns the SIG assigned to a test, or else the empty string if none is found.
 func (o *OwnerList) TestSIG(testName string) string {
 	ownerInfo := o.get(testName)
@@ -108,30 +399,296 @@ func (o *OwnerList) TestSIG(testName string) string {
 	return strings.TrimSpace(ownerInfo.SIG)
 }
 
+// BatchTestSIGs returns the SIG of each test, in the same order as testNames.
+func (o *OwnerList) BatchTestSIGs(testNames []string) []string {
+	sigs := make([]string, len(testNames))
+	for i, test := range testNames {
+		sigs[i] = o.TestSIG(test)
+	}
+	return sigs
+}
+
+// OwnershipGaps lists the tests that could not be routed to an owner or a SIG.
+type OwnershipGaps struct {
+	// NoOwner holds the tests without an individual owner.
+	NoOwner []string
+	// NoSIG holds the tests without a SIG.
+	NoSIG []string
+	// Expired holds the tests whose owner's assignment has expired and needs re-ownership.
+	Expired []string
+}
+
+// Empty returns true iff every test has both an owner and a SIG and no assignment has expired.
+func (g *OwnershipGaps) Empty() bool {
+	return len(g.NoOwner) == 0 && len(g.NoSIG) == 0 && len(g.Expired) == 0
+}
+
+// OwnershipGaps reports which of the given tests have no owner, which have no SIG, and which have
+// an expired owner. The DEFAULT row does not count as an owner. Each list is sorted and free of
+// duplicates.
+func (o *OwnerList) OwnershipGaps(testNames []string) *OwnershipGaps {
+	gaps := &OwnershipGaps{}
+	seen := make(map[string]bool)
+	for _, test := range testNames {
+		if seen[test] {
+			continue
+		}
+		seen[test] = true
+		info := o.lookup(test)
+		if info == nil || strings.TrimSpace(info.User) == "" {
+			gaps.NoOwner = append(gaps.NoOwner, test)
+		} else if o.expired(info) {
+			gaps.Expired = append(gaps.Expired, test)
+		}
+		if info == nil || strings.TrimSpace(info.SIG) == "" {
+			gaps.NoSIG = append(gaps.NoSIG, test)
+		}
+	}
+	sort.Strings(gaps.NoOwner)
+	sort.Strings(gaps.NoSIG)
+	sort.Strings(gaps.Expired)
+	return gaps
+}
+
+// ExpiredOwner is an owners entry whose assignment has expired.
+type ExpiredOwner struct {
+	// Name is the normalized test name or pattern of the entry.
+	Name string
+	OwnerInfo
+}
+
+// ExpiredOwners returns every entry whose 'until' date has passed, sorted by name. These tests
+// need a new owner.
+func (o *OwnerList) ExpiredOwners() []ExpiredOwner {
+	var expired []ExpiredOwner
+	for name, info := range o.mapping {
+		if o.expired(info) {
+			expired = append(expired, ExpiredOwner{Name: name, OwnerInfo: *info})
+		}
+	}
+	sort.Slice(expired, func(i, j int) bool { return expired[i].Name < expired[j].Name })
+	return expired
+}
+
+// ConflictPolicy determines how rows of the owners data that normalize to the same test name but
+// disagree on the owner or SIG are handled.
+type ConflictPolicy int
+
+const (
+	// FirstWins keeps the first row for the test name and ignores the rest.
+	FirstWins ConflictPolicy = iota
+	// FailOnConflict refuses to load owners data that contains conflicting rows.
+	FailOnConflict
+	// MergeConflicts combines the owners of every row and keeps the first non-empty SIG.
+	MergeConflicts
+)
+
+// ParseConflictPolicy converts "first-wins", "error", or "merge" into a ConflictPolicy.
+func ParseConflictPolicy(policy string) (ConflictPolicy, error) {
+	switch policy {
+	case "first-wins":
+		return FirstWins, nil
+	case "error":
+		return FailOnConflict, nil
+	case "merge":
+		return MergeConflicts, nil
+	}
+	return FirstWins, fmt.Errorf("unknown test owners conflict policy %q", policy)
+}
+
+// Conflict describes rows of the owners data that normalize to the same test name but disagree
+// on the owner or SIG.
+type Conflict struct {
+	// Name is the normalized test name.
+	Name string
+	// Rows holds the conflicting entries in the order they were read.
+	Rows []OwnerInfo
+}
+
+func (c Conflict) String() string {
+	rows := make([]string, 0, len(c.Rows))
+	for _, row := range c.Rows {
+		rows = append(rows, row.String())
+	}
+	return fmt.Sprintf("%q: %s", c.Name, strings.Join(rows, ", "))
+}
+
+// Options control how an OwnerList is built from the owners data.
+type Options struct {
+	// Conflicts determines how rows for the same test name with different owners are handled.
+	Conflicts ConflictPolicy
+	// Matching determines how strictly test names must match the owners data.
+	Matching MatchMode
+}
+
+// ownerRow is a single test name to owner entry, before normalization.
+type ownerRow struct {
+	name string
+	info *OwnerInfo
+	// line is the line of the owners file the row was read from, or 0 if it wasn't read from a file.
+	line int
+}
+
+// key returns the mapping key for the row under mode. DEFAULT rows are recognized regardless of
+// the matching mode, and a DEFAULT row with a SIG gets a key of its own. Braces can't survive
+// normalization so the SIG key can't collide with a test name.
+func (row ownerRow) key(mode MatchMode) string {
+	if normalize(row.name) != defaultName {
+		return mode.key(row.name)
+	}
+	if sig := strings.TrimSpace(row.info.SIG); sig != "" {
+		return "{" + defaultName + " " + strings.ToLower(sig) + "}"
+	}
+	return defaultName
+}
+
 // NewOwnerList constructs an OwnerList given a mapping from test names to test owners.
+// When several names normalize to the same test name, the alphabetically first one wins. The
+// owners of the entries are assigned unless MentionOnly is set, as they are for CSV rows.
 func NewOwnerList(mapping map[string]*OwnerInfo) *OwnerList {
+	names := make([]string, 0, len(mapping))
+	for name := range mapping {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	rows := make([]ownerRow, 0, len(names))
+	for _, name := range names {
+		rows = append(rows, ownerRow{name: name, info: mapping[name]})
+	}
+	list, conflicts, _ := newOwnerList(rows, Options{})
+	for _, conflict := range conflicts {
+		glog.Warningf("Conflicting test owners for %v.", conflict)
+	}
+	return list
+}
+
+// newOwnerList constructs an OwnerList from rows in priority order, combining rows that match the
+// same test name according to opts.Conflicts. Conflicting rows are returned, and are an error under
+// FailOnConflict.
+func newOwnerList(rows []ownerRow, opts Options) (*OwnerList, []Conflict, error) {
+	policy := opts.Conflicts
 	list := OwnerList{}
 	list.rng = rand.New(rand.NewSource(time.Now().UnixNano()))
+	list.now = time.Now
+	list.mode = opts.Matching
 	list.mapping = make(map[string]*OwnerInfo)
-	for input, output := range mapping {
-		list.mapping[normalize(input)] = output
+	seen := make(map[string][]OwnerInfo)
+	var conflicted []string
+	for _, row := range rows {
+		name := row.key(opts.Matching)
+		existing, ok := list.mapping[name]
+		if !ok {
+			list.mapping[name] = row.info
+			seen[name] = []OwnerInfo{*row.info}
+			continue
+		}
+		if sameOwner(existing, row.info) {
+			continue
+		}
+		if len(seen[name]) == 1 {
+			// First disagreement for this name.
+			conflicted = append(conflicted, name)
+		}
+		seen[name] = append(seen[name], *row.info)
+		if policy == MergeConflicts {
+			list.mapping[name] = mergeOwners(existing, row.info)
+		}
+	}
+
+	var conflicts []Conflict
+	for _, name := range conflicted {
+		conflicts = append(conflicts, Conflict{Name: name, Rows: seen[name]})
+	}
+	if policy == FailOnConflict && len(conflicts) > 0 {
+		return nil, conflicts, fmt.Errorf("%d test names have conflicting owners, first is %v", len(conflicts), conflicts[0])
+	}
+
+	list.sigDefaults = make(map[string]*OwnerInfo)
+	for name, owner := range list.mapping {
+		if name == defaultName {
+			list.defaultOwner = owner
+			delete(list.mapping, name)
+		} else if strings.HasPrefix(name, "{"+defaultName+" ") {
+			list.sigDefaults[strings.ToLower(strings.TrimSpace(owner.SIG))] = owner
+			delete(list.mapping, name)
+		}
+	}
+
+	for name := range list.mapping {
+		if isPattern(name) {
+			list.patterns = append(list.patterns, name)
+		}
+	}
+	sortPatterns(list.patterns)
+	if list.mode == MatchFuzzy {
+		list.fuzzy = fuzzyNames(list.mapping)
+	}
+	list.memo = make(map[string]match)
+	return &list, conflicts, nil
+}
+
+// sameOwner returns true iff both entries name the same owner and SIG, ignoring whitespace.
+func sameOwner(a, b *OwnerInfo) bool {
+	return strings.TrimSpace(a.User) == strings.TrimSpace(b.User) &&
+		strings.TrimSpace(a.SIG) == strings.TrimSpace(b.SIG)
+}
+
+// mergeOwners combines the '/' separated owners of both entries and keeps the first non-empty SIG.
+func mergeOwners(a, b *OwnerInfo) *OwnerInfo {
+	var users []string
+	seen := make(map[string]bool)
+	for _, user := range strings.Split(a.User+"/"+b.User, "/") {
+		user = strings.TrimSpace(user)
+		if user == "" || seen[user] {
+			continue
+		}
+		seen[user] = true
+		users = append(users, user)
 	}
-	return &list
+	sig := a.SIG
+	if strings.TrimSpace(sig) == "" {
+		sig = b.SIG
+	}
+	return &OwnerInfo{User: strings.Join(users, "/"), SIG: sig, Until: a.Until, MentionOnly: a.MentionOnly && b.MentionOnly}
 }
 
 // NewOwnerListFromCsv constructs an OwnerList given a CSV file that includes
-// 'owner' and 'test name' columns.
+// 'owner' and 'test name' columns. Conflicting rows are logged and the first one wins.
 func NewOwnerListFromCsv(r io.Reader) (*OwnerList, error) {
+	list, conflicts, err := NewOwnerListFromCsvWithOptions(r, Options{})
+	for _, conflict := range conflicts {
+		glog.Warningf("Conflicting test owners for %v.", conflict)
+	}
+	return list, err
+}
+
+// NewOwnerListFromCsvWithOptions constructs an OwnerList given a CSV file that includes
+// 'owner' and 'test name' columns, and optionally an 'until' column holding the date (YYYY-MM-DD)
+// after which the owner is replaced by the DEFAULT row's owner. Test names are matched according
+// to opts.Matching. Rows for the same test name that disagree on the owner or SIG are handled
+// according to opts.Conflicts and returned to the caller.
+func NewOwnerListFromCsvWithOptions(r io.Reader, opts Options) (*OwnerList, []Conflict, error) {
+	rows, err := readOwnerRows(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	return newOwnerList(rows, opts)
+}
+
+// readOwnerRows reads the rows of a test owners CSV file in order.
+func readOwnerRows(r io.Reader) ([]ownerRow, error) {
 	reader := csv.NewReader(r)
 	records, err := reader.ReadAll()
 	if err != nil {
 		return nil, err
 	}
-	mapping := make(map[string]*OwnerInfo)
+	var rows []ownerRow
 	ownerCol := -1
 	nameCol := -1
 	sigCol := -1
-	for _, record := range records {
+	untilCol := -1
+	autoCol := -1
+	for i, record := range records {
 		if ownerCol == -1 || nameCol == -1 || sigCol == -1 {
 			for col, val := range record {
 				switch strings.ToLower(val) {
@@ -141,26 +698,140 @@ func NewOwnerListFromCsv(r io.Reader) (*OwnerList, error) {
 					nameCol = col
 				case "sig":
 					sigCol = col
+				case "until":
+					untilCol = col
+				case "auto-assigned":
+					autoCol = col
 				}
 
 			}
 		} else {
-			mapping[record[nameCol]] = &OwnerInfo{
+			info := &OwnerInfo{
 				User: record[ownerCol],
 				SIG:  record[sigCol],
 			}
+			if autoCol != -1 && autoCol < len(record) {
+				info.MentionOnly = !parseAutoAssigned(record[autoCol])
+			}
+			if untilCol != -1 && untilCol < len(record) && strings.TrimSpace(record[untilCol]) != "" {
+				if info.Until, err = time.Parse(untilFormat, strings.TrimSpace(record[untilCol])); err != nil {
+					return nil, fmt.Errorf("invalid 'until' date for test %q: %v", record[nameCol], err)
+				}
+				// The owner remains responsible through the whole day.
+				info.Until = info.Until.AddDate(0, 0, 1).Add(-time.Nanosecond)
+			}
+			rows = append(rows, ownerRow{name: record[nameCol], info: info, line: i + 1})
 		}
 	}
-	if len(mapping) == 0 {
+	if len(rows) == 0 {
 		return nil, errors.New("no mappings found in test owners CSV")
 	}
-	return NewOwnerList(mapping), nil
+	return rows, nil
+}
+
+// parseAutoAssigned interprets the 'auto-assigned' column. Owners are assigned unless the column
+// explicitly says otherwise.
+func parseAutoAssigned(value string) bool {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return true
+	}
+	auto, err := strconv.ParseBool(value)
+	if err != nil {
+		glog.Warningf("Invalid auto-assigned value %q in test owners, assigning the owner.", value)
+		return true
+	}
+	return auto
+}
+
+// ownerEntry is a single row of the merged ownership table, as exported by WriteCSV and WriteJSON.
+type ownerEntry struct {
+	Name         string `json:"name"`
+	Owner        string `json:"owner"`
+	SIG          string `json:"sig"`
+	Until        string `json:"until,omitempty"`
+	AutoAssigned bool   `json:"auto-assigned"`
+}
+
+func newOwnerEntry(name string, info *OwnerInfo) ownerEntry {
+	entry := ownerEntry{
+		Name:         name,
+		Owner:        strings.TrimSpace(info.User),
+		SIG:          strings.TrimSpace(info.SIG),
+		AutoAssigned: !info.MentionOnly,
+	}
+	if !info.Until.IsZero() {
+		entry.Until = info.Until.Format(untilFormat)
+	}
+	return entry
+}
+
+// entries returns the normalized ownership table: the DEFAULT rows first (the global one, then one
+// per SIG ordered by SIG), followed by every test name or pattern in alphabetical order.
+func (o *OwnerList) entries() []ownerEntry {
+	var entries []ownerEntry
+	if o.defaultOwner != nil {
+		entries = append(entries, newOwnerEntry("DEFAULT", o.defaultOwner))
+	}
+	sigs := make([]string, 0, len(o.sigDefaults))
+	for sig := range o.sigDefaults {
+		sigs = append(sigs, sig)
+	}
+	sort.Strings(sigs)
+	for _, sig := range sigs {
+		entries = append(entries, newOwnerEntry("DEFAULT", o.sigDefaults[sig]))
+	}
+	names := make([]string, 0, len(o.mapping))
+	for name := range o.mapping {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		entries = append(entries, newOwnerEntry(name, o.mapping[name]))
+	}
+	return entries
+}
+
+// WriteCSV writes the merged and normalized ownership table as CSV with 'name', 'owner', 'sig',
+// 'until', and 'auto-assigned' columns. The output can be loaded with NewOwnerListFromCsv.
+func (o *OwnerList) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"name", "owner", "sig", "until", "auto-assigned"}); err != nil {
+		return err
+	}
+	for _, entry := range o.entries() {
+		auto := "0"
+		if entry.AutoAssigned {
+			auto = "1"
+		}
+		if err := writer.Write([]string{entry.Name, entry.Owner, entry.SIG, entry.Until, auto}); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteJSON writes the merged and normalized ownership table as a JSON list of objects with
+// 'name', 'owner', 'sig', 'auto-assigned', and (if set) 'until' keys.
+func (o *OwnerList) WriteJSON(w io.Writer) error {
+	entries := o.entries()
+	if entries == nil {
+		entries = []ownerEntry{}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(entries)
 }
 
 // ReloadingOwnerList maps test names to owners, reloading the mapping when the
-// underlying file is changed.
+// underlying file is changed. The mapping is swapped atomically so lookups made
+// concurrently with a reload see either the old or the new data, never a mix.
 type ReloadingOwnerList struct {
-	path      string
+	path string
+	opts Options
+
+	lock      sync.RWMutex
 	mtime     time.Time
 	ownerList *OwnerList
 }
@@ -168,7 +839,13 @@ type ReloadingOwnerList struct {
 // NewReloadingOwnerList creates a ReloadingOwnerList given a path to a CSV
 // file containing owner mapping information.
 func NewReloadingOwnerList(path string) (*ReloadingOwnerList, error) {
-	ownerList := &ReloadingOwnerList{path: path}
+	return NewReloadingOwnerListWithOptions(path, Options{})
+}
+
+// NewReloadingOwnerListWithOptions creates a ReloadingOwnerList given a path to a CSV file
+// containing owner mapping information and the options used to build the mapping.
+func NewReloadingOwnerListWithOptions(path string, opts Options) (*ReloadingOwnerList, error) {
+	ownerList := &ReloadingOwnerList{path: path, opts: opts}
 	err := ownerList.reload()
 	if err != nil {
 		if _, ok := err.(badCsv); !ok {
@@ -187,7 +864,7 @@ func (o *ReloadingOwnerList) TestOwner(testName string) string {
 		glog.Errorf("Unable to reload test owners at %s: %v", o.path, err)
 		// Process using the previous data.
 	}
-	return o.ownerList.TestOwner(testName)
+	return o.current().TestOwner(testName)
 }
 
 // TestSIG returns the SIG for a test, or the empty string if none is found.
@@ -197,7 +874,69 @@ func (o *ReloadingOwnerList) TestSIG(testName string) string {
 		glog.Errorf("Unable to reload test owners at %s: %v", o.path, err)
 		// Process using the previous data.
 	}
-	return o.ownerList.TestSIG(testName)
+	return o.current().TestSIG(testName)
+}
+
+// BatchTestOwners returns the owner of each test, in the same order as testNames. The owners file
+// is checked for changes once for the whole batch.
+func (o *ReloadingOwnerList) BatchTestOwners(testNames []string) []string {
+	err := o.reload()
+	if err != nil {
+		glog.Errorf("Unable to reload test owners at %s: %v", o.path, err)
+		// Process using the previous data.
+	}
+	return o.current().BatchTestOwners(testNames)
+}
+
+// BatchTestSIGs returns the SIG of each test, in the same order as testNames. The owners file is
+// checked for changes once for the whole batch.
+func (o *ReloadingOwnerList) BatchTestSIGs(testNames []string) []string {
+	err := o.reload()
+	if err != nil {
+		glog.Errorf("Unable to reload test owners at %s: %v", o.path, err)
+		// Process using the previous data.
+	}
+	return o.current().BatchTestSIGs(testNames)
+}
+
+// TestInfo returns the owner, SIG, and whether the owner should be assigned for a test.
+func (o *ReloadingOwnerList) TestInfo(testName string) OwnerInfo {
+	err := o.reload()
+	if err != nil {
+		glog.Errorf("Unable to reload test owners at %s: %v", o.path, err)
+		// Process using the previous data.
+	}
+	return o.current().TestInfo(testName)
+}
+
+// OwnershipGaps reports which of the given tests have no owner and which have no SIG.
+func (o *ReloadingOwnerList) OwnershipGaps(testNames []string) *OwnershipGaps {
+	err := o.reload()
+	if err != nil {
+		glog.Errorf("Unable to reload test owners at %s: %v", o.path, err)
+		// Process using the previous data.
+	}
+	return o.current().OwnershipGaps(testNames)
+}
+
+// ExpiredOwners returns every entry whose 'until' date has passed, sorted by name.
+func (o *ReloadingOwnerList) ExpiredOwners() []ExpiredOwner {
+	err := o.reload()
+	if err != nil {
+		glog.Errorf("Unable to reload test owners at %s: %v", o.path, err)
+		// Process using the previous data.
+	}
+	return o.current().ExpiredOwners()
+}
+
+// WriteCSV writes the merged and normalized ownership table currently in use as CSV.
+func (o *ReloadingOwnerList) WriteCSV(w io.Writer) error {
+	return o.current().WriteCSV(w)
+}
+
+// WriteJSON writes the merged and normalized ownership table currently in use as JSON.
+func (o *ReloadingOwnerList) WriteJSON(w io.Writer) error {
+	return o.current().WriteJSON(w)
 }
 
 type badCsv string
@@ -206,12 +945,22 @@ func (b badCsv) Error() string {
 	return string(b)
 }
 
+// current returns the most recently loaded OwnerList.
+func (o *ReloadingOwnerList) current() *OwnerList {
+	o.lock.RLock()
+	defer o.lock.RUnlock()
+	return o.ownerList
+}
+
 func (o *ReloadingOwnerList) reload() error {
 	info, err := os.Stat(o.path)
 	if err != nil {
 		return err
 	}
-	if info.ModTime() == o.mtime {
+	o.lock.RLock()
+	unchanged := info.ModTime() == o.mtime
+	o.lock.RUnlock()
+	if unchanged {
 		return nil
 	}
 	file, err := os.Open(o.path)
@@ -219,11 +968,18 @@ func (o *ReloadingOwnerList) reload() error {
 		return err
 	}
 	defer file.Close()
-	ownerList, err := NewOwnerListFromCsv(file)
+	ownerList, conflicts, err := NewOwnerListFromCsvWithOptions(file, o.opts)
 	if err != nil {
 		return badCsv(fmt.Sprintf("could not parse owner list: %v", err))
 	}
+	for _, conflict := range conflicts {
+		glog.Warningf("Conflicting test owners in %s for %v.", o.path, conflict)
+	}
+	o.lock.Lock()
 	o.ownerList = ownerList
 	o.mtime = info.ModTime()
+	o.lock.Unlock()
+	lastReloadGauge.SetToCurrentTime()
+	glog.Infof("Loaded test owners from %s (modified %v).", o.path, info.ModTime())
 	return nil
 }
@@ -19,8 +19,11 @@ package testowner
 import (
 	"bufio"
 	"bytes"
+	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"os"
+	"reflect"
 	"testing"
 	"time"
 )
@@ -31,6 +34,16 @@ func TestNormalize(t *testing.T) {
 		"Perf [Performance]":                   "perf",
 		"[k8s.io] test [performance] stuff":    "test stuff",
 		"[k8s.io] blah {Kubernetes e2e suite}": "blah",
+		"Pod \u201cquoted\u201d name\u2019s test": `pod "quoted" name's test`,
+		"Test \uff3bFeature:Foo\uff3d thing":      "test thing",
+		"Test \u3010Slow\u3011 thing":             "test thing",
+		"Volumes \U0001f680 should mount":         "volumes should mount",
+		"Thumbs \U0001f44d\U0001f3fd\ufe0f up":    "thumbs up",
+		"Caf\u0065\u0301 test":                    "caf\u00e9 test",
+		"Non\u00a0breaking\u2003spaces":           "non breaking spaces",
+		"Range 1\u20132 \u2014 ok":                "range 1-2 - ok",
+		"Zero\u200bwidth":                         "zerowidth",
+		"\u00c9CHEC \u0130stanbul \u0394":         "\u00e9chec istanbul \u03b4",
 	}
 	for input, output := range tests {
 		result := normalize(input)
@@ -63,6 +76,74 @@ func TestOwnerList(t *testing.T) {
 	}
 }
 
+func TestBatchLookups(t *testing.T) {
+	list := NewOwnerList(map[string]*OwnerInfo{
+		"Perf [performance]": {User: "me", SIG: "group"},
+		"volume *":           {User: "alice", SIG: "storage"},
+	})
+	tests := []string{"perf [flaky]", "Unknown test", "Volume mounts", "perf"}
+	if owners := list.BatchTestOwners(tests); !reflect.DeepEqual(owners, []string{"me", "", "alice", "me"}) {
+		t.Errorf("BatchTestOwners() = %q", owners)
+	}
+	if sigs := list.BatchTestSIGs(tests); !reflect.DeepEqual(sigs, []string{"group", "", "storage", "group"}) {
+		t.Errorf("BatchTestSIGs() = %q", sigs)
+	}
+}
+
+func TestOwnerInfo(t *testing.T) {
+	csv := "name,owner,auto-assigned,sig\n" +
+		"DEFAULT,rotation,1,\n" +
+		"assigned test,alice,1,node\n" +
+		"tracked test,bob,0,node\n" +
+		"unspecified test,carol,,storage\n" +
+		"false test,dave,false,storage\n"
+	list, err := NewOwnerListFromCsv(bytes.NewReader([]byte(csv)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for test, expected := range map[string]OwnerInfo{
+		"assigned test":    {User: "alice", SIG: "node"},
+		"tracked test":     {User: "bob", SIG: "node", MentionOnly: true},
+		"unspecified test": {User: "carol", SIG: "storage"},
+		"false test":       {User: "dave", SIG: "storage", MentionOnly: true},
+		"unknown test":     {User: "rotation"},
+	} {
+		if info := list.TestInfo(test); info != expected {
+			t.Errorf("TestInfo(%q) = %v, expected %v", test, info, expected)
+		}
+	}
+}
+
+func TestOwnerInfoMapDefaults(t *testing.T) {
+	list := NewOwnerList(map[string]*OwnerInfo{
+		"assigned test": {User: "alice", SIG: "node"},
+		"tracked test":  {User: "bob", SIG: "node", MentionOnly: true},
+	})
+	for test, mentionOnly := range map[string]bool{
+		"assigned test": false,
+		"tracked test":  true,
+	} {
+		if info := list.TestInfo(test); info.MentionOnly != mentionOnly {
+			t.Errorf("TestInfo(%q).MentionOnly = %t, expected %t", test, info.MentionOnly, mentionOnly)
+		}
+	}
+}
+
+func TestOwnerListUnicode(t *testing.T) {
+	list := NewOwnerList(map[string]*OwnerInfo{
+		"Pod \"quoted\" name's test":     {User: "me", SIG: "node"},
+		"Caf\u00e9 [Feature:Latte] test": {User: "barista", SIG: "node"},
+	})
+	for test, owner := range map[string]string{
+		"Pod \u201cquoted\u201d name\u2019s test \uff3bFlaky\uff3d": "me",
+		"\u2615 Caf\u0065\u0301 \u3010Slow\u3011 test":              "barista",
+	} {
+		if actual := list.TestOwner(test); actual != owner {
+			t.Errorf("%q: expected owner %q, got %q", test, owner, actual)
+		}
+	}
+}
+
 func TestOwnerGlob(t *testing.T) {
 	list := NewOwnerList(map[string]*OwnerInfo{"blah * [performance] test *": {
 		User: "me",
@@ -86,6 +167,164 @@ func TestOwnerGlob(t *testing.T) {
 	}
 }
 
+func TestOwnerPatterns(t *testing.T) {
+	list := NewOwnerList(map[string]*OwnerInfo{
+		"Upgrade *":                 {User: "upgrader", SIG: "cluster-lifecycle"},
+		"Volume *":                  {User: "volumes", SIG: "storage"},
+		"Volume Disk Format *":      {User: "diskformat", SIG: "storage"},
+		"* should be mountable":     {User: "mounter", SIG: "storage"},
+		"Volume Disk Format legacy": {User: "legacy", SIG: "storage"},
+	})
+	cases := []struct {
+		test  string
+		owner string
+		sig   string
+	}{
+		{
+			test:  "Upgrade master upgrade should maintain a functioning cluster",
+			owner: "upgrader",
+			sig:   "cluster-lifecycle",
+		},
+		{
+			test:  "[k8s.io] Upgrade node upgrade should maintain pods [Feature:Upgrade]",
+			owner: "upgrader",
+			sig:   "cluster-lifecycle",
+		},
+		{
+			// Like filepath.Match, '*' doesn't match '/'.
+			test: "Upgrade node upgrade creating/deleting pods",
+		},
+		{
+			test:  "Volume Disk Format verify disk format type - thin is honored",
+			owner: "diskformat",
+			sig:   "storage",
+		},
+		{
+			test:  "Volume Disk Format legacy",
+			owner: "legacy",
+			sig:   "storage",
+		},
+		{
+			test:  "Volume expansion should resize",
+			owner: "volumes",
+			sig:   "storage",
+		},
+		{
+			test:  "[k8s.io] PD should be mountable",
+			owner: "mounter",
+			sig:   "storage",
+		},
+		{
+			test: "Upgrades are not a prefix match",
+		},
+	}
+	for _, tc := range cases {
+		if owner := list.TestOwner(tc.test); owner != tc.owner {
+			t.Errorf("%q: expected owner %q, got %q", tc.test, tc.owner, owner)
+		}
+		if sig := list.TestSIG(tc.test); sig != tc.sig {
+			t.Errorf("%q: expected sig %q, got %q", tc.test, tc.sig, sig)
+		}
+	}
+}
+
+func TestOwnerMemo(t *testing.T) {
+	list := NewOwnerList(map[string]*OwnerInfo{
+		"Upgrade *": {User: "upgrader", SIG: "cluster-lifecycle"},
+		"exact":     {User: "exact", SIG: "node"},
+	})
+	for _, test := range []string{"Upgrade master", "[k8s.io] upgrade  MASTER", "exact", "[sig-node] Exact"} {
+		list.TestOwner(test)
+	}
+	// Names that normalize to the same key share an entry, and exact entries aren't memoized.
+	if expected := map[string]match{"upgrade master": {owner: list.mapping["upgrade *"]}}; !reflect.DeepEqual(list.memo, expected) {
+		t.Errorf("Expected memo %v, got %v", expected, list.memo)
+	}
+	for i := 0; i < maxMemoized+5; i++ {
+		list.TestOwner(fmt.Sprintf("unowned %d", i))
+	}
+	if len(list.memo) > maxMemoized {
+		t.Errorf("Expected at most %d memoized keys, got %d", maxMemoized, len(list.memo))
+	}
+	if owner := list.TestOwner("Upgrade master"); owner != "upgrader" {
+		t.Errorf("Expected owner 'upgrader' after the memo filled up, got %q", owner)
+	}
+}
+
+func TestOwnerPatternSyntax(t *testing.T) {
+	// Entries are matched with filepath.Match, as they always were.
+	rows := []ownerRow{
+		{name: "Volume ? mounts", info: &OwnerInfo{User: "single"}},
+		{name: "Volume [a-c]? resizes", info: &OwnerInfo{User: "class"}},
+		{name: "Volume [d-f]* resizes", info: &OwnerInfo{User: "classstar"}},
+		{name: "Volume */* works", info: &OwnerInfo{User: "slash"}},
+		{name: `Volume \* literal`, info: &OwnerInfo{User: "escaped"}},
+		{name: "Volume [ malformed", info: &OwnerInfo{User: "malformed"}},
+	}
+	list, _, err := newOwnerList(rows, Options{Matching: MatchExact})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	cases := []struct {
+		test  string
+		owner string
+	}{
+		{test: "Volume A mounts", owner: "single"},
+		{test: "Volume AB mounts"},
+		{test: "Volume / mounts"},
+		{test: "Volume b1 resizes", owner: "class"},
+		{test: "Volume e12 resizes", owner: "classstar"},
+		{test: "Volume b12 resizes"},
+		{test: "Volume nfs/v4 works", owner: "slash"},
+		{test: "Volume nfs/v4/tcp works"},
+		{test: "Volume * literal", owner: "escaped"},
+		{test: "Volume x literal"},
+		{test: "Volume [ malformed", owner: "malformed"},
+		{test: "Volume x malformed"},
+	}
+	for _, tc := range cases {
+		if owner := list.TestOwner(tc.test); owner != tc.owner {
+			t.Errorf("%q: expected owner %q, got %q", tc.test, tc.owner, owner)
+		}
+	}
+	if literal := literalLength(`volume [a-c]? \* resizes`); literal != len("volume  * resizes") {
+		t.Errorf("Expected the literal length to skip wildcards and classes, got %d", literal)
+	}
+}
+
+func TestWeightedOwners(t *testing.T) {
+	list := NewOwnerList(map[string]*OwnerInfo{
+		"weighted":   {User: "alice:3/ bob:1", SIG: "node"},
+		"unweighted": {User: "alice/bob", SIG: "node"},
+		"single":     {User: "carol:5", SIG: "node"},
+		"invalid":    {User: "dave:-1/erin:0", SIG: "node"},
+		"team":       {User: "team:sig-node:3/frank", SIG: "node"},
+	})
+	counts := map[string]map[string]int{}
+	for _, test := range []string{"weighted", "unweighted", "single", "invalid", "team"} {
+		counts[test] = map[string]int{}
+		for i := 0; i < 4000; i++ {
+			counts[test][list.TestOwner(test)]++
+		}
+	}
+
+	if len(counts["weighted"]) != 2 || counts["weighted"]["alice"] < 2*counts["weighted"]["bob"] {
+		t.Errorf("expected alice to be assigned about 3 times as often as bob, got %v", counts["weighted"])
+	}
+	if len(counts["unweighted"]) != 2 || counts["unweighted"]["alice"] < 1500 || counts["unweighted"]["bob"] < 1500 {
+		t.Errorf("expected alice and bob to be assigned about equally, got %v", counts["unweighted"])
+	}
+	if counts["single"]["carol"] != 4000 {
+		t.Errorf("expected carol to always be assigned, got %v", counts["single"])
+	}
+	if len(counts["invalid"]) != 2 || counts["invalid"]["dave"] == 0 || counts["invalid"]["erin"] == 0 {
+		t.Errorf("expected invalid weights to count as 1, got %v", counts["invalid"])
+	}
+	if len(counts["team"]) != 2 || counts["team"]["team:sig-node"] < 2*counts["team"]["frank"] {
+		t.Errorf("expected team:sig-node to be picked about 3 times as often as frank, got %v", counts["team"])
+	}
+}
+
 func TestOwnerListFromCsv(t *testing.T) {
 	r := bytes.NewReader([]byte(",,,header nonsense,\n" +
 		",owner,suggested owner,name,sig\n" +
@@ -109,6 +348,221 @@ func TestOwnerListFromCsv(t *testing.T) {
 	}
 }
 
+func TestOwnerListConflicts(t *testing.T) {
+	csv := "owner,name,sig\n" +
+		"foo,Test name,node\n" +
+		"foo,[k8s.io] test name,node\n" +
+		"bar,test  NAME {Kubernetes e2e suite},\n" +
+		"baz,other test,storage\n" +
+		"baz,Other test,network\n"
+	expectedConflicts := []Conflict{
+		{Name: "test name", Rows: []OwnerInfo{{User: "foo", SIG: "node"}, {User: "bar"}}},
+		{Name: "other test", Rows: []OwnerInfo{{User: "baz", SIG: "storage"}, {User: "baz", SIG: "network"}}},
+	}
+	cases := []struct {
+		name   string
+		policy ConflictPolicy
+		err    bool
+		owners map[string]string
+		sigs   map[string]string
+	}{
+		{
+			name:   "first wins",
+			policy: FirstWins,
+			owners: map[string]string{"test name": "foo", "other test": "baz"},
+			sigs:   map[string]string{"test name": "node", "other test": "storage"},
+		},
+		{
+			name:   "error",
+			policy: FailOnConflict,
+			err:    true,
+		},
+		{
+			name:   "merge",
+			policy: MergeConflicts,
+			owners: map[string]string{"other test": "baz"},
+			sigs:   map[string]string{"test name": "node", "other test": "storage"},
+		},
+	}
+	for _, tc := range cases {
+		list, conflicts, err := NewOwnerListFromCsvWithOptions(bytes.NewReader([]byte(csv)), Options{Conflicts: tc.policy})
+		if !reflect.DeepEqual(conflicts, expectedConflicts) {
+			t.Errorf("%s: expected conflicts %v, got %v", tc.name, expectedConflicts, conflicts)
+		}
+		if tc.err {
+			if err == nil || list != nil {
+				t.Errorf("%s: expected an error and no list", tc.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tc.name, err)
+		}
+		for test, owner := range tc.owners {
+			if actual := list.TestOwner(test); actual != owner {
+				t.Errorf("%s: bad owner for %q %s != %s", tc.name, test, actual, owner)
+			}
+		}
+		for test, sig := range tc.sigs {
+			if actual := list.TestSIG(test); actual != sig {
+				t.Errorf("%s: bad sig for %q %s != %s", tc.name, test, actual, sig)
+			}
+		}
+		if tc.policy == MergeConflicts {
+			if merged := list.get("test name").User; merged != "foo/bar" {
+				t.Errorf("%s: expected merged owners foo/bar, got %s", tc.name, merged)
+			}
+		}
+	}
+}
+
+func TestParseConflictPolicy(t *testing.T) {
+	for input, expected := range map[string]ConflictPolicy{
+		"first-wins": FirstWins,
+		"error":      FailOnConflict,
+		"merge":      MergeConflicts,
+	} {
+		if policy, err := ParseConflictPolicy(input); err != nil || policy != expected {
+			t.Errorf("ParseConflictPolicy(%q) = %v, %v; expected %v", input, policy, err, expected)
+		}
+	}
+	if _, err := ParseConflictPolicy("last-wins"); err == nil {
+		t.Error("expected an error for an unknown policy")
+	}
+}
+
+func TestOwnerExpiry(t *testing.T) {
+	csv := "name,owner,sig,until\n" +
+		"DEFAULT,rotation,,\n" +
+		"current,alice,node,2000-01-10\n" +
+		"expired,bob,storage,2000-01-09\n" +
+		"forever,carol,network,\n"
+	list, err := NewOwnerListFromCsv(bytes.NewReader([]byte(csv)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	list.now = func() time.Time { return time.Date(2000, 1, 10, 12, 0, 0, 0, time.UTC) }
+
+	cases := []struct {
+		test, owner, sig string
+	}{
+		{test: "current", owner: "alice", sig: "node"},
+		{test: "expired", owner: "rotation", sig: "storage"},
+		{test: "forever", owner: "carol", sig: "network"},
+		{test: "unknown", owner: "rotation"},
+	}
+	for _, tc := range cases {
+		if owner := list.TestOwner(tc.test); owner != tc.owner {
+			t.Errorf("%s: bad owner %s != %s", tc.test, owner, tc.owner)
+		}
+		if sig := list.TestSIG(tc.test); sig != tc.sig {
+			t.Errorf("%s: bad sig %s != %s", tc.test, sig, tc.sig)
+		}
+	}
+
+	expired := list.ExpiredOwners()
+	if len(expired) != 1 || expired[0].Name != "expired" || expired[0].User != "bob" {
+		t.Errorf("expected only bob's assignment to have expired, got %+v", expired)
+	}
+	gaps := list.OwnershipGaps([]string{"current", "expired", "unknown"})
+	if !reflect.DeepEqual(gaps.Expired, []string{"expired"}) || !reflect.DeepEqual(gaps.NoOwner, []string{"unknown"}) {
+		t.Errorf("unexpected gaps %+v", gaps)
+	}
+
+	if _, err := NewOwnerListFromCsv(bytes.NewReader([]byte("name,owner,sig,until\nfoo,bar,node,soon\n"))); err == nil {
+		t.Error("expected an error for an invalid until date")
+	}
+}
+
+func TestSIGDefaultOwners(t *testing.T) {
+	csv := "name,owner,sig,until\n" +
+		"DEFAULT,rotation,,\n" +
+		"DEFAULT,node-oncall,Node,\n" +
+		"DEFAULT,storage-oncall,storage,\n" +
+		"DEFAULT,other-storage-oncall,storage,\n" +
+		"kubelet test,,node,\n" +
+		"expired kubelet test,alice,node,2000-01-01\n" +
+		"volume test,bob,storage,\n" +
+		"network test,,network,\n"
+	list, conflicts, err := NewOwnerListFromCsvWithOptions(bytes.NewReader([]byte(csv)), Options{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(conflicts) != 1 || len(conflicts[0].Rows) != 2 || conflicts[0].Rows[1].User != "other-storage-oncall" {
+		t.Errorf("expected the duplicate storage DEFAULT rows to conflict, got %v", conflicts)
+	}
+
+	cases := []struct {
+		test, owner, sig string
+	}{
+		{test: "kubelet test", owner: "node-oncall", sig: "node"},
+		{test: "expired kubelet test", owner: "node-oncall", sig: "node"},
+		{test: "volume test", owner: "bob", sig: "storage"},
+		{test: "network test", owner: "rotation", sig: "network"},
+		{test: "unknown test", owner: "rotation"},
+	}
+	for _, tc := range cases {
+		if owner := list.TestOwner(tc.test); owner != tc.owner {
+			t.Errorf("%s: bad owner %s != %s", tc.test, owner, tc.owner)
+		}
+		if sig := list.TestSIG(tc.test); sig != tc.sig {
+			t.Errorf("%s: bad sig %s != %s", tc.test, sig, tc.sig)
+		}
+	}
+}
+
+func TestOwnerListExport(t *testing.T) {
+	csv := "name,owner,sig,until,auto-assigned\n" +
+		"DEFAULT,rotation,,,\n" +
+		"DEFAULT,node-oncall,node,,1\n" +
+		"[k8s.io] Kubelet test {Kubernetes e2e suite},alice ,node,2000-01-10,1\n" +
+		"Upgrade *,bob,cluster-lifecycle,,0\n"
+	list, err := NewOwnerListFromCsv(bytes.NewReader([]byte(csv)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var out bytes.Buffer
+	if err := list.WriteCSV(&out); err != nil {
+		t.Fatalf("unexpected error writing CSV: %v", err)
+	}
+	expectedCSV := "name,owner,sig,until,auto-assigned\n" +
+		"DEFAULT,rotation,,,1\n" +
+		"DEFAULT,node-oncall,node,,1\n" +
+		"kubelet test,alice,node,2000-01-10,1\n" +
+		"upgrade *,bob,cluster-lifecycle,,0\n"
+	if out.String() != expectedCSV {
+		t.Errorf("expected CSV:\n%s\ngot:\n%s", expectedCSV, out.String())
+	}
+	// The export must load back into an equivalent list.
+	reloaded, err := NewOwnerListFromCsv(bytes.NewReader(out.Bytes()))
+	if err != nil {
+		t.Fatalf("unexpected error reloading exported CSV: %v", err)
+	}
+	var again bytes.Buffer
+	if err := reloaded.WriteCSV(&again); err != nil || again.String() != expectedCSV {
+		t.Errorf("exported CSV did not round trip (err %v):\n%s", err, again.String())
+	}
+
+	out.Reset()
+	if err := list.WriteJSON(&out); err != nil {
+		t.Fatalf("unexpected error writing JSON: %v", err)
+	}
+	var entries []map[string]interface{}
+	if err := json.Unmarshal(out.Bytes(), &entries); err != nil {
+		t.Fatalf("invalid JSON %q: %v", out.String(), err)
+	}
+	expectedJSON := []map[string]interface{}{
+		{"name": "DEFAULT", "owner": "rotation", "sig": "", "auto-assigned": true},
+		{"name": "DEFAULT", "owner": "node-oncall", "sig": "node", "auto-assigned": true},
+		{"name": "kubelet test", "owner": "alice", "sig": "node", "until": "2000-01-10", "auto-assigned": true},
+		{"name": "upgrade *", "owner": "bob", "sig": "cluster-lifecycle", "auto-assigned": false},
+	}
+	if !reflect.DeepEqual(entries, expectedJSON) {
+		t.Errorf("expected JSON %v, got %v", expectedJSON, entries)
+	}
+}
+
 func TestReloadingOwnerList(t *testing.T) {
 	cases := []struct {
 		name   string
@@ -180,3 +634,113 @@ func TestReloadingOwnerList(t *testing.T) {
 		}
 	}
 }
+
+func TestReloadingOwnerListPicksUpEdits(t *testing.T) {
+	tempfile, err := ioutil.TempFile(os.TempDir(), "ownertest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tempfile.Name())
+	tempfile.Close()
+
+	if err := ioutil.WriteFile(tempfile.Name(), []byte("owner,name,sig\nfoo,flake,node\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	list, err := NewReloadingOwnerList(tempfile.Name())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if owner := list.TestOwner("flake"); owner != "foo" {
+		t.Errorf("bad owner %s != foo", owner)
+	}
+
+	// Look up owners concurrently with the edit to exercise the swap.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			list.TestSIG("flake")
+		}
+	}()
+	// Bump the mtime explicitly rather than relying on filesystem resolution.
+	if err := ioutil.WriteFile(tempfile.Name(), []byte("owner,name,sig\nbar,flake,storage\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(tempfile.Name(), later, later); err != nil {
+		t.Fatal(err)
+	}
+	<-done
+
+	if owner := list.TestOwner("flake"); owner != "bar" {
+		t.Errorf("bad owner after edit %s != bar", owner)
+	}
+	if sig := list.TestSIG("flake"); sig != "storage" {
+		t.Errorf("bad sig after edit %s != storage", sig)
+	}
+}
+
+// benchmarkOwnerList builds an OwnerList shaped like the production owners file: mostly exact
+// names plus a handful of patterns.
+func benchmarkOwnerList() (*OwnerList, []string) {
+	mapping := make(map[string]*OwnerInfo)
+	var names []string
+	for i := 0; i < 2000; i++ {
+		name := fmt.Sprintf("[k8s.io] Feature %d should work [Slow] {Kubernetes e2e suite}", i)
+		mapping[name] = &OwnerInfo{User: "owner", SIG: "sig"}
+		names = append(names, name)
+	}
+	for i := 0; i < 50; i++ {
+		mapping[fmt.Sprintf("Family %d *", i)] = &OwnerInfo{User: "owner", SIG: "sig"}
+		names = append(names, fmt.Sprintf("Family %d member should pass", i))
+	}
+	return NewOwnerList(mapping), names
+}
+
+func BenchmarkTestOwner(b *testing.B) {
+	list, names := benchmarkOwnerList()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		list.TestOwner(names[i%len(names)])
+	}
+}
+
+func BenchmarkTestSIGUnowned(b *testing.B) {
+	list, _ := benchmarkOwnerList()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		list.TestSIG("[k8s.io] Some unowned test should still resolve quickly")
+	}
+}
+
+func TestOwnershipGaps(t *testing.T) {
+	list := NewOwnerList(map[string]*OwnerInfo{
+		"owned":      {User: "foo", SIG: "node"},
+		"no sig":     {User: "foo"},
+		"no owner":   {SIG: "node"},
+		"Upgrade *":  {User: "bar", SIG: "cluster-lifecycle"},
+		"whitespace": {User: " ", SIG: " "},
+	})
+	gaps := list.OwnershipGaps([]string{
+		"owned",
+		"unknown",
+		"no sig",
+		"no owner",
+		"Upgrade master",
+		"whitespace",
+		"unknown",
+	})
+	expected := &OwnershipGaps{
+		NoOwner: []string{"no owner", "unknown", "whitespace"},
+		NoSIG:   []string{"no sig", "unknown", "whitespace"},
+	}
+	if !reflect.DeepEqual(gaps, expected) {
+		t.Errorf("expected gaps %+v, got %+v", expected, gaps)
+	}
+	if gaps.Empty() {
+		t.Error("gaps should not be empty")
+	}
+	if gaps := list.OwnershipGaps([]string{"owned", "Upgrade node"}); !gaps.Empty() {
+		t.Errorf("expected no gaps, got %+v", gaps)
+	}
+}
@@ -0,0 +1,212 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package testowner
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+)
+
+const (
+	// serviceFailureThreshold is the number of consecutive failed requests that opens the circuit.
+	serviceFailureThreshold = 5
+	// serviceCooldown is how long an open circuit rejects lookups before trying the service again.
+	serviceCooldown = time.Minute
+)
+
+// OwnerService maps test names to owners by querying a remote ownership service. The service
+// answers GET <base>/owner?test=<name> and GET <base>/sig?test=<name> with the owner or SIG as
+// plain text, or with 404 if it has none.
+//
+// After serviceFailureThreshold consecutive failures the circuit opens and lookups return ""
+// without contacting the service until serviceCooldown has passed.
+type OwnerService struct {
+	base   string
+	client *http.Client
+	// ctx cancels the requests to the service once it is done.
+	ctx context.Context
+	// cache holds recent answers from the service, or is nil if answers are not cached.
+	cache *lookupCache
+	// now returns the current time and is used to time the circuit breaker.
+	now func() time.Time
+
+	lock      sync.Mutex
+	failures  int
+	openUntil time.Time
+}
+
+// NewOwnerService creates an OwnerService for the service at baseURL. Requests that take longer
+// than timeout fail.
+func NewOwnerService(baseURL string, timeout time.Duration) *OwnerService {
+	return &OwnerService{
+		base:   strings.TrimSuffix(baseURL, "/"),
+		client: &http.Client{Timeout: timeout},
+		ctx:    context.Background(),
+		now:    time.Now,
+	}
+}
+
+// EnableCache keeps up to size answers from the service for ttl, so that repeated lookups of the
+// same test do not reach the service. Failed lookups are not cached.
+func (s *OwnerService) EnableCache(size int, ttl time.Duration) {
+	if size <= 0 || ttl <= 0 {
+		s.cache = nil
+		return
+	}
+	s.cache = newLookupCache(size, ttl)
+}
+
+// SetTransport sends requests to the service with rt, e.g. to go through a proxy.
+func (s *OwnerService) SetTransport(rt http.RoundTripper) {
+	s.client.Transport = rt
+}
+
+// SetContext cancels the requests to the service in flight once ctx is done, and fails later
+// lookups without contacting the service, e.g. so that shutting down doesn't wait for them.
+func (s *OwnerService) SetContext(ctx context.Context) {
+	s.ctx = ctx
+}
+
+// TestOwner returns the owner for a test, or the empty string if none is found or the service is
+// unavailable.
+func (s *OwnerService) TestOwner(testName string) string {
+	owner, err := s.get("owner", testName)
+	if err != nil {
+		glog.Errorf("Unable to look up the owner of %q: %v", testName, err)
+	}
+	return owner
+}
+
+// TestSIG returns the SIG for a test, or the empty string if none is found or the service is
+// unavailable.
+func (s *OwnerService) TestSIG(testName string) string {
+	sig, err := s.get("sig", testName)
+	if err != nil {
+		glog.Errorf("Unable to look up the SIG of %q: %v", testName, err)
+	}
+	return sig
+}
+
+// BatchTestOwners returns the owner of each test, in the same order as testNames. The service is
+// asked about each distinct test only once.
+func (s *OwnerService) BatchTestOwners(testNames []string) []string {
+	return s.batch(testNames, s.TestOwner)
+}
+
+// BatchTestSIGs returns the SIG of each test, in the same order as testNames. The service is
+// asked about each distinct test only once.
+func (s *OwnerService) BatchTestSIGs(testNames []string) []string {
+	return s.batch(testNames, s.TestSIG)
+}
+
+func (s *OwnerService) batch(testNames []string, lookup func(string) string) []string {
+	answers := make(map[string]string)
+	values := make([]string, len(testNames))
+	for i, test := range testNames {
+		value, ok := answers[test]
+		if !ok {
+			value = lookup(test)
+			answers[test] = value
+		}
+		values[i] = value
+	}
+	return values
+}
+
+// get queries the service's endpoint for the test, recording the outcome with the circuit breaker.
+func (s *OwnerService) get(endpoint, testName string) (value string, err error) {
+	defer func() {
+		result := "hit"
+		if err != nil {
+			result = "error"
+		} else if value == "" {
+			result = "miss"
+		}
+		lookupCounter.WithLabelValues("service", result).Inc()
+	}()
+
+	key := endpoint + "\x00" + testName
+	if s.cache != nil {
+		if value, ok := s.cache.get(key); ok {
+			return value, nil
+		}
+	}
+
+	if err := s.ctx.Err(); err != nil {
+		return "", err
+	}
+	s.lock.Lock()
+	open := s.now().Before(s.openUntil)
+	s.lock.Unlock()
+	if open {
+		return "", fmt.Errorf("ownership service at %s is unavailable", s.base)
+	}
+
+	value, err = s.fetch(endpoint, testName)
+	if err != nil && s.ctx.Err() != nil {
+		// Cancelled requests say nothing about the health of the service.
+		return "", err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if err != nil {
+		s.failures++
+		if s.failures >= serviceFailureThreshold {
+			glog.Warningf("Ownership service at %s failed %d times in a row, pausing lookups for %v.", s.base, s.failures, serviceCooldown)
+			s.openUntil = s.now().Add(serviceCooldown)
+			s.failures = 0
+		}
+		return "", err
+	}
+	s.failures = 0
+	if s.cache != nil {
+		s.cache.put(key, value)
+	}
+	return value, nil
+}
+
+func (s *OwnerService) fetch(endpoint, testName string) (string, error) {
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/%s?test=%s", s.base, endpoint, url.QueryEscape(testName)), nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := s.client.Do(req.WithContext(s.ctx))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return "", nil
+	case resp.StatusCode != http.StatusOK:
+		return "", fmt.Errorf("ownership service returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return strings.TrimSpace(string(body)), nil
+}
@@ -0,0 +1,144 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package testowner
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestOwnerService(t *testing.T) {
+	owners := map[string]string{"Volumes should mount": "alice"}
+	sigs := map[string]string{"Volumes should mount": "storage"}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var values map[string]string
+		switch r.URL.Path {
+		case "/owner":
+			values = owners
+		case "/sig":
+			values = sigs
+		default:
+			http.Error(w, "bad path", http.StatusBadRequest)
+			return
+		}
+		value, ok := values[r.URL.Query().Get("test")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintln(w, value)
+	}))
+	defer server.Close()
+
+	service := NewOwnerService(server.URL+"/", time.Second)
+	if owner := service.TestOwner("Volumes should mount"); owner != "alice" {
+		t.Errorf("TestOwner() = %q, expected %q", owner, "alice")
+	}
+	if sig := service.TestSIG("Volumes should mount"); sig != "storage" {
+		t.Errorf("TestSIG() = %q, expected %q", sig, "storage")
+	}
+	if owner := service.TestOwner("unknown test"); owner != "" {
+		t.Errorf("TestOwner(unknown) = %q, expected no owner", owner)
+	}
+}
+
+func TestOwnerServiceCircuitBreaker(t *testing.T) {
+	hits := 0
+	healthy := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		if !healthy {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, "alice")
+	}))
+	defer server.Close()
+
+	now := time.Now()
+	service := NewOwnerService(server.URL, time.Second)
+	service.now = func() time.Time { return now }
+
+	for i := 0; i < serviceFailureThreshold; i++ {
+		if owner := service.TestOwner("test"); owner != "" {
+			t.Errorf("TestOwner() = %q while the service is failing", owner)
+		}
+	}
+	if hits != serviceFailureThreshold {
+		t.Fatalf("expected %d requests, got %d", serviceFailureThreshold, hits)
+	}
+
+	// The circuit is open, so the service is not contacted.
+	healthy = true
+	if owner := service.TestOwner("test"); owner != "" {
+		t.Errorf("TestOwner() = %q while the circuit is open", owner)
+	}
+	if hits != serviceFailureThreshold {
+		t.Errorf("expected no requests while the circuit is open, got %d", hits-serviceFailureThreshold)
+	}
+
+	now = now.Add(serviceCooldown)
+	if owner := service.TestOwner("test"); owner != "alice" {
+		t.Errorf("TestOwner() = %q after the cooldown, expected %q", owner, "alice")
+	}
+}
+
+func TestOwnerServiceTimeout(t *testing.T) {
+	done := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-done
+	}))
+	defer server.Close()
+	defer close(done)
+
+	service := NewOwnerService(server.URL, 10*time.Millisecond)
+	if owner := service.TestOwner("test"); owner != "" {
+		t.Errorf("TestOwner() = %q from a service that timed out", owner)
+	}
+}
+
+func TestOwnerServiceCancelled(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		fmt.Fprint(w, "alice")
+	}))
+	defer server.Close()
+
+	service := NewOwnerService(server.URL, time.Second)
+	ctx, cancel := context.WithCancel(context.Background())
+	service.SetContext(ctx)
+	if owner := service.TestOwner("test"); owner != "alice" {
+		t.Errorf("TestOwner() = %q before cancellation, expected \"alice\"", owner)
+	}
+	cancel()
+	for i := 0; i < serviceFailureThreshold+1; i++ {
+		if owner := service.TestOwner(fmt.Sprintf("test%d", i)); owner != "" {
+			t.Errorf("TestOwner() = %q after cancellation", owner)
+		}
+	}
+	if requests != 1 {
+		t.Errorf("expected 1 request to the service, got %d", requests)
+	}
+	if !service.openUntil.IsZero() {
+		t.Errorf("expected cancelled lookups not to open the circuit, it is open until %v", service.openUntil)
+	}
+}